@@ -0,0 +1,142 @@
+package valuemap
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// AuditRecord describes a change of a map created with WithAudit or
+// WithAuditLog: the Event, when it happened and who made it.
+type AuditRecord[K comparable, V any] struct {
+	Event[K, V]
+	Time  time.Time
+	Actor string // the actor of the context of the change, if any
+}
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx that names actor, such as a user or a
+// service, as the author of the changes made with it by SetContext and
+// DeleteContext, for the audit records of the map.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor named by ctx, or "" if there is none.
+func ActorFrom(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// WithAudit makes the map call fn with a record of every change: the
+// assignments, deletions including evictions and expirations, and clears
+// that Watch reports. fn is called with the external mutex locked for
+// writing, as part of the change, so that no change is made without being
+// recorded and records are in the order of the changes; it must not call
+// methods that lock the mutex. Changes made with SetContext and
+// DeleteContext carry the actor of their context. The types of fn must
+// match the map, otherwise the constructor panics.
+func WithAudit[K comparable, V any](fn func(r AuditRecord[K, V])) Option {
+	return optionFunc(func(c *config) {
+		c.audit = fn
+	})
+}
+
+// WithAuditLog is like WithAudit, but writes the records to w as JSON
+// objects, one per line, with the fields time, op, key, old, new, existed,
+// reason and actor, of which old, new, existed and reason are only written
+// when they apply. Records are written with one Write call each. The first
+// failed write stops the log, and its error is returned by Close.
+func WithAuditLog(w io.Writer) Option {
+	return optionFunc(func(c *config) {
+		c.auditW = w
+	})
+}
+
+// auditLog writes audit records to a writer.
+type auditLog struct {
+	mu  sync.Mutex
+	w   io.Writer
+	err error
+}
+
+// auditLine is the JSON form of an audit record.
+type auditLine[K comparable, V any] struct {
+	Time    time.Time `json:"time"`
+	Op      string    `json:"op"`
+	Key     *K        `json:"key,omitempty"`
+	Old     *V        `json:"old,omitempty"`
+	New     *V        `json:"new,omitempty"`
+	Existed bool      `json:"existed,omitempty"`
+	Reason  string    `json:"reason,omitempty"`
+	Actor   string    `json:"actor"`
+}
+
+// auditTo returns an audit function that writes records to l.
+func auditTo[K comparable, V any](l *auditLog) func(r AuditRecord[K, V]) {
+	return func(r AuditRecord[K, V]) {
+		line := auditLine[K, V]{Time: r.Time, Op: r.Kind.String(), Existed: r.Existed, Actor: r.Actor}
+		if r.Kind != EventClear {
+			line.Key = &r.Key
+		}
+		if r.Existed {
+			line.Old = &r.Old
+		}
+		if r.Kind == EventSet {
+			line.New = &r.New
+		}
+		if r.Kind == EventDelete {
+			line.Reason = r.Reason.String()
+		}
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.err != nil {
+			return
+		}
+		b, err := json.Marshal(line)
+		if err != nil {
+			l.err = err
+			return
+		}
+		_, l.err = l.w.Write(append(b, '\n'))
+	}
+}
+
+// error returns the first error of the log.
+func (l *auditLog) error() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
+
+// record passes a change to the audit function of the map.
+// The caller must hold the write lock.
+func (m *ValueMap[K, V]) record(e Event[K, V]) {
+	m.audit(AuditRecord[K, V]{Event: e, Time: m.now(), Actor: m.actor})
+}
+
+// SetContext is like Set, but the change is recorded in the audit records
+// of the map as made by the actor of ctx. ctx is not used otherwise.
+//
+// mu is an external mutex to lock the internal map during value assigning
+func (m *ValueMap[K, V]) SetContext(ctx context.Context, mu *sync.RWMutex, key K, value V) {
+	defer m.lock(mu).Unlock()
+	m.actor = ActorFrom(ctx)
+	defer func() { m.actor = "" }()
+	m.store(key, value)
+}
+
+// DeleteContext is like Delete, but the change is recorded in the audit
+// records of the map as made by the actor of ctx. ctx is not used
+// otherwise.
+//
+// mu is an external mutex to lock the internal map during key deletion
+func (m *ValueMap[K, V]) DeleteContext(ctx context.Context, mu *sync.RWMutex, key K) {
+	defer m.lock(mu).Unlock()
+	m.actor = ActorFrom(ctx)
+	defer func() { m.actor = "" }()
+	m.remove(key)
+}
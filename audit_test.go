@@ -0,0 +1,73 @@
+package valuemap
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithAudit(t *testing.T) {
+	mu := sync.RWMutex{}
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	var records []AuditRecord[string, int]
+	var log bytes.Buffer
+	m := New[string, int](
+		WithClock(clock),
+		WithAudit(func(r AuditRecord[string, int]) {
+			records = append(records, r)
+		}),
+		WithAuditLog(&log),
+	)
+
+	ctx := WithActor(context.Background(), "alice")
+	m.SetContext(ctx, &mu, "a", 1)
+	clock.Advance(time.Second)
+	m.Set(&mu, "a", 2)
+	m.DeleteContext(ctx, &mu, "a")
+	m.Set(&mu, "b", 3)
+	m.Clear(&mu)
+
+	want := []AuditRecord[string, int]{
+		{Event: Event[string, int]{Kind: EventSet, Key: "a", New: 1}, Time: clock.Now().Add(-time.Second), Actor: "alice"},
+		{Event: Event[string, int]{Kind: EventSet, Key: "a", Old: 1, New: 2, Existed: true}, Time: clock.Now()},
+		{Event: Event[string, int]{Kind: EventDelete, Key: "a", Old: 2, Existed: true, Reason: ReasonDeleted}, Time: clock.Now(), Actor: "alice"},
+		{Event: Event[string, int]{Kind: EventSet, Key: "b", New: 3}, Time: clock.Now()},
+		{Event: Event[string, int]{Kind: EventClear}, Time: clock.Now()},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("%d records, want %d: %+v", len(records), len(want), records)
+	}
+	for i, w := range want {
+		if records[i] != w {
+			t.Errorf("record %d = %+v, want %+v", i, records[i], w)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSuffix(log.String(), "\n"), "\n")
+	wantLines := []string{
+		`{"time":"2024-01-01T00:00:00Z","op":"set","key":"a","new":1,"actor":"alice"}`,
+		`{"time":"2024-01-01T00:00:01Z","op":"set","key":"a","old":1,"new":2,"existed":true,"actor":""}`,
+		`{"time":"2024-01-01T00:00:01Z","op":"delete","key":"a","old":2,"existed":true,"reason":"deleted","actor":"alice"}`,
+		`{"time":"2024-01-01T00:00:01Z","op":"set","key":"b","new":3,"actor":""}`,
+		`{"time":"2024-01-01T00:00:01Z","op":"clear","actor":""}`,
+	}
+	if strings.Join(lines, "\n") != strings.Join(wantLines, "\n") {
+		t.Errorf("log =\n%s\nwant\n%s", log.String(), strings.Join(wantLines, "\n"))
+	}
+	if ActorFrom(context.Background()) != "" {
+		t.Error("ActorFrom of a context without actor is not empty")
+	}
+}
+
+func TestWithAuditLogError(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := New[string, int](WithAuditLog(&failingWriter{n: 1}))
+	m.Set(&mu, "a", 1)
+	m.Set(&mu, "b", 2)
+	if err := m.Close(); err == nil || err.Error() != "disk full" {
+		t.Errorf("Close error = %v, want disk full", err)
+	}
+}
@@ -0,0 +1,101 @@
+package valuemap
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// autosave holds the state of a map created with WithAutosave.
+type autosave[K comparable, V any] struct {
+	mu        *sync.RWMutex
+	path      string
+	codec     Codec[K, V]
+	dirty     atomic.Bool // set under the write lock of mu, cleared when saving
+	saving    sync.Mutex  // serializes writes to path
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// WithAutosave makes the map save itself to the file at path with c every
+// interval, and once more when Close is called. Files are written as with
+// SaveToFile, and only when the map was modified since the last save.
+// An interval of zero or less disables the timer, so the map is only saved
+// by Close. Like the other options, it is passed to New or another constructor.
+//
+// The contents of path are not loaded; call LoadFromFile after construction
+// to resume from an earlier run. A failed periodic save is retried on the
+// next tick, and Close reports the error of the final save.
+//
+// mu is an external mutex to lock the internal map while it is copied for
+// saving. It must be the same mutex passed to the other methods
+func WithAutosave[K comparable, V any](mu *sync.RWMutex, path string, interval time.Duration, c Codec[K, V]) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.autosaveMu = mu
+		cfg.autosavePath = path
+		cfg.autosaveInterval = interval
+		cfg.autosaveCodec = c
+	})
+}
+
+func (m *ValueMap[K, V]) startAutosave(c *config) {
+	a := &autosave[K, V]{
+		mu:      c.autosaveMu,
+		path:    c.autosavePath,
+		codec:   typed[Codec[K, V]](c.autosaveCodec, "autosave codec"),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	m.autosave = a
+	if c.autosaveInterval <= 0 {
+		close(a.stopped)
+		return
+	}
+	go m.autosaver(c.autosaveInterval)
+}
+
+func (m *ValueMap[K, V]) autosaver(interval time.Duration) {
+	a := m.autosave
+	defer close(a.stopped)
+	m.every(interval, a.done, func() { m.flush() })
+}
+
+// flush saves the map if it was modified since the last save.
+func (m *ValueMap[K, V]) flush() error {
+	a := m.autosave
+	a.saving.Lock()
+	defer a.saving.Unlock()
+
+	a.mu.RLock()
+	if !a.dirty.Swap(false) {
+		a.mu.RUnlock()
+		return nil
+	}
+	data := make(map[K]V, m.size())
+	for k, v := range m.live() {
+		data[k] = v
+	}
+	a.mu.RUnlock()
+
+	err := writeFile(a.path, func(f *os.File) error {
+		return a.codec.Encode(f, data)
+	})
+	if err != nil {
+		a.dirty.Store(true)
+	}
+	return err
+}
+
+// closeAutosave stops the autosave timer and saves the map a last time.
+func (m *ValueMap[K, V]) closeAutosave() error {
+	a := m.autosave
+	a.closeOnce.Do(func() {
+		close(a.done)
+		<-a.stopped
+		a.closeErr = m.flush()
+	})
+	return a.closeErr
+}
@@ -0,0 +1,76 @@
+package valuemap
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAutosave(t *testing.T) {
+	mu := sync.RWMutex{}
+	path := filepath.Join(t.TempDir(), "cache.json")
+	m := NewBounded[string, int](0, WithAutosave(&mu, path, 10*time.Millisecond, JSONCodec[string, int]{}))
+	defer m.Close()
+
+	m.Set(&mu, "a", 1)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		got := New[string, int]()
+		if err := got.LoadFromFile(&mu, path, JSONCodec[string, int]{}); err == nil {
+			if v, _ := got.Get(&mu, "a"); v == 1 {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("map was not saved by the timer")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestAutosaveOnClose(t *testing.T) {
+	mu := sync.RWMutex{}
+	path := filepath.Join(t.TempDir(), "cache.json")
+	m := NewWithTTL[string, int](&mu, time.Hour, 0, WithAutosave(&mu, path, 0, JSONCodec[string, int]{}))
+
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("unmodified map was saved: %v", err)
+	}
+
+	m = NewWithTTL[string, int](&mu, time.Hour, 0, WithAutosave(&mu, path, 0, JSONCodec[string, int]{}))
+	m.Set(&mu, "a", 1)
+	m.Set(&mu, "b", 2)
+	m.Delete(&mu, "a")
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Close(); err != nil {
+		t.Errorf("second Close() = %v, want nil", err)
+	}
+
+	got := New[string, int]()
+	if err := got.LoadFromFile(&mu, path, JSONCodec[string, int]{}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Len(&mu) != 1 {
+		t.Errorf("Len() = %d, want 1", got.Len(&mu))
+	}
+	if v, _ := got.Get(&mu, "b"); v != 2 {
+		t.Errorf("Get(b) = %d, want 2", v)
+	}
+}
+
+func TestAutosaveError(t *testing.T) {
+	mu := sync.RWMutex{}
+	path := filepath.Join(t.TempDir(), "missing", "cache.json")
+	m := NewBounded[string, int](0, WithAutosave(&mu, path, 0, JSONCodec[string, int]{}))
+	m.Set(&mu, "a", 1)
+	if err := m.Close(); err == nil {
+		t.Error("Close() = nil, want the save error")
+	}
+}
@@ -0,0 +1,218 @@
+package valuemap
+
+import "container/list"
+
+// bound holds the capacity state of a ValueMap created with NewBounded.
+type bound[K comparable, V any] struct {
+	capacity int
+	policy   EvictionPolicy[K]
+	maxCost  int64
+	costFn   func(key K, value V) int64
+	costs    map[K]int64
+	used     int64
+}
+
+// charge records the cost of a key that was assigned value.
+func (b *bound[K, V]) charge(key K, value V) {
+	if b.costFn == nil {
+		return
+	}
+	c := b.costFn(key, value)
+	b.used += c - b.costs[key]
+	b.costs[key] = c
+}
+
+// refund releases the cost of a removed key.
+func (b *bound[K, V]) refund(key K) {
+	if b.costFn == nil {
+		return
+	}
+	b.used -= b.costs[key]
+	delete(b.costs, key)
+}
+
+// full reports whether the map holds more than its limits allow.
+func (b *bound[K, V]) full(n int) bool {
+	return (b.capacity > 0 && n > b.capacity) || (b.costFn != nil && b.used > b.maxCost)
+}
+
+// EvictionPolicy decides which key a bounded map evicts when it is full.
+// Its methods are called with the write lock of the map held, so they need
+// no locking of their own and must not call methods of the map.
+type EvictionPolicy[K comparable] interface {
+	// OnSet is called after a key is assigned, whether it is new or not.
+	OnSet(key K)
+	// OnGet is called after a present key is read.
+	OnGet(key K)
+	// OnDelete is called after a key is removed, including by eviction.
+	OnDelete(key K)
+	// Victim returns the key to evict next. It may return the key that
+	// was just set to reject it. It returns false if it has no candidate.
+	Victim() (K, bool)
+}
+
+// resetter is implemented by policies that can forget all keys at once
+// when the map is cleared. Other policies get OnDelete for every key.
+type resetter interface {
+	Reset()
+}
+
+// Policy selects the built-in eviction policy of a bounded map.
+type Policy int
+
+const (
+	// LRU evicts the least recently used entry.
+	LRU Policy = iota
+	// TinyLFU evicts the least recently used entry, but only admits a new
+	// key if it is estimated to be used more often than that entry.
+	// Frequencies are tracked in a compact count-min sketch that ages
+	// over time, so it suits workloads dominated by a set of heavy hitters.
+	TinyLFU
+)
+
+// WithPolicy selects the eviction policy of a bounded map. The default is LRU.
+// It has no effect on maps without WithMaxEntries or WithMaxCost.
+func WithPolicy(p Policy) Option {
+	return optionFunc(func(c *config) {
+		c.policy = p
+	})
+}
+
+// WithEvictionPolicy makes a bounded map use a custom eviction policy,
+// overriding WithPolicy. The key type of the policy must match the map,
+// otherwise the constructor panics.
+func WithEvictionPolicy[K comparable](p EvictionPolicy[K]) Option {
+	return optionFunc(func(c *config) {
+		c.customPolicy = p
+	})
+}
+
+// WithMaxCost limits a bounded map by the total cost of its entries,
+// as computed by cost when an entry is assigned, in addition to its capacity.
+// An entry costing more than total on its own is removed right away.
+// Like WithMaxEntries, it makes the map bounded, with no entry limit unless
+// WithMaxEntries sets one.
+// The types of cost must match the map, otherwise the constructor panics.
+func WithMaxCost[K comparable, V any](total int64, cost func(key K, value V) int64) Option {
+	return optionFunc(func(c *config) {
+		c.bounded = true
+		c.maxCost = total
+		c.costFn = cost
+	})
+}
+
+// NewBounded returns a new pointer to a ValueMap that holds at most capacity
+// entries. When a new key would exceed the capacity, an entry chosen by
+// the eviction policy is removed. A capacity of zero or less means no entry
+// limit, which is useful together with WithMaxCost. Get, GetMany, GetOrSet and
+// ComputeIfAbsent count as uses, so these reads lock the external mutex for writing.
+func NewBounded[K comparable, V any](capacity int, opts ...Option) *ValueMap[K, V] {
+	return New[K, V](append([]Option{WithMaxEntries(capacity)}, opts...)...)
+}
+
+// WithMaxEntries bounds the map to at most n entries, as NewBounded does.
+// When a new key would exceed the limit, an entry chosen by the eviction
+// policy is removed. A limit of zero or less means no entry limit, which is
+// useful together with WithMaxCost.
+func WithMaxEntries(n int) Option {
+	return optionFunc(func(c *config) {
+		c.bounded = true
+		c.maxEntries = n
+	})
+}
+
+// bind sets up the capacity state of a bounded map.
+func (m *ValueMap[K, V]) bind(c *config) {
+	capacity := c.maxEntries
+	m.bound = &bound[K, V]{capacity: capacity}
+	if c.costFn != nil {
+		m.bound.maxCost = c.maxCost
+		m.bound.costFn = typed[func(K, V) int64](c.costFn, "cost function")
+		m.bound.costs = make(map[K]int64)
+	}
+	switch {
+	case c.customPolicy != nil:
+		m.bound.policy = typed[EvictionPolicy[K]](c.customPolicy, "eviction policy")
+	case c.policy == TinyLFU:
+		m.bound.policy = newTinyLFU[K](capacity)
+	default:
+		m.bound.policy = newLRU[K]()
+	}
+	m.trackReads = true
+}
+
+// NewLRU returns a new pointer to a ValueMap that holds at most capacity
+// entries, evicting the least recently used entry when full.
+// It is a shorthand for NewBounded with the LRU policy.
+func NewLRU[K comparable, V any](capacity int) *ValueMap[K, V] {
+	return NewBounded[K, V](capacity)
+}
+
+// evict removes entries until the map is within its limits after key was
+// assigned. The caller must hold the write lock.
+func (m *ValueMap[K, V]) evict(key K) {
+	if m.bound.costFn != nil && m.bound.costs[key] > m.bound.maxCost {
+		// The entry can never fit, so it goes before anything else does.
+		m.removeFor(key, ReasonEvicted)
+		return
+	}
+	for m.bound.full(m.size()) {
+		k, ok := m.bound.policy.Victim()
+		if !ok {
+			return
+		}
+		if _, ok := m.stored(k); !ok {
+			// A victim that is not in the map would never free a slot.
+			return
+		}
+		m.removeFor(k, ReasonEvicted)
+	}
+}
+
+// lru tracks key recency in a list ordered from most to least recently used.
+type lru[K comparable] struct {
+	order *list.List
+	elems map[K]*list.Element
+}
+
+func newLRU[K comparable]() *lru[K] {
+	return &lru[K]{
+		order: list.New(),
+		elems: make(map[K]*list.Element),
+	}
+}
+
+func (p *lru[K]) OnSet(key K) {
+	if el, ok := p.elems[key]; ok {
+		p.order.MoveToFront(el)
+		return
+	}
+	p.elems[key] = p.order.PushFront(key)
+}
+
+func (p *lru[K]) OnGet(key K) {
+	if el, ok := p.elems[key]; ok {
+		p.order.MoveToFront(el)
+	}
+}
+
+func (p *lru[K]) OnDelete(key K) {
+	if el, ok := p.elems[key]; ok {
+		p.order.Remove(el)
+		delete(p.elems, key)
+	}
+}
+
+func (p *lru[K]) Victim() (K, bool) {
+	el := p.order.Back()
+	if el == nil {
+		var zero K
+		return zero, false
+	}
+	return el.Value.(K), true
+}
+
+func (p *lru[K]) Reset() {
+	p.order.Init()
+	clear(p.elems)
+}
@@ -0,0 +1,124 @@
+package valuemap
+
+import (
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestLRU(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := NewLRU[string, int](2)
+
+	m.Set(&mu, "a", 1)
+	m.Set(&mu, "b", 2)
+	m.Get(&mu, "a")
+	m.Set(&mu, "c", 3)
+
+	if _, ok := m.Get(&mu, "b"); ok {
+		t.Error("least recently used entry was not evicted")
+	}
+	if _, ok := m.Get(&mu, "a"); !ok {
+		t.Error("recently read entry was evicted")
+	}
+	if n := m.Len(&mu); n != 2 {
+		t.Errorf("Len = %d, want 2", n)
+	}
+
+	m.Delete(&mu, "a")
+	m.Set(&mu, "d", 4)
+	if _, ok := m.Get(&mu, "c"); !ok {
+		t.Error("entry evicted although a slot was freed by Delete")
+	}
+
+	m.Clear(&mu)
+	m.SetMany(&mu, map[string]int{"x": 1, "y": 2, "z": 3})
+	if n := m.Len(&mu); n != 2 {
+		t.Errorf("Len after SetMany = %d, want 2", n)
+	}
+}
+
+// fifo evicts keys in the order they were first set.
+type fifo[K comparable] struct {
+	keys []K
+}
+
+func (p *fifo[K]) OnSet(key K) {
+	if !slices.Contains(p.keys, key) {
+		p.keys = append(p.keys, key)
+	}
+}
+
+func (p *fifo[K]) OnGet(key K) {}
+
+func (p *fifo[K]) OnDelete(key K) {
+	p.keys = slices.DeleteFunc(p.keys, func(k K) bool {
+		return k == key
+	})
+}
+
+func (p *fifo[K]) Victim() (K, bool) {
+	if len(p.keys) == 0 {
+		var zero K
+		return zero, false
+	}
+	return p.keys[0], true
+}
+
+func TestEvictionPolicy(t *testing.T) {
+	mu := sync.RWMutex{}
+	p := &fifo[string]{}
+	m := NewBounded[string, int](2, WithEvictionPolicy[string](p))
+
+	m.Set(&mu, "a", 1)
+	m.Set(&mu, "b", 2)
+	m.Get(&mu, "a")
+	m.Set(&mu, "c", 3)
+
+	if _, ok := m.Get(&mu, "a"); ok {
+		t.Error("first key was not evicted")
+	}
+	m.Clear(&mu)
+	if len(p.keys) != 0 {
+		t.Errorf("policy still tracks %v after Clear", p.keys)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("mismatched policy key type did not panic")
+		}
+	}()
+	NewBounded[int, int](1, WithEvictionPolicy[string](p))
+}
+
+func TestMaxCost(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := NewBounded[string, []byte](0, WithMaxCost(10, func(_ string, v []byte) int64 {
+		return int64(len(v))
+	}))
+
+	m.Set(&mu, "a", make([]byte, 4))
+	m.Set(&mu, "b", make([]byte, 4))
+	m.Set(&mu, "c", make([]byte, 4))
+	if _, ok := m.Get(&mu, "a"); ok {
+		t.Error("oldest entry was not evicted when over cost")
+	}
+	if n := m.Len(&mu); n != 2 {
+		t.Errorf("Len = %d, want 2", n)
+	}
+
+	// Shrinking a value frees cost for another entry.
+	m.Set(&mu, "b", make([]byte, 1))
+	m.Set(&mu, "d", make([]byte, 4))
+	if n := m.Len(&mu); n != 3 {
+		t.Errorf("Len = %d, want 3", n)
+	}
+
+	m.Set(&mu, "huge", make([]byte, 11))
+	if _, ok := m.Get(&mu, "huge"); ok {
+		t.Error("entry costing more than the total was kept")
+	}
+	if n := m.Len(&mu); n != 3 {
+		t.Errorf("Len = %d after oversized entry, want 3", n)
+	}
+}
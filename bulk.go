@@ -0,0 +1,185 @@
+package valuemap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// BulkLoader loads the values of several keys with one request to a
+// backing store, such as a single SQL query with an IN clause.
+//
+// LoadMany returns the values of the keys it found; keys missing from the
+// result are not found. It can return a partial result along with an
+// error: a LoadErrors for keys that failed individually, in which case the
+// other keys are found or not found as usual, or any other error, which
+// then applies to all the keys missing from the result.
+type BulkLoader[K comparable, V any] interface {
+	LoadMany(ctx context.Context, keys []K) (map[K]V, error)
+}
+
+// LoadErrors holds the errors of the keys that failed to load. It is
+// returned by BulkLoader implementations and by GetManyContext.
+type LoadErrors[K comparable] map[K]error
+
+func (e LoadErrors[K]) Error() string {
+	if len(e) == 1 {
+		for k, err := range e {
+			return fmt.Sprintf("valuemap: loading %v: %v", k, err)
+		}
+	}
+	return fmt.Sprintf("valuemap: %d keys failed to load", len(e))
+}
+
+// Unwrap returns the errors of the keys, so that errors.Is and errors.As
+// find them.
+func (e LoadErrors[K]) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, err := range e {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// WithBulkLoader is like WithLoader, but GetMany and GetManyContext load
+// all their missing keys with one call of LoadMany, so that a page that
+// needs 200 keys sends one query for those not in the map instead of 200.
+// Missing keys of Get and GetContext are loaded with a call of LoadMany
+// for the key alone, unless the map has another loader, from WithLoader
+// or from a store.
+//
+// The keys being loaded are shared with the other lookups as with
+// WithLoader: a key that is already being loaded is not requested again,
+// and lookups of a key that a LoadMany call is loading wait for it. The
+// types of l must match the map, otherwise the constructor panics.
+func WithBulkLoader[K comparable, V any](l BulkLoader[K, V]) Option {
+	return optionFunc(func(c *config) {
+		c.bulkLoader = l
+	})
+}
+
+// bulkLoad returns a load function that loads a single key with l.
+func bulkLoad[K comparable, V any](l BulkLoader[K, V]) func(ctx context.Context, key K) (V, error) {
+	return func(ctx context.Context, key K) (V, error) {
+		res, err := l.LoadMany(ctx, []K{key})
+		return bulkResult(res, err, key)
+	}
+}
+
+// bulkResult returns the value or the error of a key in the result of
+// LoadMany.
+func bulkResult[K comparable, V any](res map[K]V, err error, key K) (V, error) {
+	if v, ok := res[key]; ok {
+		return v, nil
+	}
+	var zero V
+	var errs LoadErrors[K]
+	switch {
+	case errors.As(err, &errs):
+		if err, ok := errs[key]; ok {
+			return zero, err
+		}
+		return zero, ErrNotFound
+	case err != nil:
+		return zero, err
+	}
+	return zero, ErrNotFound
+}
+
+// GetManyContext retrieves the values of the given keys. If some are not
+// present and the map was created with WithLoader or WithBulkLoader, they
+// are loaded and stored, with one call of LoadMany for a bulk loader and
+// with concurrent calls of the loader otherwise. Keys that do not exist
+// are omitted from the result.
+//
+// The values found are returned even if some keys failed to load, along
+// with a LoadErrors holding the errors of those keys, or the error of ctx
+// if it is done before the keys are loaded.
+//
+// mu is an external mutex to lock the internal map once to look up the
+// keys, and once to store the loaded values. It is not held while the
+// loader runs
+func (m *ValueMap[K, V]) GetManyContext(ctx context.Context, mu *sync.RWMutex, keys []K) (map[K]V, error) {
+	res, missing := m.getMany(mu, keys)
+	if len(missing) == 0 || m.loader == nil {
+		return res, nil
+	}
+	return res, m.loadMany(ctx, mu, res, missing)
+}
+
+// loadMany loads missing keys into res, joining the calls in progress for
+// those that are already being loaded.
+func (m *ValueMap[K, V]) loadMany(ctx context.Context, mu *sync.RWMutex, res map[K]V, missing []K) error {
+	l := m.loader
+	errs := make(LoadErrors[K])
+	calls := make(map[K]*loadCall[V], len(missing))
+	var keys []K
+	var started []*loadCall[V]
+	l.mu.Lock()
+	for _, k := range missing {
+		if _, ok := calls[k]; ok {
+			continue
+		}
+		if f, ok := l.failed[k]; ok && m.now().Before(f.until) {
+			errs[k] = f.err
+			continue
+		}
+		c, ok := l.calls[k]
+		if !ok {
+			c = &loadCall[V]{done: make(chan struct{})}
+			l.calls[k] = c
+			keys = append(keys, k)
+			started = append(started, c)
+		}
+		calls[k] = c
+	}
+	l.mu.Unlock()
+
+	detached := context.WithoutCancel(ctx)
+	if l.loadMany != nil && len(keys) > 0 {
+		go m.runMany(detached, mu, keys, started)
+	} else {
+		for i, k := range keys {
+			go m.run(l, mu, k, started[i], func() (V, error) { return l.load(detached, k) })
+		}
+	}
+
+	for k, c := range calls {
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if c.err == nil {
+			res[k] = c.value
+		} else if !errors.Is(c.err, ErrNotFound) {
+			errs[k] = c.err
+		}
+	}
+	for k, err := range errs {
+		if errors.Is(err, ErrNotFound) {
+			delete(errs, k)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// runMany loads keys with one call of the bulk loader and stores the
+// values it returns.
+func (m *ValueMap[K, V]) runMany(ctx context.Context, mu *sync.RWMutex, keys []K, calls []*loadCall[V]) {
+	res, err := m.loader.loadMany(ctx, keys)
+	defer func() {
+		for _, c := range calls {
+			close(c.done)
+		}
+	}()
+	defer m.lock(mu).Unlock()
+	for i, k := range keys {
+		calls[i].value, calls[i].err = bulkResult(res, err, k)
+		m.settle(m.loader, k, calls[i])
+	}
+}
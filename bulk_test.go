@@ -0,0 +1,109 @@
+package valuemap
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// bulkFunc is a BulkLoader that records the keys it is asked for.
+type bulkFunc struct {
+	mu    sync.Mutex
+	calls [][]string
+	load  func(keys []string) (map[string]int, error)
+}
+
+func (b *bulkFunc) LoadMany(ctx context.Context, keys []string) (map[string]int, error) {
+	b.mu.Lock()
+	b.calls = append(b.calls, slices.Sorted(slices.Values(keys)))
+	b.mu.Unlock()
+	return b.load(keys)
+}
+
+func TestWithBulkLoader(t *testing.T) {
+	mu := sync.RWMutex{}
+	errDown := errors.New("unavailable")
+	b := &bulkFunc{load: func(keys []string) (map[string]int, error) {
+		res := make(map[string]int)
+		errs := make(LoadErrors[string])
+		for _, k := range keys {
+			switch k {
+			case "missing":
+			case "broken":
+				errs[k] = errDown
+			default:
+				res[k] = len(k)
+			}
+		}
+		if len(errs) > 0 {
+			return res, errs
+		}
+		return res, nil
+	}}
+	m := New[string, int](WithBulkLoader[string, int](b))
+	m.Set(&mu, "a", 10)
+
+	got, err := m.GetManyContext(context.Background(), &mu, []string{"a", "bb", "ccc", "missing", "broken", "bb"})
+	if want := map[string]int{"a": 10, "bb": 2, "ccc": 3}; !maps.Equal(got, want) {
+		t.Errorf("GetManyContext = %v, want %v", got, want)
+	}
+	var errs LoadErrors[string]
+	if !errors.As(err, &errs) || len(errs) != 1 || !errors.Is(err, errDown) {
+		t.Errorf("GetManyContext error = %v, want an error for broken only", err)
+	}
+	if want := [][]string{{"bb", "broken", "ccc", "missing"}}; !slices.EqualFunc(b.calls, want, slices.Equal) {
+		t.Errorf("LoadMany calls = %v, want %v", b.calls, want)
+	}
+
+	// Loaded values are stored, and single misses use LoadMany too.
+	if got := m.GetMany(&mu, []string{"bb", "ccc"}); len(got) != 2 || len(b.calls) != 1 {
+		t.Errorf("GetMany = %v after %d calls, want the stored values", got, len(b.calls))
+	}
+	if v, ok := m.Get(&mu, "dddd"); v != 4 || !ok {
+		t.Errorf("Get(dddd) = %d, %v, want 4", v, ok)
+	}
+	if _, err := m.GetContext(context.Background(), &mu, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetContext(missing) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestWithBulkLoaderBatchError(t *testing.T) {
+	mu := sync.RWMutex{}
+	errDown := errors.New("unavailable")
+	b := &bulkFunc{load: func(keys []string) (map[string]int, error) {
+		return map[string]int{"a": 1}, errDown
+	}}
+	m := New[string, int](WithBulkLoader[string, int](b), WithNegativeCache(0, time.Minute))
+
+	got, err := m.GetManyContext(context.Background(), &mu, []string{"a", "b", "c"})
+	if !maps.Equal(got, map[string]int{"a": 1}) {
+		t.Errorf("GetManyContext = %v, want the partial result", got)
+	}
+	var errs LoadErrors[string]
+	if !errors.As(err, &errs) || len(errs) != 2 || errs["b"] != errDown {
+		t.Errorf("GetManyContext error = %v, want errors for b and c", err)
+	}
+
+	// The failures are cached.
+	if _, err := m.GetManyContext(context.Background(), &mu, []string{"b", "c"}); len(b.calls) != 1 || !errors.Is(err, errDown) {
+		t.Errorf("GetManyContext error = %v after %d calls, want the cached failures", err, len(b.calls))
+	}
+}
+
+func TestGetManyWithLoader(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := New[string, int](WithLoader(func(ctx context.Context, key string) (int, error) {
+		if key == "missing" {
+			return 0, ErrNotFound
+		}
+		return len(key), nil
+	}))
+	got := m.GetMany(&mu, []string{"a", "bb", "missing"})
+	if want := map[string]int{"a": 1, "bb": 2}; !maps.Equal(got, want) {
+		t.Errorf("GetMany = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,48 @@
+package valuemap
+
+import (
+	"maps"
+	"sync"
+)
+
+// NewWithCapacity returns a new pointer to a thread-safe ValueMap with room
+// for n entries, so that it does not grow until it holds more.
+func NewWithCapacity[K comparable, V any](n int) *ValueMap[K, V] {
+	return New[K, V](WithCapacity(n))
+}
+
+// WithCapacity gives the map room for n entries, as NewWithCapacity does.
+func WithCapacity(n int) Option {
+	return optionFunc(func(c *config) {
+		c.capacity = max(n, 0)
+	})
+}
+
+// Reserve makes room for n entries in total, so that the map does not grow
+// until it holds more. Growing a map rehashes its entries every time its
+// size doubles, so reserving room before a bulk load is faster than letting
+// it grow. If the map already has room for n entries, Reserve does nothing;
+// otherwise it copies the entries into a map of the new size.
+//
+// mu is an external mutex to lock the internal map during the reservation
+func (m *ValueMap[K, V]) Reserve(mu *sync.RWMutex, n int) {
+	defer m.lock(mu).Unlock()
+	m.grow(n)
+}
+
+// grow makes room for n entries in the internal map.
+// The caller must hold the write lock.
+func (m *ValueMap[K, V]) grow(n int) {
+	if m.stripes != nil {
+		m.stripes.grow(n)
+		return
+	}
+	if n <= len(m.data) || (n <= m.reserved && !m.shared) {
+		return
+	}
+	data := make(map[K]V, n)
+	maps.Copy(data, m.data)
+	m.data = data
+	m.shared = false
+	m.reserved = n
+}
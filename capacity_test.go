@@ -0,0 +1,55 @@
+package valuemap
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestReserve(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := NewWithCapacity[int, int](10)
+	m.Set(&mu, 1, 1)
+	c := m.Clone(&mu)
+
+	m.Reserve(&mu, 1000)
+	if allocs := testing.AllocsPerRun(1, func() {
+		for i := range 1000 {
+			m.Set(&mu, i, i)
+		}
+	}); allocs > 0 {
+		t.Errorf("%v allocations filling a reserved map, want 0", allocs)
+	}
+	if m.Len(&mu) != 1000 {
+		t.Errorf("Len() = %d, want 1000", m.Len(&mu))
+	}
+	if c.Len(&mu) != 1 {
+		t.Errorf("Len() of the clone = %d after Reserve on the original, want 1", c.Len(&mu))
+	}
+
+	// Reserving less than the map holds keeps its entries.
+	m.Reserve(&mu, 10)
+	if v, _ := m.Get(&mu, 999); v != 999 {
+		t.Errorf("Get(999) = %d after Reserve, want 999", v)
+	}
+}
+
+func BenchmarkReserve(b *testing.B) {
+	mu := sync.RWMutex{}
+	for _, reserve := range []bool{false, true} {
+		name := "grow"
+		if reserve {
+			name = "reserve"
+		}
+		b.Run(name, func(b *testing.B) {
+			for range b.N {
+				m := New[int, int]()
+				if reserve {
+					m.Reserve(&mu, 100_000)
+				}
+				for i := range 100_000 {
+					m.Set(&mu, i, i)
+				}
+			}
+		})
+	}
+}
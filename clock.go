@@ -0,0 +1,171 @@
+package valuemap
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time to the features of a map that depend on it: the
+// expiration of entries, the janitor of NewWithTTL, and the timers of
+// WithAutosave and WithCheckpoint. Tests can pass a ManualClock with
+// WithClock to control it instead of sleeping.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a timer created by a Clock, like time.Timer.
+type Timer interface {
+	// C returns the channel on which the time is delivered when the
+	// timer fires.
+	C() <-chan time.Time
+	// Stop prevents the timer from firing and reports whether it was active.
+	Stop() bool
+	// Reset makes the timer fire after d and reports whether it was active.
+	Reset(d time.Duration) bool
+}
+
+// WithClock makes the map tell the time with c instead of the system
+// clock. A nil c means the system clock.
+func WithClock(c Clock) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.clock = c
+	})
+}
+
+// systemClock is the Clock of the time package.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+type systemTimer struct {
+	*time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time { return t.Timer.C }
+
+// clk returns the clock of the map.
+func (m *ValueMap[K, V]) clk() Clock {
+	if m.clock == nil {
+		return systemClock{}
+	}
+	return m.clock
+}
+
+// now returns the current time used for expiration.
+func (m *ValueMap[K, V]) now() time.Time {
+	return m.clk().Now()
+}
+
+// every calls fn every interval, as told by the clock of the map,
+// until done is closed.
+func (m *ValueMap[K, V]) every(interval time.Duration, done <-chan struct{}, fn func()) {
+	t := m.clk().NewTimer(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C():
+			fn()
+			t.Reset(interval)
+		case <-done:
+			return
+		}
+	}
+}
+
+// ManualClock is a Clock whose time only changes when it is told to, so
+// that tests of expiration do not depend on the system clock. Its timers
+// fire when Advance or Set moves the time past them. It is safe for
+// concurrent use.
+type ManualClock struct {
+	mu      sync.Mutex
+	changed sync.Cond // signaled when a timer is set
+	now     time.Time
+	timers  map[*manualTimer]struct{}
+}
+
+// NewManualClock returns a ManualClock set to start.
+func NewManualClock(start time.Time) *ManualClock {
+	c := &ManualClock{now: start, timers: make(map[*manualTimer]struct{})}
+	c.changed.L = &c.mu
+	return c
+}
+
+// Now implements Clock.
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTimer implements Clock.
+func (c *ManualClock) NewTimer(d time.Duration) Timer {
+	t := &manualTimer{clock: c, ch: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+// BlockUntil waits until at least n timers are set and have not fired,
+// for example until the background goroutines of a map are waiting for
+// the clock, so that moving it makes them run.
+func (c *ManualClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.timers) < n {
+		c.changed.Wait()
+	}
+}
+
+// Advance moves the time forward by d and fires the timers that are due.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	t := c.now.Add(d)
+	c.mu.Unlock()
+	c.Set(t)
+}
+
+// Set moves the time to t and fires the timers that are due.
+func (c *ManualClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+	for timer := range c.timers {
+		if !timer.when.After(t) {
+			delete(c.timers, timer)
+			select {
+			case timer.ch <- t:
+			default:
+			}
+		}
+	}
+}
+
+type manualTimer struct {
+	clock *ManualClock
+	ch    chan time.Time
+	when  time.Time
+}
+
+func (t *manualTimer) C() <-chan time.Time { return t.ch }
+
+func (t *manualTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	_, active := t.clock.timers[t]
+	delete(t.clock.timers, t)
+	return active
+}
+
+func (t *manualTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	_, active := t.clock.timers[t]
+	t.when = t.clock.now.Add(d)
+	t.clock.timers[t] = struct{}{}
+	t.clock.changed.Broadcast()
+	return active
+}
@@ -0,0 +1,71 @@
+package valuemap
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithClock(t *testing.T) {
+	mu := sync.RWMutex{}
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	removed := make(chan string, 1)
+	m := New[string, int](
+		WithClock(clock),
+		WithTTL(&mu, time.Minute, 10*time.Second),
+		WithOnEvict(func(key string, _ int, reason RemovalReason) {
+			if reason == ReasonExpired {
+				removed <- key
+			}
+		}),
+	)
+	defer m.Close()
+
+	m.Set(&mu, "a", 1)
+	clock.BlockUntil(1)
+	clock.Advance(59 * time.Second)
+	if _, ok := m.Get(&mu, "a"); !ok {
+		t.Fatal("a expired before its lifetime")
+	}
+	if d, _ := m.TTL(&mu, "a"); d != time.Second {
+		t.Errorf("TTL(a) = %v, want 1s", d)
+	}
+
+	// Moving past the deadline expires the entry at once, and the janitor
+	// removes it on its next run.
+	clock.Advance(time.Second)
+	if _, ok := m.Get(&mu, "a"); ok {
+		t.Error("a did not expire")
+	}
+	clock.BlockUntil(1)
+	clock.Advance(10 * time.Second)
+	select {
+	case k := <-removed:
+		if k != "a" {
+			t.Errorf("janitor removed %q, want a", k)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("janitor did not run")
+	}
+}
+
+func TestManualClockTimer(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	timer := clock.NewTimer(time.Minute)
+	clock.Advance(30 * time.Second)
+	select {
+	case <-timer.C():
+		t.Fatal("timer fired early")
+	default:
+	}
+	if !timer.Reset(time.Minute) {
+		t.Error("Reset() = false for an active timer")
+	}
+	clock.Advance(time.Minute)
+	if got := <-timer.C(); !got.Equal(time.Unix(90, 0)) {
+		t.Errorf("timer fired at %v, want 90s", got)
+	}
+	if timer.Stop() {
+		t.Error("Stop() = true for a timer that fired")
+	}
+}
@@ -0,0 +1,54 @@
+package valuemap
+
+import (
+	"cmp"
+	"errors"
+)
+
+// ErrClosed is returned by the methods of a map that depend on its
+// background work, such as Sync and Checkpoint, once it is closed.
+var ErrClosed = errors.New("valuemap: map is closed")
+
+// Close stops the background work of the map and releases what it holds.
+// It stops the janitor of a map created with WithTTL. For a map created
+// with WithAutosave, it stops the autosave timer and saves the map a last
+// time, and for a map created with WithJournal, it stops the checkpoint
+// timer, syncs the journal and closes the journal file opened by
+// OpenJournaled. For a map created with WithWriteBehind, it stops the
+// background flushes and flushes the queued writes a last time, and for a
+// map created with WithAuditLog, it reports the error of the log. It also
+// ends every Watch and WatchKey subscription by closing its channel. Close
+// implements io.Closer, and returns the first error of these steps, as do
+// the calls after the first.
+//
+// The entries of a closed map can still be read and written in memory, but
+// changes are no longer saved, journaled or written behind, and entries
+// are only found expired on access or by DeleteExpired. Maps created with
+// WithWriteThrough still write to their store. Watch and WatchKey return
+// closed channels, and Sync, Checkpoint and WaitForKey return ErrClosed.
+func (m *ValueMap[K, V]) Close() error {
+	m.closeOnce.Do(func() {
+		m.closed.Store(true)
+		if m.ttl != nil {
+			m.ttl.closeOnce.Do(func() {
+				close(m.ttl.done)
+			})
+		}
+		var err error
+		if m.journal != nil {
+			err = m.closeJournal()
+		}
+		if m.autosave != nil {
+			err = cmp.Or(err, m.closeAutosave())
+		}
+		if m.writer != nil {
+			err = cmp.Or(err, m.closeWriter())
+		}
+		if m.auditLog != nil {
+			err = cmp.Or(err, m.auditLog.error())
+		}
+		m.hub().close()
+		m.closeErr = err
+	})
+	return m.closeErr
+}
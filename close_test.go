@@ -0,0 +1,74 @@
+package valuemap
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+var _ io.Closer = (*ValueMap[string, int])(nil)
+
+func TestCloseEndsSubscriptions(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := New[string, int]()
+	all := m.Watch(context.Background(), &mu)
+	one := m.WatchKey(context.Background(), &mu, "a")
+
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, ch := range []<-chan Event[string, int]{all, one} {
+		select {
+		case _, ok := <-ch:
+			if ok {
+				t.Error("received an event after Close")
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("subscription not closed by Close")
+		}
+	}
+	if _, ok := <-m.Watch(context.Background(), &mu); ok {
+		t.Error("Watch after Close returned an open channel")
+	}
+
+	m.Set(&mu, "a", 1)
+	if v, _ := m.Get(&mu, "a"); v != 1 {
+		t.Errorf("Get(a) = %d after Close, want 1", v)
+	}
+}
+
+func TestCloseJournaled(t *testing.T) {
+	mu := sync.RWMutex{}
+	path := filepath.Join(t.TempDir(), "state.json")
+	m, err := OpenJournaled(&mu, path, JSONCodec[string, int]{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Set(&mu, "a", 1)
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path + ".wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.Set(&mu, "b", 2)
+	if after, _ := os.Stat(path + ".wal"); after.Size() != info.Size() {
+		t.Error("a change after Close was journaled")
+	}
+	if err := m.Sync(&mu); !errors.Is(err, ErrClosed) {
+		t.Errorf("Sync() = %v after Close, want ErrClosed", err)
+	}
+	if err := m.Checkpoint(&mu); !errors.Is(err, ErrClosed) {
+		t.Errorf("Checkpoint() = %v after Close, want ErrClosed", err)
+	}
+	if err := m.Close(); err != nil {
+		t.Errorf("second Close() = %v, want the result of the first", err)
+	}
+}
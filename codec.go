@@ -0,0 +1,78 @@
+package valuemap
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// Codec converts the contents of a map to and from a wire format.
+// Encode, Decode, SaveToFile and LoadFromFile accept any Codec, so a new
+// format needs no new methods on ValueMap.
+//
+// Decode must return the complete map or an error. It must not return
+// partially decoded data along with a nil error.
+type Codec[K comparable, V any] interface {
+	Encode(w io.Writer, data map[K]V) error
+	Decode(r io.Reader) (map[K]V, error)
+}
+
+// JSONCodec is a Codec that writes the map as a JSON object with
+// encoding/json, following the key rules described at ValueMap.MarshalJSON.
+type JSONCodec[K comparable, V any] struct{}
+
+// Encode implements Codec.
+func (JSONCodec[K, V]) Encode(w io.Writer, data map[K]V) error {
+	return json.NewEncoder(w).Encode(data)
+}
+
+// Decode implements Codec.
+func (JSONCodec[K, V]) Decode(r io.Reader) (map[K]V, error) {
+	var data map[K]V
+	if err := json.NewDecoder(r).Decode(&data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// GobCodec is a Codec that writes the map with encoding/gob.
+// Interface values must be registered with gob.Register.
+type GobCodec[K comparable, V any] struct{}
+
+// Encode implements Codec.
+func (GobCodec[K, V]) Encode(w io.Writer, data map[K]V) error {
+	return gob.NewEncoder(w).Encode(data)
+}
+
+// Decode implements Codec.
+func (GobCodec[K, V]) Decode(r io.Reader) (map[K]V, error) {
+	var data map[K]V
+	if err := gob.NewDecoder(r).Decode(&data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Encode writes the contents of the map to w with c.
+// The map is copied under the read lock and encoded after it is released,
+// so a slow writer does not block other goroutines.
+//
+// mu is an external mutex to lock the internal map during copying
+func (m *ValueMap[K, V]) Encode(mu *sync.RWMutex, w io.Writer, c Codec[K, V]) error {
+	return c.Encode(w, m.Raw(mu))
+}
+
+// Decode reads a map from r with c and replaces the contents of the map
+// with it. The input is decoded completely before the map is changed,
+// so on error the map is left as it was.
+//
+// mu is an external mutex to lock the internal map during content replacement
+func (m *ValueMap[K, V]) Decode(mu *sync.RWMutex, r io.Reader, c Codec[K, V]) error {
+	data, err := c.Decode(r)
+	if err != nil {
+		return err
+	}
+	m.Replace(mu, data)
+	return nil
+}
@@ -0,0 +1,89 @@
+package valuemap
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCodecs(t *testing.T) {
+	codecs := map[string]Codec[int, string]{
+		"json": JSONCodec[int, string]{},
+		"gob":  GobCodec[int, string]{},
+	}
+	for name, c := range codecs {
+		t.Run(name, func(t *testing.T) {
+			mu := sync.RWMutex{}
+			m := FromMap(map[int]string{1: "one", 2: "two"})
+
+			var buf bytes.Buffer
+			if err := m.Encode(&mu, &buf, c); err != nil {
+				t.Fatal(err)
+			}
+			got := FromMap(map[int]string{3: "three"})
+			if err := got.Decode(&mu, &buf, c); err != nil {
+				t.Fatal(err)
+			}
+			if got.Len(&mu) != 2 {
+				t.Errorf("Len() = %d, want 2", got.Len(&mu))
+			}
+			if v, _ := got.Get(&mu, 2); v != "two" {
+				t.Errorf("Get(2) = %q, want two", v)
+			}
+		})
+	}
+}
+
+func TestDecodeErrorKeepsMap(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := FromMap(map[string]int{"a": 1})
+	if err := m.Decode(&mu, strings.NewReader(`{"b": "x"}`), JSONCodec[string, int]{}); err == nil {
+		t.Fatal("Decode() error = nil, want type error")
+	}
+	if v, ok := m.Get(&mu, "a"); !ok || v != 1 {
+		t.Errorf("Get(a) = %d, %v, want 1, true", v, ok)
+	}
+}
+
+// upperCodec is a custom Codec writing one key=value pair per line.
+type upperCodec struct{}
+
+func (upperCodec) Encode(w io.Writer, data map[string]string) error {
+	for k, v := range data {
+		if _, err := io.WriteString(w, k+"="+strings.ToUpper(v)+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (upperCodec) Decode(r io.Reader) (map[string]string, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data := make(map[string]string)
+	for line := range strings.Lines(string(b)) {
+		k, v, _ := strings.Cut(strings.TrimSpace(line), "=")
+		data[k] = v
+	}
+	return data, nil
+}
+
+func TestCustomCodec(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := FromMap(map[string]string{"a": "x"})
+
+	var buf bytes.Buffer
+	if err := m.Encode(&mu, &buf, upperCodec{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Decode(&mu, &buf, upperCodec{}); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := m.Get(&mu, "a"); v != "X" {
+		t.Errorf("Get(a) = %q, want X", v)
+	}
+}
@@ -0,0 +1,72 @@
+package valuemap
+
+import "sync"
+
+// Compact rebuilds the internal map, and the bookkeeping of expiring and
+// bounded maps, at the size of their current contents. Go maps keep the
+// memory of their largest size, so a map that had most of its entries
+// deleted holds on to it until it is compacted. Compact copies every entry,
+// so it takes time proportional to the size of the map; it is meant to be
+// called after mass deletions, not routinely.
+//
+// mu is an external mutex to lock the internal map during the rebuild
+func (m *ValueMap[K, V]) Compact(mu *sync.RWMutex) {
+	defer m.lock(mu).Unlock()
+	if m.stripes != nil {
+		m.stripes.compact()
+	} else {
+		m.data = shrink(m.data)
+		m.shared = false
+		m.reserved = len(m.data)
+	}
+	if m.ttl != nil {
+		m.ttl.deadlines = shrink(m.ttl.deadlines)
+		m.ttl.lifetimes = shrink(m.ttl.lifetimes)
+	}
+	if m.revs != nil {
+		m.revs.byKey = shrink(m.revs.byKey)
+	}
+	if m.hist != nil {
+		m.hist.byKey = shrink(m.hist.byKey)
+	}
+	if m.tombs != nil {
+		m.tombs.byKey = shrink(m.tombs.byKey)
+	}
+	if m.reverse != nil {
+		m.reverse.compact()
+	}
+	for _, ix := range m.indexes {
+		ix.byValue = shrink(ix.byValue)
+	}
+	if m.bound != nil {
+		if m.bound.costs != nil {
+			m.bound.costs = shrink(m.bound.costs)
+		}
+		if c, ok := m.bound.policy.(compacter); ok {
+			c.compact()
+		}
+	}
+}
+
+// compacter is implemented by the built-in policies, whose bookkeeping
+// is rebuilt by Compact.
+type compacter interface {
+	compact()
+}
+
+func (p *lru[K]) compact() {
+	p.elems = shrink(p.elems)
+}
+
+func (p *tinyLFU[K]) compact() {
+	p.lru.compact()
+}
+
+// shrink returns a copy of m allocated for its current size.
+func shrink[K comparable, V any](m map[K]V) map[K]V {
+	cp := make(map[K]V, len(m))
+	for k, v := range m {
+		cp[k] = v
+	}
+	return cp
+}
@@ -0,0 +1,40 @@
+package valuemap
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestCompact(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := NewBounded[int, [64]byte](0)
+	for i := range 100_000 {
+		m.Set(&mu, i, [64]byte{})
+	}
+	m.DeleteFunc(&mu, func(k int, _ [64]byte) bool { return k >= 10 })
+
+	heap := func() uint64 {
+		runtime.GC()
+		var s runtime.MemStats
+		runtime.ReadMemStats(&s)
+		return s.HeapAlloc
+	}
+	before := heap()
+	m.Compact(&mu)
+	after := heap()
+	if after >= before || before-after < 4<<20 {
+		t.Errorf("heap went from %d to %d bytes, want several MB freed", before, after)
+	}
+
+	if m.Len(&mu) != 10 {
+		t.Errorf("Len() = %d after Compact, want 10", m.Len(&mu))
+	}
+	m.Get(&mu, 0)
+	for i := 10; i < 15; i++ {
+		m.Set(&mu, i, [64]byte{})
+	}
+	if _, ok := m.Get(&mu, 0); !ok {
+		t.Error("key 0 is missing after Compact")
+	}
+}
@@ -0,0 +1,44 @@
+package valuemap
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// GzipCodec is a Codec that compresses the output of another Codec with
+// compress/gzip. It can be used wherever a Codec is accepted, such as
+// SaveToFile, WithAutosave and WithJournal. Maps of long or repetitive
+// strings compress well, but journal records are compressed one by one,
+// so a compressed journal saves little unless the values are large.
+type GzipCodec[K comparable, V any] struct {
+	Codec Codec[K, V]
+	// Level is a compression level of compress/gzip.
+	// Zero means gzip.DefaultCompression.
+	Level int
+}
+
+// Encode implements Codec.
+func (c GzipCodec[K, V]) Encode(w io.Writer, data map[K]V) error {
+	level := c.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	zw, err := gzip.NewWriterLevel(w, level)
+	if err != nil {
+		return err
+	}
+	if err := c.Codec.Encode(zw, data); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// Decode implements Codec.
+func (c GzipCodec[K, V]) Decode(r io.Reader) (map[K]V, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return c.Codec.Decode(zr)
+}
@@ -0,0 +1,66 @@
+package valuemap
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestGzipCodec(t *testing.T) {
+	mu := sync.RWMutex{}
+	path := filepath.Join(t.TempDir(), "data.json.gz")
+	data := make(map[int]string)
+	for i := range 100 {
+		data[i] = strings.Repeat("value ", 20)
+	}
+	m := FromMap(data)
+	c := GzipCodec[int, string]{Codec: JSONCodec[int, string]{}}
+
+	if err := m.SaveToFile(&mu, path, c); err != nil {
+		t.Fatal(err)
+	}
+	var plain bytes.Buffer
+	if err := m.Encode(&mu, &plain, JSONCodec[int, string]{}); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size()*5 > int64(plain.Len()) {
+		t.Errorf("compressed size = %d, want at most a fifth of %d", fi.Size(), plain.Len())
+	}
+
+	got := New[int, string]()
+	if err := got.LoadFromFile(&mu, path, c); err != nil {
+		t.Fatal(err)
+	}
+	if got.Len(&mu) != 100 {
+		t.Errorf("Len() = %d, want 100", got.Len(&mu))
+	}
+}
+
+func TestGzipCodecJournal(t *testing.T) {
+	mu := sync.RWMutex{}
+	path := filepath.Join(t.TempDir(), "data.gz")
+	c := GzipCodec[string, int]{Codec: GobCodec[string, int]{}, Level: 9}
+
+	m, err := OpenJournaled(&mu, path, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Set(&mu, "a", 1)
+	m.Close()
+
+	m, err = OpenJournaled(&mu, path, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if v, _ := m.Get(&mu, "a"); v != 1 {
+		t.Errorf("Get(a) = %d, want 1", v)
+	}
+}
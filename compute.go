@@ -0,0 +1,75 @@
+package valuemap
+
+import (
+	"context"
+	"sync"
+)
+
+// GetOrCompute returns the value of the key if present. Otherwise, it calls
+// compute to build the value, stores it and returns it, or returns the
+// error of compute without storing anything. Unlike ComputeIfAbsent,
+// compute runs without the external mutex locked, so it can be slow, fail
+// and call the other methods of the map.
+//
+// Concurrent calls for the same missing key share a single call of
+// compute, which receives a context with the values of ctx. That context
+// is canceled once every call waiting for the value has given up because
+// its own ctx is done, and those calls return the error of their ctx. If
+// the key is assigned while compute runs, the assigned value is kept and
+// returned instead. For a map created with WithLoader, GetOrCompute does
+// not call the loader.
+//
+// mu is an external mutex to lock the internal map during value retrieval,
+// and while the computed value is stored. It is not held while compute runs
+func (m *ValueMap[K, V]) GetOrCompute(ctx context.Context, mu *sync.RWMutex, key K, compute func(ctx context.Context) (V, error)) (V, error) {
+	v, ok, reload := m.get(mu, key)
+	if reload {
+		m.revalidate(mu, key)
+	}
+	if ok {
+		return v, nil
+	}
+
+	l := m.computers()
+	l.mu.Lock()
+	c, ok := l.calls[key]
+	if !ok || c.waiters == 0 {
+		// No call, or one that every caller gave up on and that was
+		// canceled: start a new one.
+		cctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		c = &loadCall[V]{done: make(chan struct{}), cancel: cancel}
+		l.calls[key] = c
+		go m.run(l, mu, key, c, func() (V, error) {
+			defer cancel()
+			return compute(cctx)
+		})
+	}
+	c.waiters++
+	l.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.value, c.err
+	case <-ctx.Done():
+		l.mu.Lock()
+		if c.waiters--; c.waiters == 0 {
+			c.cancel()
+		}
+		l.mu.Unlock()
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// computers returns the calls of GetOrCompute in progress, creating them
+// on first use.
+func (m *ValueMap[K, V]) computers() *loader[K, V] {
+	if l := m.computes.Load(); l != nil {
+		return l
+	}
+	l := &loader[K, V]{calls: make(map[K]*loadCall[V]), computed: true}
+	if m.computes.CompareAndSwap(nil, l) {
+		return l
+	}
+	return m.computes.Load()
+}
@@ -0,0 +1,78 @@
+package valuemap
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetOrCompute(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := New[string, int]()
+	var calls atomic.Int32
+	release := make(chan struct{})
+	compute := func(ctx context.Context) (int, error) {
+		calls.Add(1)
+		<-release
+		return 1, nil
+	}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := m.GetOrCompute(context.Background(), &mu, "a", compute); v != 1 || err != nil {
+				t.Errorf("GetOrCompute = %d, %v, want 1", v, err)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if n := calls.Load(); n != 1 {
+		t.Errorf("compute called %d times, want 1", n)
+	}
+	if v, _ := m.Get(&mu, "a"); v != 1 {
+		t.Errorf("Get(a) = %d, want the computed 1", v)
+	}
+
+	errFail := errors.New("failed")
+	_, err := m.GetOrCompute(context.Background(), &mu, "b", func(ctx context.Context) (int, error) {
+		return 0, errFail
+	})
+	if !errors.Is(err, errFail) || m.Len(&mu) != 1 {
+		t.Errorf("GetOrCompute error = %v, want %v and nothing stored", err, errFail)
+	}
+}
+
+func TestGetOrComputeCanceled(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := New[string, int]()
+	canceled := make(chan struct{})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := m.GetOrCompute(ctx, &mu, "a", func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		close(canceled)
+		return 0, ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetOrCompute error = %v, want context.DeadlineExceeded", err)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("compute was not canceled")
+	}
+
+	// A later call starts afresh.
+	if v, err := m.GetOrCompute(context.Background(), &mu, "a", func(ctx context.Context) (int, error) {
+		return 2, nil
+	}); v != 2 || err != nil {
+		t.Errorf("GetOrCompute = %d, %v, want 2", v, err)
+	}
+}
@@ -0,0 +1,85 @@
+package valuemap
+
+import "sync"
+
+// Changeset is the difference between two maps: the entries added to the
+// first to get the second, the entries removed from it, and the entries
+// whose value changed.
+type Changeset[K comparable, V any] struct {
+	Added   map[K]V
+	Removed map[K]V
+	Changed map[K]Change[V]
+}
+
+// Change is the old and new value of a changed entry.
+type Change[V any] struct {
+	Old V
+	New V
+}
+
+// Empty reports whether the changeset has no changes.
+func (c Changeset[K, V]) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Changed) == 0
+}
+
+// DiffFunc returns the changes that turn the contents of m into those of
+// other, using eq to compare values. Each map is copied under its own
+// lock, so the changeset is computed from a consistent snapshot of each,
+// and the maps can share a mutex or be the same map.
+//
+// mu is an external mutex to lock the internal map while it is copied,
+// and otherMu likewise for other
+func (m *ValueMap[K, V]) DiffFunc(mu *sync.RWMutex, other *ValueMap[K, V], otherMu *sync.RWMutex, eq func(a, b V) bool) Changeset[K, V] {
+	from := m.Raw(mu)
+	to := other.Raw(otherMu)
+	c := Changeset[K, V]{
+		Added:   make(map[K]V),
+		Removed: make(map[K]V),
+		Changed: make(map[K]Change[V]),
+	}
+	for k, old := range from {
+		v, ok := to[k]
+		switch {
+		case !ok:
+			c.Removed[k] = old
+		case !eq(old, v):
+			c.Changed[k] = Change[V]{Old: old, New: v}
+		}
+	}
+	for k, v := range to {
+		if _, ok := from[k]; !ok {
+			c.Added[k] = v
+		}
+	}
+	return c
+}
+
+// Diff is DiffFunc for comparable values, compared with ==.
+//
+// mu is an external mutex to lock the internal map while it is copied,
+// and otherMu likewise for other
+func Diff[K, V comparable](m *ValueMap[K, V], mu *sync.RWMutex, other *ValueMap[K, V], otherMu *sync.RWMutex) Changeset[K, V] {
+	return m.DiffFunc(mu, other, otherMu, equal[V])
+}
+
+// ApplyPatch applies a changeset to the map in one step: it deletes the
+// removed keys and assigns the added and changed ones their new values, so
+// readers see the map either before or after the whole changeset. Applying
+// the changeset from DiffFunc(mu, other, ...) to m makes it hold the same
+// entries as other had, which can be used to keep a copy of a map in sync
+// by sending changesets over any transport. The current values are not
+// checked against the old values of the changeset.
+//
+// mu is an external mutex to lock the internal map during the changes
+func (m *ValueMap[K, V]) ApplyPatch(mu *sync.RWMutex, c Changeset[K, V]) {
+	defer m.lock(mu).Unlock()
+	for k := range c.Removed {
+		m.remove(k)
+	}
+	for k, v := range c.Added {
+		m.store(k, v)
+	}
+	for k, ch := range c.Changed {
+		m.store(k, ch.New)
+	}
+}
@@ -0,0 +1,48 @@
+package valuemap
+
+import (
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	mu := sync.RWMutex{}
+	old := FromMap(map[string]int{"a": 1, "b": 2, "c": 3})
+	cur := FromMap(map[string]int{"a": 1, "b": 20, "d": 4})
+
+	c := Diff(old, &mu, cur, &mu)
+	if !maps.Equal(c.Added, map[string]int{"d": 4}) {
+		t.Errorf("Added = %v, want d", c.Added)
+	}
+	if !maps.Equal(c.Removed, map[string]int{"c": 3}) {
+		t.Errorf("Removed = %v, want c", c.Removed)
+	}
+	if !maps.Equal(c.Changed, map[string]Change[int]{"b": {Old: 2, New: 20}}) {
+		t.Errorf("Changed = %v, want b", c.Changed)
+	}
+	if c.Empty() || !Diff(old, &mu, old, &mu).Empty() {
+		t.Error("Empty reports the wrong changesets")
+	}
+
+	slicesOld := FromMap(map[string][]int{"a": {1, 2}})
+	slicesCur := FromMap(map[string][]int{"a": {1, 2}})
+	if c := slicesOld.DiffFunc(&mu, slicesCur, &mu, slices.Equal); !c.Empty() {
+		t.Errorf("DiffFunc = %+v, want no changes", c)
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	mu := sync.RWMutex{}
+	replica := FromMap(map[string]int{"a": 1, "b": 2, "c": 3})
+	primary := replica.Clone(&mu)
+	primary.Set(&mu, "b", 20)
+	primary.Delete(&mu, "c")
+	primary.Set(&mu, "d", 4)
+
+	replica.ApplyPatch(&mu, Diff(replica, &mu, primary, &mu))
+	if got, want := replica.Raw(&mu), primary.Raw(&mu); !maps.Equal(got, want) {
+		t.Errorf("patched replica = %v, want %v", got, want)
+	}
+}
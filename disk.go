@@ -0,0 +1,235 @@
+package valuemap
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+)
+
+// Map is the set of methods shared by ValueMap and the maps that keep
+// their entries elsewhere, such as DiskValueMap and MappedValueMap, so that
+// code written against it can switch storage without changing call sites.
+type Map[K comparable, V any] interface {
+	Set(mu *sync.RWMutex, key K, value V)
+	Get(mu *sync.RWMutex, key K) (V, bool)
+	Delete(mu *sync.RWMutex, key K)
+	Len(mu *sync.RWMutex) int
+	Keys(mu *sync.RWMutex) []K
+	Values(mu *sync.RWMutex) []V
+	Range(mu *sync.RWMutex, fn func(key K, value V) bool)
+	Clear(mu *sync.RWMutex)
+}
+
+// Backend is a store of byte keys and values that a DiskValueMap keeps its
+// entries in, usually an embedded key-value database. Slices passed to
+// Put and to the Range callback must not be retained by the callee, and
+// slices returned by Get are owned by the caller.
+type Backend interface {
+	Get(key []byte) (value []byte, ok bool, err error)
+	Put(key, value []byte) error
+	Delete(key []byte) error
+	// Range calls fn for each entry until it returns false.
+	Range(fn func(key, value []byte) bool) error
+	// Clear removes all entries.
+	Clear() error
+	Close() error
+}
+
+// DiskValueMap is a map that keeps its entries in a Backend, so that they
+// are as durable as the backend makes them. It implements Map.
+//
+// Each entry is stored under its encoded key, with the value holding the
+// entry encoded by the Codec as a single-entry map. String and integer keys
+// are stored as their bytes, with integers in big-endian order so that
+// ordered backends keep them sorted, and the empty string and strings
+// starting with a zero byte prefixed with a zero byte. Other keys are
+// stored as JSON. Key types whose JSON form does not tell keys apart are
+// not supported: interfaces, pointers, maps and slices, and structs with
+// unexported fields or with fields of these types.
+//
+// Since the methods of Map do not return errors, the first error of the
+// backend or codec is kept and reported by Err and Close, and the failing
+// operation acts as if the key were absent.
+type DiskValueMap[K comparable, V any] struct {
+	b     Backend
+	codec Codec[K, V]
+	errMu sync.Mutex
+	err   error
+}
+
+// NewDisk returns a new pointer to a DiskValueMap storing its entries in b,
+// encoded with c.
+func NewDisk[K comparable, V any](b Backend, c Codec[K, V]) *DiskValueMap[K, V] {
+	return &DiskValueMap[K, V]{b: b, codec: c}
+}
+
+// fail records the first error met by the map.
+func (m *DiskValueMap[K, V]) fail(err error) {
+	m.errMu.Lock()
+	defer m.errMu.Unlock()
+	if m.err == nil {
+		m.err = err
+	}
+}
+
+// decode returns the entry stored in a backend value.
+func (m *DiskValueMap[K, V]) decode(b []byte) (K, V, bool) {
+	var (
+		key   K
+		value V
+	)
+	entry, err := m.codec.Decode(bytes.NewReader(b))
+	if err != nil {
+		m.fail(err)
+		return key, value, false
+	}
+	if len(entry) != 1 {
+		m.fail(errors.New("valuemap: stored value does not hold one entry"))
+		return key, value, false
+	}
+	for k, v := range entry {
+		key, value = k, v
+	}
+	return key, value, true
+}
+
+// Set assigns a value to a key.
+//
+// mu is an external mutex to lock the internal map during value assigning
+func (m *DiskValueMap[K, V]) Set(mu *sync.RWMutex, key K, value V) {
+	mu.Lock()
+	defer mu.Unlock()
+	k, err := encodeKey(key)
+	if err != nil {
+		m.fail(err)
+		return
+	}
+	var buf bytes.Buffer
+	if err := m.codec.Encode(&buf, map[K]V{key: value}); err != nil {
+		m.fail(err)
+		return
+	}
+	if err := m.b.Put(k, buf.Bytes()); err != nil {
+		m.fail(err)
+	}
+}
+
+// Get retrieves a value by key.
+//
+// mu is an external mutex to lock the internal map during value retrieval
+func (m *DiskValueMap[K, V]) Get(mu *sync.RWMutex, key K) (V, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	var zero V
+	k, err := encodeKey(key)
+	if err != nil {
+		m.fail(err)
+		return zero, false
+	}
+	b, ok, err := m.b.Get(k)
+	if err != nil {
+		m.fail(err)
+		return zero, false
+	}
+	if !ok {
+		return zero, false
+	}
+	_, v, ok := m.decode(b)
+	return v, ok
+}
+
+// Delete removes a key.
+//
+// mu is an external mutex to lock the internal map during key deletion
+func (m *DiskValueMap[K, V]) Delete(mu *sync.RWMutex, key K) {
+	mu.Lock()
+	defer mu.Unlock()
+	k, err := encodeKey(key)
+	if err != nil {
+		m.fail(err)
+		return
+	}
+	if err := m.b.Delete(k); err != nil {
+		m.fail(err)
+	}
+}
+
+// Len returns the number of entries. It walks the whole backend.
+//
+// mu is an external mutex to lock the internal map during length retrieval
+func (m *DiskValueMap[K, V]) Len(mu *sync.RWMutex) int {
+	mu.RLock()
+	defer mu.RUnlock()
+	n := 0
+	if err := m.b.Range(func(_, _ []byte) bool {
+		n++
+		return true
+	}); err != nil {
+		m.fail(err)
+	}
+	return n
+}
+
+// Keys returns a slice of all keys, in the order of the backend.
+//
+// mu is an external mutex to lock the internal map during key retrieval
+func (m *DiskValueMap[K, V]) Keys(mu *sync.RWMutex) []K {
+	var keys []K
+	m.Range(mu, func(k K, _ V) bool {
+		keys = append(keys, k)
+		return true
+	})
+	return keys
+}
+
+// Values returns a slice of all values, in the order of the backend.
+//
+// mu is an external mutex to lock the internal map during value retrieval
+func (m *DiskValueMap[K, V]) Values(mu *sync.RWMutex) []V {
+	var values []V
+	m.Range(mu, func(_ K, v V) bool {
+		values = append(values, v)
+		return true
+	})
+	return values
+}
+
+// Range calls fn sequentially for each key and value present in the map,
+// in the order of the backend. If fn returns false, Range stops the iteration.
+//
+// mu is an external mutex to lock the internal map during iteration,
+// so fn must not call methods that lock mu
+func (m *DiskValueMap[K, V]) Range(mu *sync.RWMutex, fn func(key K, value V) bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if err := m.b.Range(func(_, b []byte) bool {
+		k, v, ok := m.decode(b)
+		return !ok || fn(k, v)
+	}); err != nil {
+		m.fail(err)
+	}
+}
+
+// Clear removes all entries.
+//
+// mu is an external mutex to lock the internal map during clearing
+func (m *DiskValueMap[K, V]) Clear(mu *sync.RWMutex) {
+	mu.Lock()
+	defer mu.Unlock()
+	if err := m.b.Clear(); err != nil {
+		m.fail(err)
+	}
+}
+
+// Err returns the first error met by the map, if any.
+func (m *DiskValueMap[K, V]) Err() error {
+	m.errMu.Lock()
+	defer m.errMu.Unlock()
+	return m.err
+}
+
+// Close closes the backend and returns the first error met by the map
+// or by closing it.
+func (m *DiskValueMap[K, V]) Close() error {
+	return errors.Join(m.Err(), m.b.Close())
+}
@@ -0,0 +1,136 @@
+package valuemap
+
+import (
+	"bytes"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+)
+
+var (
+	_ Map[string, int] = (*ValueMap[string, int])(nil)
+	_ Map[string, int] = (*DiskValueMap[string, int])(nil)
+	_ Map[string, int] = (*MappedValueMap[string, int])(nil)
+)
+
+// memBackend is a Backend keeping entries in memory, ordered by key.
+type memBackend struct {
+	data    map[string][]byte
+	failPut bool
+	closed  bool
+}
+
+func newMemBackend() *memBackend {
+	return &memBackend{data: make(map[string][]byte)}
+}
+
+func (b *memBackend) Get(key []byte) ([]byte, bool, error) {
+	v, ok := b.data[string(key)]
+	return bytes.Clone(v), ok, nil
+}
+
+func (b *memBackend) Put(key, value []byte) error {
+	if b.failPut {
+		return errors.New("backend full")
+	}
+	if len(key) == 0 {
+		return errors.New("key required") // as in bbolt
+	}
+	b.data[string(key)] = bytes.Clone(value)
+	return nil
+}
+
+func (b *memBackend) Delete(key []byte) error {
+	delete(b.data, string(key))
+	return nil
+}
+
+func (b *memBackend) Range(fn func(key, value []byte) bool) error {
+	for _, k := range slices.Sorted(maps.Keys(b.data)) {
+		if !fn([]byte(k), b.data[k]) {
+			break
+		}
+	}
+	return nil
+}
+
+func (b *memBackend) Clear() error {
+	clear(b.data)
+	return nil
+}
+
+func (b *memBackend) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestDiskValueMap(t *testing.T) {
+	mu := sync.RWMutex{}
+	b := newMemBackend()
+	m := NewDisk(b, GobCodec[string, int]{})
+
+	m.Set(&mu, "b", 2)
+	m.Set(&mu, "a", 1)
+	m.Set(&mu, "c", 3)
+	m.Delete(&mu, "c")
+	if v, ok := m.Get(&mu, "a"); !ok || v != 1 {
+		t.Errorf("Get(a) = %d, %v, want 1, true", v, ok)
+	}
+	if _, ok := m.Get(&mu, "c"); ok {
+		t.Error("deleted key c is present")
+	}
+	if keys := m.Keys(&mu); !slices.Equal(keys, []string{"a", "b"}) {
+		t.Errorf("Keys() = %v, want [a b]", keys)
+	}
+	if values := m.Values(&mu); !slices.Equal(values, []int{1, 2}) {
+		t.Errorf("Values() = %v, want [1 2]", values)
+	}
+	if m.Len(&mu) != 2 {
+		t.Errorf("Len() = %d, want 2", m.Len(&mu))
+	}
+	if _, ok := b.data["a"]; !ok {
+		t.Errorf("backend keys = %v, want string keys stored as is", slices.Collect(maps.Keys(b.data)))
+	}
+	m.Clear(&mu)
+	if m.Len(&mu) != 0 {
+		t.Errorf("Len() = %d after Clear, want 0", m.Len(&mu))
+	}
+	if err := m.Close(); err != nil || !b.closed {
+		t.Errorf("Close() = %v, closed = %v, want nil, true", err, b.closed)
+	}
+}
+
+func TestDiskValueMapEmptyKey(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := NewDisk(newMemBackend(), JSONCodec[string, int]{})
+	m.Set(&mu, "", 1)
+	m.Set(&mu, "\x00", 2)
+	if v, ok := m.Get(&mu, ""); !ok || v != 1 {
+		t.Errorf("Get(\"\") = %d, %v, want 1, true", v, ok)
+	}
+	if m.Len(&mu) != 2 {
+		t.Errorf("Len() = %d, want 2", m.Len(&mu))
+	}
+	if err := m.Err(); err != nil {
+		t.Errorf("Err() = %v", err)
+	}
+}
+
+func TestDiskValueMapError(t *testing.T) {
+	mu := sync.RWMutex{}
+	b := newMemBackend()
+	b.failPut = true
+	m := NewDisk(b, JSONCodec[string, int]{})
+	m.Set(&mu, "a", 1)
+	if _, ok := m.Get(&mu, "a"); ok {
+		t.Error("failed Set stored the key")
+	}
+	if m.Err() == nil {
+		t.Error("Err() = nil, want the backend error")
+	}
+	if m.Close() == nil {
+		t.Error("Close() = nil, want the backend error")
+	}
+}
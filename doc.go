@@ -0,0 +1,25 @@
+// Package valuemap provides generic maps that are safe for concurrent use.
+//
+// # Locking
+//
+// A ValueMap does not own a lock. Each method takes an external
+// *sync.RWMutex and locks it for the duration of the call, so the caller
+// decides which data shares a lock. A struct holding several maps and other
+// fields can guard all of them with one mutex and still use the map methods.
+// The same mutex must be passed to every method of a given map.
+//
+// Maps created with WithShards stripe their entries over shards that have
+// locks of their own. The methods on a single key hold the mutex only for
+// reading and lock the shard of their key, so they run in parallel, while
+// the other methods hold it for writing. Holding the mutex for writing thus
+// still excludes every other operation on the map. Workloads that need no
+// such mutex at all can use a ShardedValueMap, which owns one lock per
+// shard and takes no mutex.
+//
+// # Adapters
+//
+// The packages that integrate maps with third-party libraries, such as
+// valuemapredis or valuemapzstd, are modules of their own, so that programs
+// depend only on the libraries they use. Each requires the release of this
+// module it was tagged with.
+package valuemap
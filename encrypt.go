@@ -0,0 +1,132 @@
+package valuemap
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrDecrypt is returned when encrypted data is damaged, was encrypted with
+// another key, or was tampered with.
+var ErrDecrypt = errors.New("valuemap: cannot decrypt data")
+
+// KeyProvider supplies the keys of an EncryptedCodec. Each key has an ID
+// stored next to the data it encrypts, so keys can be rotated: data is
+// encrypted with the current key and decrypted with the key of its ID.
+type KeyProvider interface {
+	// CurrentKey returns the key to encrypt with and its ID.
+	// The ID must not be longer than 255 bytes.
+	CurrentKey() (id string, key []byte, err error)
+	// Key returns the key with the given ID.
+	Key(id string) ([]byte, error)
+}
+
+// StaticKey returns a KeyProvider holding the single key with an empty ID.
+func StaticKey(key []byte) KeyProvider {
+	return staticKey(key)
+}
+
+type staticKey []byte
+
+func (k staticKey) CurrentKey() (string, []byte, error) {
+	return "", k, nil
+}
+
+func (k staticKey) Key(id string) ([]byte, error) {
+	if id != "" {
+		return nil, fmt.Errorf("valuemap: unknown key %q", id)
+	}
+	return k, nil
+}
+
+// EncryptedCodec is a Codec that encrypts the output of another Codec with
+// an AEAD cipher, so that snapshots and journals do not reach the disk in
+// plaintext. It can be used wherever a Codec is accepted, such as
+// SaveToFile, WithAutosave and WithJournal. Wrap a compressing codec in it,
+// not the other way round, since encrypted data does not compress.
+//
+// The output is the length of the key ID as one byte, the key ID, a random
+// nonce and the sealed data. The key ID is authenticated along with the data.
+type EncryptedCodec[K comparable, V any] struct {
+	Codec Codec[K, V]
+	Keys  KeyProvider
+	// NewAEAD creates the cipher for a key. It is nil for AES-GCM, which
+	// takes 16, 24 or 32 byte keys. chacha20poly1305.New of
+	// golang.org/x/crypto can be used as is.
+	NewAEAD func(key []byte) (cipher.AEAD, error)
+}
+
+func (c EncryptedCodec[K, V]) aead(key []byte) (cipher.AEAD, error) {
+	if c.NewAEAD != nil {
+		return c.NewAEAD(key)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encode implements Codec.
+func (c EncryptedCodec[K, V]) Encode(w io.Writer, data map[K]V) error {
+	id, key, err := c.Keys.CurrentKey()
+	if err != nil {
+		return err
+	}
+	if len(id) > 255 {
+		return fmt.Errorf("valuemap: key ID %q is too long", id)
+	}
+	aead, err := c.aead(key)
+	if err != nil {
+		return err
+	}
+	var plain bytes.Buffer
+	if err := c.Codec.Encode(&plain, data); err != nil {
+		return err
+	}
+
+	out := make([]byte, 0, 1+len(id)+aead.NonceSize()+plain.Len()+aead.Overhead())
+	out = append(out, byte(len(id)))
+	out = append(out, id...)
+	nonce := out[len(out) : len(out)+aead.NonceSize()]
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	out = out[:len(out)+len(nonce)]
+	out = aead.Seal(out, nonce, plain.Bytes(), []byte(id))
+	_, err = w.Write(out)
+	return err
+}
+
+// Decode implements Codec.
+func (c EncryptedCodec[K, V]) Decode(r io.Reader) (map[K]V, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < 1 || len(b) < 1+int(b[0]) {
+		return nil, ErrDecrypt
+	}
+	id := string(b[1 : 1+b[0]])
+	b = b[1+len(id):]
+	key, err := c.Keys.Key(id)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := c.aead(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	plain, err := aead.Open(nil, b[:aead.NonceSize()], b[aead.NonceSize():], []byte(id))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return c.Codec.Decode(bytes.NewReader(plain))
+}
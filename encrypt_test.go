@@ -0,0 +1,102 @@
+package valuemap
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+type rotatingKeys struct {
+	current string
+	keys    map[string][]byte
+}
+
+func (k *rotatingKeys) CurrentKey() (string, []byte, error) {
+	return k.current, k.keys[k.current], nil
+}
+
+func (k *rotatingKeys) Key(id string) ([]byte, error) {
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", id)
+	}
+	return key, nil
+}
+
+func TestEncryptedCodec(t *testing.T) {
+	mu := sync.RWMutex{}
+	path := filepath.Join(t.TempDir(), "tokens")
+	keys := &rotatingKeys{current: "k1", keys: map[string][]byte{
+		"k1": bytes.Repeat([]byte{1}, 32),
+		"k2": bytes.Repeat([]byte{2}, 32),
+	}}
+	c := EncryptedCodec[string, string]{Codec: JSONCodec[string, string]{}, Keys: keys}
+	m := FromMap(map[string]string{"alice": "secret-token"})
+
+	if err := m.SaveToFile(&mu, path, c); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(b, []byte("secret-token")) {
+		t.Error("file holds the value in plaintext")
+	}
+
+	keys.current = "k2"
+	got := New[string, string]()
+	if err := got.LoadFromFile(&mu, path, c); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := got.Get(&mu, "alice"); v != "secret-token" {
+		t.Errorf("Get(alice) = %q, want secret-token", v)
+	}
+
+	b[len(b)-1] ^= 1
+	if _, err := c.Decode(bytes.NewReader(b)); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Decode(tampered) error = %v, want ErrDecrypt", err)
+	}
+}
+
+func TestEncryptedCodecCustomAEAD(t *testing.T) {
+	mu := sync.RWMutex{}
+	var used int
+	c := EncryptedCodec[string, int]{
+		Codec: GobCodec[string, int]{},
+		Keys:  StaticKey(bytes.Repeat([]byte{7}, 16)),
+		NewAEAD: func(key []byte) (cipher.AEAD, error) {
+			used++
+			block, err := aes.NewCipher(key)
+			if err != nil {
+				return nil, err
+			}
+			return cipher.NewGCMWithRandomNonce(block)
+		},
+	}
+	path := filepath.Join(t.TempDir(), "data")
+	m, err := OpenJournaled(&mu, path, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Set(&mu, "a", 1)
+	m.Close()
+
+	m, err = OpenJournaled(&mu, path, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if v, _ := m.Get(&mu, "a"); v != 1 {
+		t.Errorf("Get(a) = %d, want 1", v)
+	}
+	if used == 0 {
+		t.Error("NewAEAD was not used")
+	}
+}
@@ -0,0 +1,62 @@
+package valuemap
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+)
+
+// Entry is a key-value pair of a ValueMap.
+type Entry[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// FromEntries returns a new ValueMap initialized with the given entries.
+// Later entries overwrite earlier entries with the same key.
+func FromEntries[K comparable, V any](entries []Entry[K, V]) *ValueMap[K, V] {
+	data := make(map[K]V, len(entries))
+	for _, e := range entries {
+		data[e.Key] = e.Value
+	}
+	return &ValueMap[K, V]{data: data}
+}
+
+// Entries returns a slice of all key-value pairs.
+//
+// mu is an external mutex to lock the internal map during entry retrieval
+func (m *ValueMap[K, V]) Entries(mu *sync.RWMutex) []Entry[K, V] {
+	defer m.readLock(mu).Unlock()
+	entries := make([]Entry[K, V], 0, m.size())
+	for k, v := range m.live() {
+		entries = append(entries, Entry[K, V]{Key: k, Value: v})
+	}
+	return entries
+}
+
+// SortedEntries returns a slice of all key-value pairs sorted by key,
+// using less to order the keys.
+//
+// mu is an external mutex to lock the internal map during entry retrieval
+func (m *ValueMap[K, V]) SortedEntries(mu *sync.RWMutex, less func(a, b K) bool) []Entry[K, V] {
+	entries := m.Entries(mu)
+	slices.SortFunc(entries, func(a, b Entry[K, V]) int {
+		switch {
+		case less(a.Key, b.Key):
+			return -1
+		case less(b.Key, a.Key):
+			return 1
+		}
+		return 0
+	})
+	return entries
+}
+
+// SortedKeys returns a slice of all keys in ascending order.
+//
+// mu is an external mutex to lock the internal map during key retrieval
+func SortedKeys[K cmp.Ordered, V any](m *ValueMap[K, V], mu *sync.RWMutex) []K {
+	keys := m.Keys(mu)
+	slices.Sort(keys)
+	return keys
+}
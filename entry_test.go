@@ -0,0 +1,46 @@
+package valuemap
+
+import (
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestEntries(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := FromEntries([]Entry[string, int]{
+		{Key: "a", Value: 1},
+		{Key: "b", Value: 2},
+		{Key: "a", Value: 3},
+	})
+
+	if v, _ := m.Get(&mu, "a"); v != 3 {
+		t.Errorf("Get(a) = %d, want 3", v)
+	}
+	entries := m.Entries(&mu)
+	if len(entries) != 2 {
+		t.Fatalf("Entries = %v, want 2 entries", entries)
+	}
+	for _, e := range entries {
+		if v, _ := m.Get(&mu, e.Key); v != e.Value {
+			t.Errorf("entry %v does not match map value %d", e, v)
+		}
+	}
+}
+
+func TestSorted(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := FromMap(map[string]int{"b": 2, "c": 3, "a": 1})
+
+	if keys := SortedKeys(m, &mu); !slices.Equal(keys, []string{"a", "b", "c"}) {
+		t.Errorf("SortedKeys = %v", keys)
+	}
+
+	entries := m.SortedEntries(&mu, func(a, b string) bool {
+		return a > b
+	})
+	want := []Entry[string, int]{{"c", 3}, {"b", 2}, {"a", 1}}
+	if !slices.Equal(entries, want) {
+		t.Errorf("SortedEntries = %v, want %v", entries, want)
+	}
+}
@@ -0,0 +1,57 @@
+package valuemap
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"sync"
+)
+
+// PublishExpvar publishes the map as an expvar variable with the given name,
+// so that it appears under /debug/vars. The variable is a JSON object with
+// the number of entries and the operation counters of the map: hits and
+// misses of Get, GetMany, GetOrSet and ComputeIfAbsent, values set, and
+// entries deleted, evicted, expired and cleared.
+//
+// If the map holds at most maxEntries entries, the object also includes them
+// under "entries", keyed by their fmt representation. Values that cannot be
+// encoded as JSON are written in their fmt representation too. A maxEntries
+// of zero or less never includes them.
+//
+// Like expvar.Publish, PublishExpvar panics if the name is already in use.
+//
+// mu is an external mutex to lock the internal map whenever the variable is read
+func (m *ValueMap[K, V]) PublishExpvar(mu *sync.RWMutex, name string, maxEntries int) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return m.expvar(mu, maxEntries)
+	}))
+}
+
+func (m *ValueMap[K, V]) expvar(mu *sync.RWMutex, maxEntries int) map[string]any {
+	defer m.rlock(mu).Unlock()
+	n := m.count()
+	st := m.Stats(false)
+	v := map[string]any{
+		"len":         n,
+		"gets":        st.Gets,
+		"hits":        st.Hits,
+		"misses":      st.Misses,
+		"sets":        st.Sets,
+		"deletes":     st.Deletes,
+		"evictions":   st.Evictions,
+		"expirations": st.Expirations,
+		"clears":      st.Clears,
+	}
+	if n > 0 && n <= maxEntries {
+		entries := make(map[string]any, n)
+		for k, e := range m.live() {
+			if b, err := json.Marshal(e); err == nil {
+				entries[fmt.Sprint(k)] = json.RawMessage(b)
+			} else {
+				entries[fmt.Sprint(k)] = fmt.Sprint(e)
+			}
+		}
+		v["entries"] = entries
+	}
+	return v
+}
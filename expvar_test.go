@@ -0,0 +1,58 @@
+package valuemap
+
+import (
+	"encoding/json"
+	"expvar"
+	"sync"
+	"testing"
+)
+
+func TestPublishExpvar(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := New[int, string]()
+	m.PublishExpvar(&mu, "valuemap_test_small", 2)
+
+	m.Set(&mu, 1, "one")
+	m.Set(&mu, 2, "two")
+	m.Get(&mu, 1)
+	m.Get(&mu, 3)
+	m.Delete(&mu, 2)
+
+	var got struct {
+		Len     int               `json:"len"`
+		Hits    uint64            `json:"hits"`
+		Misses  uint64            `json:"misses"`
+		Sets    uint64            `json:"sets"`
+		Deletes uint64            `json:"deletes"`
+		Entries map[string]string `json:"entries"`
+	}
+	s := expvar.Get("valuemap_test_small").String()
+	if err := json.Unmarshal([]byte(s), &got); err != nil {
+		t.Fatalf("decoding %s: %v", s, err)
+	}
+	if got.Len != 1 || got.Hits != 1 || got.Misses != 1 || got.Sets != 2 || got.Deletes != 1 {
+		t.Errorf("variable = %s, want len 1, 1 hit, 1 miss, 2 sets and 1 delete", s)
+	}
+	if got.Entries["1"] != "one" || len(got.Entries) != 1 {
+		t.Errorf("entries = %v, want map[1:one]", got.Entries)
+	}
+
+	m.Set(&mu, 2, "two")
+	m.Set(&mu, 3, "three")
+	var large map[string]any
+	if err := json.Unmarshal([]byte(expvar.Get("valuemap_test_small").String()), &large); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := large["entries"]; ok {
+		t.Error("entries are published for a map larger than maxEntries")
+	}
+}
+
+func TestPublishExpvarUnencodable(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := FromMap(map[string]chan int{"c": nil})
+	m.PublishExpvar(&mu, "valuemap_test_chan", 10)
+	if s := expvar.Get("valuemap_test_chan").String(); !json.Valid([]byte(s)) {
+		t.Errorf("variable %q is not valid JSON", s)
+	}
+}
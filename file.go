@@ -0,0 +1,92 @@
+package valuemap
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// SaveToFile writes the contents of the map to the file at path with c.
+//
+// The map is encoded into a temporary file in the same directory, which is
+// synced and then renamed over path, so readers of path see either the old
+// or the new contents and never a partial write. The directory is synced
+// after the rename, so the new contents survive a crash once SaveToFile
+// returns. A new file gets mode 0644; an existing file keeps its mode.
+//
+// mu is an external mutex to lock the internal map during copying
+func (m *ValueMap[K, V]) SaveToFile(mu *sync.RWMutex, path string, c Codec[K, V]) error {
+	return writeFile(path, func(f *os.File) error {
+		return m.Encode(mu, f, c)
+	})
+}
+
+// LoadFromFile reads the file at path with c and replaces the contents of
+// the map with it. The file is decoded completely before the map is changed,
+// so on error, including when the file does not exist, the map is left as
+// it was.
+//
+// mu is an external mutex to lock the internal map during content replacement
+func (m *ValueMap[K, V]) LoadFromFile(mu *sync.RWMutex, path string, c Codec[K, V]) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return m.Decode(mu, f, c)
+}
+
+// writeFile atomically replaces the file at path with the output of write.
+func writeFile(path string, write func(f *os.File) error) (err error) {
+	mode := fs.FileMode(0o644)
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode().Perm()
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	if err = write(f); err != nil {
+		return err
+	}
+	if err = f.Chmod(mode); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(f.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// syncDir syncs a directory, so that a rename in it is durable.
+// Directories cannot be synced on Windows, so it does nothing there.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
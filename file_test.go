@@ -0,0 +1,84 @@
+package valuemap
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestSaveLoadFile(t *testing.T) {
+	mu := sync.RWMutex{}
+	path := filepath.Join(t.TempDir(), "table.json")
+	m := FromMap(map[string]int{"a": 1, "b": 2})
+
+	if err := m.SaveToFile(&mu, path, JSONCodec[string, int]{}); err != nil {
+		t.Fatal(err)
+	}
+	m.Set(&mu, "c", 3)
+	if err := m.SaveToFile(&mu, path, JSONCodec[string, int]{}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := FromMap(map[string]int{"z": 26})
+	if err := got.LoadFromFile(&mu, path, JSONCodec[string, int]{}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Len(&mu) != 3 {
+		t.Errorf("Len() = %d, want 3", got.Len(&mu))
+	}
+	if v, _ := got.Get(&mu, "c"); v != 3 {
+		t.Errorf("Get(c) = %d, want 3", v)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory has %d entries, want only the saved file", len(entries))
+	}
+}
+
+type failingCodec struct{ JSONCodec[string, int] }
+
+func (failingCodec) Encode(w io.Writer, data map[string]int) error {
+	io.WriteString(w, "{")
+	return errors.New("encode failed")
+}
+
+func TestSaveToFileFailureKeepsFile(t *testing.T) {
+	mu := sync.RWMutex{}
+	path := filepath.Join(t.TempDir(), "table.json")
+	m := FromMap(map[string]int{"a": 1})
+	if err := m.SaveToFile(&mu, path, JSONCodec[string, int]{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.SaveToFile(&mu, path, failingCodec{}); err == nil {
+		t.Fatal("SaveToFile() error = nil, want encode error")
+	}
+	got := New[string, int]()
+	if err := got.LoadFromFile(&mu, path, JSONCodec[string, int]{}); err != nil {
+		t.Fatalf("previous file was damaged: %v", err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("directory has %d entries, want the temporary file removed", len(entries))
+	}
+}
+
+func TestLoadFromFileMissing(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := FromMap(map[string]int{"a": 1})
+	err := m.LoadFromFile(&mu, filepath.Join(t.TempDir(), "missing"), JSONCodec[string, int]{})
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("LoadFromFile() error = %v, want fs.ErrNotExist", err)
+	}
+	if m.Len(&mu) != 1 {
+		t.Errorf("Len() = %d, want 1", m.Len(&mu))
+	}
+}
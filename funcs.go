@@ -0,0 +1,117 @@
+package valuemap
+
+import "sync"
+
+// DeleteFunc removes every entry for which del returns true
+// and returns how many were removed.
+//
+// mu is an external mutex to lock the internal map during deletion,
+// so del must not call methods that lock mu
+func (m *ValueMap[K, V]) DeleteFunc(mu *sync.RWMutex, del func(key K, value V) bool) int {
+	defer m.lock(mu).Unlock()
+	n := 0
+	for k, v := range m.live() {
+		if del(k, v) {
+			m.remove(k)
+			n++
+		}
+	}
+	return n
+}
+
+// Filter returns a new ValueMap containing the entries for which keep returns true.
+//
+// mu is an external mutex to lock the internal map during filtering,
+// so keep must not call methods that lock mu
+func (m *ValueMap[K, V]) Filter(mu *sync.RWMutex, keep func(key K, value V) bool) *ValueMap[K, V] {
+	defer m.readLock(mu).Unlock()
+	cp := make(map[K]V)
+	for k, v := range m.live() {
+		if keep(k, v) {
+			cp[k] = v
+		}
+	}
+	return &ValueMap[K, V]{data: cp}
+}
+
+// MapValues returns a new ValueMap with the same keys as m and
+// the values produced by fn.
+//
+// mu is an external mutex to lock the internal map of m during the transformation,
+// so fn must not call methods that lock mu
+func MapValues[K comparable, V, V2 any](m *ValueMap[K, V], mu *sync.RWMutex, fn func(key K, value V) V2) *ValueMap[K, V2] {
+	defer m.readLock(mu).Unlock()
+	cp := make(map[K]V2, m.size())
+	for k, v := range m.live() {
+		cp[k] = fn(k, v)
+	}
+	return &ValueMap[K, V2]{data: cp}
+}
+
+// Reduce folds fn over the entries of m, starting from initial,
+// and returns the accumulated result. Entries are visited in no particular order.
+//
+// mu is an external mutex to lock the internal map of m during the fold,
+// so fn must not call methods that lock mu
+func Reduce[K comparable, V, A any](m *ValueMap[K, V], mu *sync.RWMutex, initial A, fn func(acc A, key K, value V) A) A {
+	defer m.readLock(mu).Unlock()
+	acc := initial
+	for k, v := range m.live() {
+		acc = fn(acc, k, v)
+	}
+	return acc
+}
+
+// Find returns the first entry found for which pred returns true.
+// Since map order is unspecified, any matching entry may be returned.
+//
+// mu is an external mutex to lock the internal map during the search,
+// so pred must not call methods that lock mu
+func (m *ValueMap[K, V]) Find(mu *sync.RWMutex, pred func(key K, value V) bool) (K, V, bool) {
+	defer m.readLock(mu).Unlock()
+	for k, v := range m.live() {
+		if pred(k, v) {
+			return k, v, true
+		}
+	}
+	var (
+		zk K
+		zv V
+	)
+	return zk, zv, false
+}
+
+// Any reports whether pred returns true for at least one entry.
+//
+// mu is an external mutex to lock the internal map during the search,
+// so pred must not call methods that lock mu
+func (m *ValueMap[K, V]) Any(mu *sync.RWMutex, pred func(key K, value V) bool) bool {
+	_, _, ok := m.Find(mu, pred)
+	return ok
+}
+
+// Every reports whether pred returns true for all entries.
+// It returns true for an empty map.
+//
+// mu is an external mutex to lock the internal map during the search,
+// so pred must not call methods that lock mu
+func (m *ValueMap[K, V]) Every(mu *sync.RWMutex, pred func(key K, value V) bool) bool {
+	return !m.Any(mu, func(k K, v V) bool {
+		return !pred(k, v)
+	})
+}
+
+// CountFunc returns the number of entries for which pred returns true.
+//
+// mu is an external mutex to lock the internal map during counting,
+// so pred must not call methods that lock mu
+func (m *ValueMap[K, V]) CountFunc(mu *sync.RWMutex, pred func(key K, value V) bool) int {
+	defer m.readLock(mu).Unlock()
+	n := 0
+	for k, v := range m.live() {
+		if pred(k, v) {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,99 @@
+package valuemap
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestDeleteFunc(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := FromMap(map[string]int{"a": 1, "b": 2, "c": 3, "d": 4})
+
+	n := m.DeleteFunc(&mu, func(_ string, v int) bool {
+		return v%2 == 0
+	})
+	if n != 2 {
+		t.Errorf("DeleteFunc = %d, want 2", n)
+	}
+	if _, ok := m.Get(&mu, "b"); ok {
+		t.Error("key b still present")
+	}
+	if n := m.Len(&mu); n != 2 {
+		t.Errorf("Len = %d, want 2", n)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := FromMap(map[string]int{"a": 1, "b": 2, "c": 3})
+
+	f := m.Filter(&mu, func(_ string, v int) bool {
+		return v > 1
+	})
+	if n := f.Len(&mu); n != 2 {
+		t.Errorf("Filter Len = %d, want 2", n)
+	}
+	if n := m.Len(&mu); n != 3 {
+		t.Errorf("source Len = %d, want 3", n)
+	}
+}
+
+func TestMapValues(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := FromMap(map[string]string{"a": "1", "b": "22"})
+
+	lens := MapValues(m, &mu, func(_ string, v string) int {
+		return len(v)
+	})
+	if v, _ := lens.Get(&mu, "b"); v != 2 {
+		t.Errorf("Get(b) = %d, want 2", v)
+	}
+	if n := lens.Len(&mu); n != 2 {
+		t.Errorf("Len = %d, want 2", n)
+	}
+}
+
+func TestReduce(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := FromMap(map[string]int{"a": 1, "b": 2, "c": 3})
+
+	sum := Reduce(m, &mu, 0, func(acc int, _ string, v int) int {
+		return acc + v
+	})
+	if sum != 6 {
+		t.Errorf("Reduce = %d, want 6", sum)
+	}
+}
+
+func TestFind(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := FromMap(map[string]int{"a": 1, "b": 2, "c": 3})
+
+	k, v, ok := m.Find(&mu, func(_ string, v int) bool {
+		return v == 2
+	})
+	if !ok || k != "b" || v != 2 {
+		t.Errorf("Find = %q, %d, %v; want b, 2, true", k, v, ok)
+	}
+	if m.Any(&mu, func(_ string, v int) bool { return v > 3 }) {
+		t.Error("Any(v > 3) = true")
+	}
+	if !m.Every(&mu, func(_ string, v int) bool { return v > 0 }) {
+		t.Error("Every(v > 0) = false")
+	}
+	if m.Every(&mu, func(_ string, v int) bool { return v > 1 }) {
+		t.Error("Every(v > 1) = true")
+	}
+}
+
+func TestCountFunc(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := FromMap(map[string]bool{"a": true, "b": false, "c": true})
+
+	n := m.CountFunc(&mu, func(_ string, active bool) bool {
+		return active
+	})
+	if n != 2 {
+		t.Errorf("CountFunc = %d, want 2", n)
+	}
+}
@@ -0,0 +1,37 @@
+package valuemap
+
+import (
+	"bytes"
+	"encoding/gob"
+	"maps"
+)
+
+// GobEncode encodes the contents of the map with encoding/gob, so that
+// a ValueMap can be sent over net/rpc or stored with gob.
+//
+// Since gob.GobEncoder methods cannot take the external mutex, GobEncode
+// does not lock the map. Callers must hold the read lock of the mutex
+// guarding it.
+func (m *ValueMap[K, V]) GobEncode() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(maps.Collect(m.live())); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GobDecode replaces the contents of the map with data encoded by GobEncode.
+// The data is decoded completely before the map is changed, so on error
+// the map is left as it was.
+//
+// Since gob.GobDecoder methods cannot take the external mutex, GobDecode
+// does not lock the map. Callers must hold the write lock of the mutex
+// guarding it.
+func (m *ValueMap[K, V]) GobDecode(b []byte) error {
+	var data map[K]V
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&data); err != nil {
+		return err
+	}
+	m.replace(data)
+	return nil
+}
@@ -0,0 +1,33 @@
+package valuemap
+
+import (
+	"bytes"
+	"encoding/gob"
+	"sync"
+	"testing"
+)
+
+func TestGob(t *testing.T) {
+	type payload struct {
+		Name  string
+		Items *ValueMap[int, string]
+	}
+
+	mu := sync.RWMutex{}
+	in := payload{Name: "p", Items: FromMap(map[int]string{1: "one", 2: "two"})}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(in); err != nil {
+		t.Fatal(err)
+	}
+	var out payload
+	if err := gob.NewDecoder(&buf).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := out.Items.Get(&mu, 2); v != "two" {
+		t.Errorf("Get(2) = %q, want two", v)
+	}
+	if n := out.Items.Len(&mu); n != 2 {
+		t.Errorf("Len = %d, want 2", n)
+	}
+}
@@ -0,0 +1,78 @@
+package valuemap
+
+import (
+	"slices"
+	"sync"
+)
+
+// history holds the previous values of the keys of a map created with
+// WithHistory, oldest first.
+type history[K comparable, V any] struct {
+	depth int
+	byKey map[K][]V
+}
+
+// WithHistory makes the map keep the last depth values of every key that
+// were overwritten or deleted, so that History lists them and Undo restores
+// them. Evicted and expired keys, and Clear, drop the history of the keys
+// they remove, since the map no longer knows their value. A depth of zero
+// or less keeps no history.
+func WithHistory(depth int) Option {
+	return optionFunc(func(c *config) {
+		c.historyDepth = depth
+	})
+}
+
+// record adds the value a key had before a change to its history.
+// The caller must hold the write lock.
+func (h *history[K, V]) record(key K, old V) {
+	vs := append(h.byKey[key], old)
+	if len(vs) > h.depth {
+		vs = slices.Delete(vs, 0, len(vs)-h.depth)
+	}
+	h.byKey[key] = vs
+}
+
+// History returns the previous values of a key, oldest first, not
+// including the current one. It returns nil for keys without history and
+// for maps created without WithHistory.
+//
+// mu is an external mutex to lock the internal map during history retrieval
+func (m *ValueMap[K, V]) History(mu *sync.RWMutex, key K) []V {
+	defer m.readLock(mu).Unlock()
+	if m.hist == nil {
+		return nil
+	}
+	return slices.Clone(m.hist.byKey[key])
+}
+
+// Undo assigns a key the value it had before its last change, removing
+// that value from its history, and reports whether there was one. A
+// deleted key is restored. Undo is an assignment like Set: it is journaled,
+// written to the store and reported to watchers, but the value it replaces
+// is discarded rather than added to the history, so repeated calls walk
+// back through the history. Maps created without WithHistory have nothing
+// to undo.
+//
+// mu is an external mutex to lock the internal map during the restoration
+func (m *ValueMap[K, V]) Undo(mu *sync.RWMutex, key K) bool {
+	defer m.lock(mu).Unlock()
+	if m.hist == nil {
+		return false
+	}
+	vs := m.hist.byKey[key]
+	if len(vs) == 0 {
+		return false
+	}
+	prev := vs[len(vs)-1]
+	vs = vs[:len(vs)-1]
+	m.hist.byKey[key] = vs
+	m.store(key, prev)
+	// store added the discarded value to the history; drop it.
+	if len(vs) == 0 {
+		delete(m.hist.byKey, key)
+	} else {
+		m.hist.byKey[key] = vs
+	}
+	return true
+}
@@ -0,0 +1,74 @@
+package valuemap
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := New[string, int](WithHistory(3))
+	for v := 1; v <= 5; v++ {
+		m.Set(&mu, "a", v)
+	}
+	if got := m.History(&mu, "a"); !slices.Equal(got, []int{2, 3, 4}) {
+		t.Errorf("History(a) = %v, want [2 3 4]", got)
+	}
+
+	if !m.Undo(&mu, "a") {
+		t.Fatal("Undo(a) = false")
+	}
+	if v, _ := m.Get(&mu, "a"); v != 4 {
+		t.Errorf("Get(a) = %d after Undo, want 4", v)
+	}
+	if got := m.History(&mu, "a"); !slices.Equal(got, []int{2, 3}) {
+		t.Errorf("History(a) = %v after Undo, want [2 3]", got)
+	}
+
+	// A deleted key is restored.
+	m.Delete(&mu, "a")
+	if !m.Undo(&mu, "a") {
+		t.Fatal("Undo(a) = false after Delete")
+	}
+	if v, ok := m.Get(&mu, "a"); v != 4 || !ok {
+		t.Errorf("Get(a) = %d, %v after undoing Delete, want 4", v, ok)
+	}
+	m.Undo(&mu, "a")
+	m.Undo(&mu, "a")
+	if v, _ := m.Get(&mu, "a"); v != 2 || m.Undo(&mu, "a") {
+		t.Errorf("Get(a) = %d with the history exhausted, want 2 and no more undo", v)
+	}
+	if m.History(&mu, "a") != nil {
+		t.Errorf("History(a) = %v, want nil", m.History(&mu, "a"))
+	}
+
+	m.Set(&mu, "b", 1)
+	m.Set(&mu, "b", 2)
+	m.Clear(&mu)
+	if m.Undo(&mu, "b") {
+		t.Error("Undo(b) = true after Clear")
+	}
+	if New[string, int]().Undo(&mu, "a") {
+		t.Error("Undo = true without WithHistory")
+	}
+}
+
+func TestHistoryExpired(t *testing.T) {
+	mu := sync.RWMutex{}
+	clock := NewManualClock(time.Unix(0, 0))
+	m := New[string, int](WithHistory(3), WithTTL(&mu, time.Minute, 0), WithClock(clock))
+	m.Set(&mu, "a", 1)
+	m.Set(&mu, "a", 2)
+	clock.Advance(time.Minute)
+
+	// Overwriting an expired entry drops its history, as removing it does.
+	m.Set(&mu, "a", 3)
+	if got := m.History(&mu, "a"); got != nil {
+		t.Errorf("History(a) = %v after expiry, want nil", got)
+	}
+	if m.Undo(&mu, "a") {
+		t.Error("Undo(a) restored a value from before the expiry")
+	}
+}
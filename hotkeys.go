@@ -0,0 +1,116 @@
+package valuemap
+
+import (
+	"cmp"
+	"container/heap"
+	"slices"
+	"sync"
+)
+
+// HotKey is a key and the number of times it was looked up, as returned
+// by HotKeys.
+type HotKey[K comparable] struct {
+	Key   K
+	Count uint64
+}
+
+// WithHotKeys makes the map track the keys looked up most often by Get,
+// GetMany, GetOrSet and ComputeIfAbsent, for HotKeys to report. It keeps
+// capacity counters with the Space-Saving algorithm: a key that is not
+// tracked takes over the counter of the least counted key when all are in
+// use. Any key looked up more than 1/capacity of the time is guaranteed to
+// be tracked, so capacity should be a few times the number of keys to
+// report. A capacity of zero or less disables tracking.
+func WithHotKeys(capacity int) Option {
+	return optionFunc(func(c *config) {
+		c.hotKeys = capacity
+	})
+}
+
+// HotKeys returns up to n of the keys looked up most often, most counted
+// first. Counts are upper bounds: a key that took over a counter inherits
+// its count. HotKeys returns nil unless the map was created with
+// WithHotKeys. The counters are locked on their own, so HotKeys does not
+// lock the map.
+func (m *ValueMap[K, V]) HotKeys(n int) []HotKey[K] {
+	if m.hot == nil || n <= 0 {
+		return nil
+	}
+	m.hot.mu.Lock()
+	keys := make([]HotKey[K], len(m.hot.heap))
+	for i, c := range m.hot.heap {
+		keys[i] = HotKey[K]{Key: c.key, Count: c.count}
+	}
+	m.hot.mu.Unlock()
+	slices.SortFunc(keys, func(a, b HotKey[K]) int { return cmp.Compare(b.Count, a.Count) })
+	return keys[:min(n, len(keys))]
+}
+
+// hotKeys counts lookups with the Space-Saving algorithm. Its counters
+// form a min-heap on count, so the least counted key is found at once.
+// It has its own mutex, since lookups only hold the read lock of the map.
+type hotKeys[K comparable] struct {
+	mu       sync.Mutex
+	capacity int
+	heap     hotHeap[K]
+	index    map[K]*hotCounter[K]
+}
+
+type hotCounter[K comparable] struct {
+	key   K
+	count uint64
+	pos   int
+}
+
+func newHotKeys[K comparable](capacity int) *hotKeys[K] {
+	return &hotKeys[K]{capacity: capacity, index: make(map[K]*hotCounter[K], capacity)}
+}
+
+// add counts a lookup of key.
+func (h *hotKeys[K]) add(key K) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if c, ok := h.index[key]; ok {
+		c.count++
+		heap.Fix(&h.heap, c.pos)
+		return
+	}
+	if len(h.heap) < h.capacity {
+		c := &hotCounter[K]{key: key, count: 1}
+		h.index[key] = c
+		heap.Push(&h.heap, c)
+		return
+	}
+	// Replace the least counted key, which keeps its count as the error bound.
+	c := h.heap[0]
+	delete(h.index, c.key)
+	c.key = key
+	c.count++
+	h.index[key] = c
+	heap.Fix(&h.heap, 0)
+}
+
+// hotHeap implements heap.Interface over the counters.
+type hotHeap[K comparable] []*hotCounter[K]
+
+func (h hotHeap[K]) Len() int           { return len(h) }
+func (h hotHeap[K]) Less(i, j int) bool { return h[i].count < h[j].count }
+
+func (h hotHeap[K]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].pos = i
+	h[j].pos = j
+}
+
+func (h *hotHeap[K]) Push(x any) {
+	c := x.(*hotCounter[K])
+	c.pos = len(*h)
+	*h = append(*h, c)
+}
+
+func (h *hotHeap[K]) Pop() any {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
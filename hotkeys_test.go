@@ -0,0 +1,45 @@
+package valuemap
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestHotKeys(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := NewBounded[string, int](0, WithHotKeys(8))
+	if New[string, int]().HotKeys(3) != nil {
+		t.Error("HotKeys on a map without tracking is not nil")
+	}
+
+	// Three hot keys among many that are looked up once.
+	for i := range 1000 {
+		m.Get(&mu, "hot")
+		if i%2 == 0 {
+			m.Get(&mu, "warm")
+		}
+		if i%4 == 0 {
+			m.GetMany(&mu, []string{"mild"})
+		}
+		m.Get(&mu, fmt.Sprint("cold", i))
+	}
+
+	got := m.HotKeys(3)
+	want := []string{"hot", "warm", "mild"}
+	if len(got) != 3 {
+		t.Fatalf("HotKeys(3) = %v, want 3 keys", got)
+	}
+	for i, k := range want {
+		if got[i].Key != k {
+			t.Errorf("HotKeys(3) = %v, want keys %v", got, want)
+			break
+		}
+	}
+	if got[0].Count < 1000 {
+		t.Errorf("count of hot = %d, want at least 1000", got[0].Count)
+	}
+	if n := len(m.HotKeys(100)); n != 8 {
+		t.Errorf("len(HotKeys(100)) = %d, want the capacity 8", n)
+	}
+}
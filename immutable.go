@@ -0,0 +1,259 @@
+package valuemap
+
+import (
+	"hash/maphash"
+	"math/bits"
+	"slices"
+)
+
+// ImmutableValueMap is a persistent map. Set and Delete leave the map
+// unchanged and return a new map that shares most of its structure with
+// the old one, so keeping many versions around is cheap.
+//
+// Since a version never changes, any number of goroutines may read it
+// without locking. To publish new versions to readers, store the latest
+// version in an atomic.Pointer or guard the variable holding it.
+//
+// It is implemented as a hash array mapped trie, so Get, Set and Delete
+// take O(log32 n) time.
+type ImmutableValueMap[K comparable, V any] struct {
+	seed maphash.Seed
+	root *hamtNode[K, V]
+	size int
+}
+
+const (
+	hamtBits = 5
+	hamtMask = 1<<hamtBits - 1
+)
+
+// hamtNode is a trie node. bitmap marks which of the 32 positions of the
+// node are used, and slots holds the used positions in order.
+type hamtNode[K comparable, V any] struct {
+	bitmap uint32
+	slots  []hamtSlot[K, V]
+}
+
+// hamtSlot is either a child node or a leaf of entries. All entries of
+// a leaf have the same hash; there is more than one only on collision.
+type hamtSlot[K comparable, V any] struct {
+	node    *hamtNode[K, V]
+	entries []hamtEntry[K, V]
+}
+
+type hamtEntry[K comparable, V any] struct {
+	hash  uint64
+	key   K
+	value V
+}
+
+// NewImmutable returns a new empty ImmutableValueMap.
+func NewImmutable[K comparable, V any]() *ImmutableValueMap[K, V] {
+	return &ImmutableValueMap[K, V]{
+		seed: maphash.MakeSeed(),
+		root: &hamtNode[K, V]{},
+	}
+}
+
+// Len returns the number of key-value pairs.
+func (m *ImmutableValueMap[K, V]) Len() int {
+	return m.size
+}
+
+// Get retrieves a value and a boolean indicating if the key exists.
+func (m *ImmutableValueMap[K, V]) Get(key K) (V, bool) {
+	hash := maphash.Comparable(m.seed, key)
+	n := m.root
+	for shift := uint(0); ; shift += hamtBits {
+		bit, pos := n.locate(hash, shift)
+		if n.bitmap&bit == 0 {
+			break
+		}
+		s := n.slots[pos]
+		if s.node != nil {
+			n = s.node
+			continue
+		}
+		for _, e := range s.entries {
+			if e.key == key {
+				return e.value, true
+			}
+		}
+		break
+	}
+	var zero V
+	return zero, false
+}
+
+// Set returns a new map in which key is assigned value.
+func (m *ImmutableValueMap[K, V]) Set(key K, value V) *ImmutableValueMap[K, V] {
+	e := hamtEntry[K, V]{hash: maphash.Comparable(m.seed, key), key: key, value: value}
+	root, added := m.root.set(e, 0)
+	next := &ImmutableValueMap[K, V]{seed: m.seed, root: root, size: m.size}
+	if added {
+		next.size++
+	}
+	return next
+}
+
+// Delete returns a new map without key. If the key is not present,
+// it returns m itself.
+func (m *ImmutableValueMap[K, V]) Delete(key K) *ImmutableValueMap[K, V] {
+	root, removed := m.root.delete(maphash.Comparable(m.seed, key), key, 0)
+	if !removed {
+		return m
+	}
+	return &ImmutableValueMap[K, V]{seed: m.seed, root: root, size: m.size - 1}
+}
+
+// Range calls fn sequentially for each key and value present in the map.
+// If fn returns false, Range stops the iteration.
+func (m *ImmutableValueMap[K, V]) Range(fn func(key K, value V) bool) {
+	m.root.each(fn)
+}
+
+// Keys returns a slice of all keys.
+func (m *ImmutableValueMap[K, V]) Keys() []K {
+	keys := make([]K, 0, m.size)
+	m.Range(func(k K, _ V) bool {
+		keys = append(keys, k)
+		return true
+	})
+	return keys
+}
+
+// Values returns a slice of all values.
+func (m *ImmutableValueMap[K, V]) Values() []V {
+	values := make([]V, 0, m.size)
+	m.Range(func(_ K, v V) bool {
+		values = append(values, v)
+		return true
+	})
+	return values
+}
+
+// locate returns the bit of hash at shift in the bitmap and the position
+// of the corresponding slot.
+func (n *hamtNode[K, V]) locate(hash uint64, shift uint) (bit uint32, pos int) {
+	bit = 1 << ((hash >> shift) & hamtMask)
+	return bit, bits.OnesCount32(n.bitmap & (bit - 1))
+}
+
+// set returns a copy of n with e assigned, and whether e.key was added.
+func (n *hamtNode[K, V]) set(e hamtEntry[K, V], shift uint) (*hamtNode[K, V], bool) {
+	bit, pos := n.locate(e.hash, shift)
+	if n.bitmap&bit == 0 {
+		return &hamtNode[K, V]{
+			bitmap: n.bitmap | bit,
+			slots:  slices.Insert(slices.Clone(n.slots), pos, hamtSlot[K, V]{entries: []hamtEntry[K, V]{e}}),
+		}, true
+	}
+	s := n.slots[pos]
+	added := false
+	switch {
+	case s.node != nil:
+		s.node, added = s.node.set(e, shift+hamtBits)
+	case s.entries[0].hash == e.hash:
+		i := slices.IndexFunc(s.entries, func(x hamtEntry[K, V]) bool {
+			return x.key == e.key
+		})
+		if i < 0 {
+			s.entries = append(slices.Clip(s.entries), e)
+			added = true
+		} else {
+			s.entries = slices.Clone(s.entries)
+			s.entries[i] = e
+		}
+	default:
+		s = hamtSlot[K, V]{node: split(s.entries, e, shift+hamtBits)}
+		added = true
+	}
+	cp := &hamtNode[K, V]{bitmap: n.bitmap, slots: slices.Clone(n.slots)}
+	cp.slots[pos] = s
+	return cp, added
+}
+
+// split returns a node holding both a leaf and an entry with a different hash.
+func split[K comparable, V any](leaf []hamtEntry[K, V], e hamtEntry[K, V], shift uint) *hamtNode[K, V] {
+	n := &hamtNode[K, V]{}
+	bit1, _ := n.locate(leaf[0].hash, shift)
+	bit2, _ := n.locate(e.hash, shift)
+	if bit1 == bit2 {
+		n.bitmap = bit1
+		n.slots = []hamtSlot[K, V]{{node: split(leaf, e, shift+hamtBits)}}
+		return n
+	}
+	s1 := hamtSlot[K, V]{entries: leaf}
+	s2 := hamtSlot[K, V]{entries: []hamtEntry[K, V]{e}}
+	n.bitmap = bit1 | bit2
+	if bit1 < bit2 {
+		n.slots = []hamtSlot[K, V]{s1, s2}
+	} else {
+		n.slots = []hamtSlot[K, V]{s2, s1}
+	}
+	return n
+}
+
+// delete returns a copy of n without key, and whether the key was removed.
+// If it was not, n itself is returned.
+func (n *hamtNode[K, V]) delete(hash uint64, key K, shift uint) (*hamtNode[K, V], bool) {
+	bit, pos := n.locate(hash, shift)
+	if n.bitmap&bit == 0 {
+		return n, false
+	}
+	s := n.slots[pos]
+	if s.node != nil {
+		child, removed := s.node.delete(hash, key, shift+hamtBits)
+		if !removed {
+			return n, false
+		}
+		switch {
+		case len(child.slots) == 0:
+			return n.without(bit, pos), true
+		case len(child.slots) == 1 && child.slots[0].node == nil:
+			// Pull a lone leaf up so that the trie stays minimal.
+			s = child.slots[0]
+		default:
+			s.node = child
+		}
+	} else {
+		i := slices.IndexFunc(s.entries, func(x hamtEntry[K, V]) bool {
+			return x.key == key
+		})
+		if i < 0 {
+			return n, false
+		}
+		if len(s.entries) == 1 {
+			return n.without(bit, pos), true
+		}
+		s.entries = slices.Delete(slices.Clone(s.entries), i, i+1)
+	}
+	cp := &hamtNode[K, V]{bitmap: n.bitmap, slots: slices.Clone(n.slots)}
+	cp.slots[pos] = s
+	return cp, true
+}
+
+// without returns a copy of n without the slot at pos.
+func (n *hamtNode[K, V]) without(bit uint32, pos int) *hamtNode[K, V] {
+	return &hamtNode[K, V]{
+		bitmap: n.bitmap &^ bit,
+		slots:  slices.Delete(slices.Clone(n.slots), pos, pos+1),
+	}
+}
+
+func (n *hamtNode[K, V]) each(fn func(key K, value V) bool) bool {
+	for _, s := range n.slots {
+		if s.node != nil {
+			if !s.node.each(fn) {
+				return false
+			}
+			continue
+		}
+		for _, e := range s.entries {
+			if !fn(e.key, e.value) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+package valuemap
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+func TestImmutableValueMap(t *testing.T) {
+	m := NewImmutable[int, int]()
+	want := make(map[int]int)
+	versions := []*ImmutableValueMap[int, int]{m}
+	snapshots := []map[int]int{{}}
+
+	r := rand.New(rand.NewPCG(1, 2))
+	for i := range 5000 {
+		k := r.IntN(500)
+		if r.IntN(3) == 0 {
+			m = m.Delete(k)
+			delete(want, k)
+		} else {
+			m = m.Set(k, i)
+			want[k] = i
+		}
+		if i%1000 == 0 {
+			versions = append(versions, m)
+			cp := make(map[int]int, len(want))
+			for k, v := range want {
+				cp[k] = v
+			}
+			snapshots = append(snapshots, cp)
+		}
+	}
+	versions = append(versions, m)
+	snapshots = append(snapshots, want)
+
+	// Every version must still hold exactly the contents it had.
+	for i, v := range versions {
+		exp := snapshots[i]
+		if v.Len() != len(exp) {
+			t.Errorf("version %d: Len = %d, want %d", i, v.Len(), len(exp))
+		}
+		for k, val := range exp {
+			if got, ok := v.Get(k); !ok || got != val {
+				t.Errorf("version %d: Get(%d) = %d, %v; want %d, true", i, k, got, ok, val)
+			}
+		}
+		n := 0
+		v.Range(func(k, val int) bool {
+			n++
+			if exp[k] != val {
+				t.Errorf("version %d: Range yielded %d=%d, want %d", i, k, val, exp[k])
+			}
+			return true
+		})
+		if n != len(exp) {
+			t.Errorf("version %d: Range visited %d entries, want %d", i, n, len(exp))
+		}
+	}
+
+	if m.Delete(-1) != m {
+		t.Error("Delete of a missing key returned a new map")
+	}
+}
@@ -0,0 +1,106 @@
+package valuemap
+
+import (
+	"fmt"
+	"sync"
+)
+
+// fieldIndex is a secondary index added with AddIndex, from the terms that
+// fn computes for the entries to their keys.
+type fieldIndex[K comparable, V any] struct {
+	fn      func(key K, value V) string
+	byValue postings[string, K]
+}
+
+// AddIndex adds a secondary index called name, that maps the term fn
+// computes for each entry, such as the value of one of its fields, to the
+// entries it is computed for, so that ByIndex finds them without scanning
+// the map. The index is built from the current entries and kept up to date
+// under the lock of every change, so fn must be fast and must not call
+// methods of the map. Adding an index under the name of another replaces
+// it.
+//
+// mu is an external mutex to lock the internal map while the index is built
+func (m *ValueMap[K, V]) AddIndex(mu *sync.RWMutex, name string, fn func(key K, value V) string) {
+	defer m.lock(mu).Unlock()
+	ix := &fieldIndex[K, V]{fn: fn, byValue: make(postings[string, K])}
+	for k, v := range m.each() {
+		ix.byValue.add(fn(k, v), k)
+	}
+	if m.indexes == nil {
+		m.indexes = make(map[string]*fieldIndex[K, V])
+	}
+	m.indexes[name] = ix
+}
+
+// DropIndex removes the secondary index called name, if any.
+//
+// mu is an external mutex to lock the internal map during the removal
+func (m *ValueMap[K, V]) DropIndex(mu *sync.RWMutex, name string) {
+	defer m.lock(mu).Unlock()
+	delete(m.indexes, name)
+}
+
+// ByIndex returns the entries for which the index called name computed
+// term, in no particular order. It panics if the map has no such index.
+//
+// mu is an external mutex to lock the internal map during entry retrieval
+func (m *ValueMap[K, V]) ByIndex(mu *sync.RWMutex, name, term string) []Entry[K, V] {
+	defer m.readLock(mu).Unlock()
+	return m.byIndex(m.index(name), term)
+}
+
+// index returns the secondary index called name, and panics if there is
+// none. The caller must hold at least the read lock.
+func (m *ValueMap[K, V]) index(name string) *fieldIndex[K, V] {
+	ix, ok := m.indexes[name]
+	if !ok {
+		panic(fmt.Sprintf("valuemap: no index %q", name))
+	}
+	return ix
+}
+
+// byIndex returns the live entries for which ix computed term.
+// The caller must hold at least the read lock.
+func (m *ValueMap[K, V]) byIndex(ix *fieldIndex[K, V], term string) []Entry[K, V] {
+	set := ix.byValue[term]
+	entries := make([]Entry[K, V], 0, len(set))
+	for k := range set {
+		if v, ok := m.lookup(k); ok {
+			entries = append(entries, Entry[K, V]{Key: k, Value: v})
+		}
+	}
+	return entries
+}
+
+// reindex updates the indexes of the map for the assignment of a value to
+// key, before it is stored. The caller must hold the write lock.
+func (m *ValueMap[K, V]) reindex(key K, value V) {
+	if m.reverse == nil && len(m.indexes) == 0 {
+		return
+	}
+	old, had := m.stored(key)
+	if m.reverse != nil {
+		if had {
+			m.reverse.remove(key, old)
+		}
+		m.reverse.add(key, value)
+	}
+	for _, ix := range m.indexes {
+		if had {
+			ix.byValue.remove(ix.fn(key, old), key)
+		}
+		ix.byValue.add(ix.fn(key, value), key)
+	}
+}
+
+// unindex removes a deleted entry from the indexes of the map.
+// The caller must hold the write lock.
+func (m *ValueMap[K, V]) unindex(key K, value V) {
+	if m.reverse != nil {
+		m.reverse.remove(key, value)
+	}
+	for _, ix := range m.indexes {
+		ix.byValue.remove(ix.fn(key, value), key)
+	}
+}
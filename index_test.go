@@ -0,0 +1,59 @@
+package valuemap
+
+import (
+	"slices"
+	"sync"
+	"testing"
+)
+
+type server struct {
+	Name   string
+	Region string
+}
+
+func TestIndex(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := New[int, server](WithMaxEntries(4))
+	m.Set(&mu, 1, server{"a", "eu-west"})
+	m.Set(&mu, 2, server{"b", "us-east"})
+	m.AddIndex(&mu, "byRegion", func(id int, s server) string { return s.Region })
+	m.Set(&mu, 3, server{"c", "eu-west"})
+	m.Set(&mu, 2, server{"b", "eu-west"})
+	m.Set(&mu, 1, server{"a", "ap-south"})
+
+	region := func(r string) []int {
+		var ids []int
+		for _, e := range m.ByIndex(&mu, "byRegion", r) {
+			ids = append(ids, e.Key)
+		}
+		slices.Sort(ids)
+		return ids
+	}
+	if got := region("eu-west"); !slices.Equal(got, []int{2, 3}) {
+		t.Errorf("ByIndex(eu-west) = %v, want [2 3]", got)
+	}
+	if got := region("us-east"); len(got) != 0 {
+		t.Errorf("ByIndex(us-east) = %v after reassignment, want none", got)
+	}
+
+	m.Delete(&mu, 3)
+	m.Set(&mu, 4, server{"d", "eu-west"})
+	m.Set(&mu, 5, server{"e", "eu-west"})
+	m.Set(&mu, 6, server{"f", "eu-west"}) // evicts 2
+	if got := region("eu-west"); !slices.Equal(got, []int{4, 5, 6}) {
+		t.Errorf("ByIndex(eu-west) = %v after Delete and eviction, want [4 5 6]", got)
+	}
+	m.Clear(&mu)
+	m.Set(&mu, 7, server{"g", "eu-west"})
+	if got := region("eu-west"); !slices.Equal(got, []int{7}) {
+		t.Errorf("ByIndex(eu-west) = %v after Clear, want [7]", got)
+	}
+
+	m.DropIndex(&mu, "byRegion")
+	defer func() {
+		if recover() == nil {
+			t.Error("ByIndex did not panic for a dropped index")
+		}
+	}()
+	m.ByIndex(&mu, "byRegion", "eu-west")
+}
@@ -0,0 +1,295 @@
+package valuemap
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrNotJournaled is returned by Checkpoint and Sync for maps created
+// without WithJournal.
+var ErrNotJournaled = errors.New("valuemap: map is not journaled")
+
+// Journal records are framed as a 4-byte little-endian payload length,
+// a 4-byte little-endian CRC-32C of the payload, and the payload: an
+// operation byte followed, for set and delete, by the codec encoding of
+// a map holding the single affected entry.
+const (
+	opSet byte = iota + 1
+	opDelete
+	opClear
+)
+
+const recordHeaderSize = 8
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// journal holds the state of a map created with WithJournal.
+type journal[K comparable, V any] struct {
+	mu        *sync.RWMutex
+	w         io.Writer
+	codec     Codec[K, V]
+	buf       bytes.Buffer
+	err       error    // first write error; later records are dropped
+	path      string   // checkpoint snapshot file
+	file      *os.File // journal file opened by OpenJournaled
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// truncater is implemented by journal writers that Checkpoint can empty,
+// such as *os.File.
+type truncater interface {
+	Truncate(size int64) error
+	io.Seeker
+}
+
+// WithJournal makes the map append every change to w before applying it:
+// assignments, deletions including evictions and expirations, and clears.
+// Each record carries a checksum, and entries are encoded with c.
+//
+// Records are written with one Write call each and are not synced, so they
+// survive a crash of the process but not necessarily of the machine; call
+// Sync to flush them to stable storage. Since the methods of ValueMap do
+// not return errors, the first failed write stops the journal, and the
+// error is reported by Sync, Checkpoint and Close. A successful Checkpoint
+// starts the journal afresh.
+//
+// mu is an external mutex to lock the internal map during checkpoints.
+// It must be the same mutex passed to the other methods
+func WithJournal[K comparable, V any](mu *sync.RWMutex, w io.Writer, c Codec[K, V]) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.journalMu = mu
+		cfg.journalW = w
+		cfg.journalCodec = c
+	})
+}
+
+// WithCheckpoint makes Checkpoint of a journaled map save a snapshot to the
+// file at path, and calls it every interval. An interval of zero or less
+// leaves checkpoints to the caller. The journal writer must be truncatable,
+// like *os.File. Using WithCheckpoint without WithJournal panics.
+func WithCheckpoint(path string, interval time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.checkpointPath = path
+		cfg.checkpointInterval = interval
+	})
+}
+
+func (m *ValueMap[K, V]) startJournal(c *config) {
+	j := &journal[K, V]{
+		mu:      c.journalMu,
+		w:       c.journalW,
+		codec:   typed[Codec[K, V]](c.journalCodec, "journal codec"),
+		path:    c.checkpointPath,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	m.journal = j
+	if j.path == "" || c.checkpointInterval <= 0 {
+		close(j.stopped)
+		return
+	}
+	go m.checkpointer(c.checkpointInterval)
+}
+
+func (m *ValueMap[K, V]) checkpointer(interval time.Duration) {
+	j := m.journal
+	defer close(j.stopped)
+	m.every(interval, j.done, func() { m.Checkpoint(j.mu) })
+}
+
+// append writes a record to the journal.
+// The caller must hold the write lock.
+func (j *journal[K, V]) append(op byte, entry map[K]V) {
+	if j.err != nil {
+		return
+	}
+	j.buf.Reset()
+	if j.err = appendRecord(&j.buf, op, entry, j.codec); j.err != nil {
+		return
+	}
+	_, j.err = j.w.Write(j.buf.Bytes())
+}
+
+// appendRecord appends a framed record to buf.
+func appendRecord[K comparable, V any](buf *bytes.Buffer, op byte, entry map[K]V, c Codec[K, V]) error {
+	start := buf.Len()
+	buf.Write(make([]byte, recordHeaderSize))
+	buf.WriteByte(op)
+	if entry != nil {
+		if err := c.Encode(buf, entry); err != nil {
+			buf.Truncate(start)
+			return err
+		}
+	}
+	b := buf.Bytes()[start:]
+	binary.LittleEndian.PutUint32(b[0:], uint32(len(b)-recordHeaderSize))
+	binary.LittleEndian.PutUint32(b[4:], crc32.Checksum(b[recordHeaderSize:], castagnoli))
+	return nil
+}
+
+// nextRecord returns the payload of the record at the start of b and the
+// size of the whole record. ok is false if the record is incomplete or its
+// checksum does not match.
+func nextRecord(b []byte) (payload []byte, size int, ok bool) {
+	if len(b) < recordHeaderSize {
+		return nil, 0, false
+	}
+	n := int(binary.LittleEndian.Uint32(b))
+	if n == 0 || n > len(b)-recordHeaderSize {
+		return nil, 0, false
+	}
+	payload = b[recordHeaderSize : recordHeaderSize+n]
+	if crc32.Checksum(payload, castagnoli) != binary.LittleEndian.Uint32(b[4:]) {
+		return nil, 0, false
+	}
+	return payload, recordHeaderSize + n, true
+}
+
+// decodeRecord decodes the payload of a record. The entry is nil for
+// clear records. ok is false if the payload is not a valid record.
+func decodeRecord[K comparable, V any](payload []byte, c Codec[K, V]) (op byte, key K, value V, ok bool) {
+	op = payload[0]
+	switch op {
+	case opClear:
+		return op, key, value, len(payload) == 1
+	case opSet, opDelete:
+	default:
+		return op, key, value, false
+	}
+	entry, err := c.Decode(bytes.NewReader(payload[1:]))
+	if err != nil || len(entry) != 1 {
+		return op, key, value, false
+	}
+	for k, v := range entry {
+		key, value = k, v
+	}
+	return op, key, value, true
+}
+
+// logSet journals the assignment of a key.
+// The caller must hold the write lock.
+func (m *ValueMap[K, V]) logSet(key K, value V) {
+	if m.journal != nil {
+		m.journal.append(opSet, map[K]V{key: value})
+	}
+}
+
+// logDelete journals the removal of a key.
+// The caller must hold the write lock.
+func (m *ValueMap[K, V]) logDelete(key K) {
+	if m.journal != nil {
+		var zero V
+		m.journal.append(opDelete, map[K]V{key: zero})
+	}
+}
+
+// logClear journals the removal of all keys.
+// The caller must hold the write lock.
+func (m *ValueMap[K, V]) logClear() {
+	if m.journal != nil {
+		m.journal.append(opClear, nil)
+	}
+}
+
+// Checkpoint saves the contents of a journaled map to the file given to
+// WithCheckpoint and empties the journal, which then only holds changes
+// made after the snapshot. The snapshot is written as with SaveToFile,
+// with the codec of the journal.
+//
+// If the process stops after the snapshot is saved but before the journal
+// is emptied, replaying the old journal over the new snapshot gives the
+// same contents, since every record is an assignment, deletion or clear.
+//
+// mu is an external mutex to lock the internal map during the checkpoint,
+// which blocks all other methods until the snapshot is written
+func (m *ValueMap[K, V]) Checkpoint(mu *sync.RWMutex) error {
+	defer m.lock(mu).Unlock()
+	j := m.journal
+	if j == nil {
+		return ErrNotJournaled
+	}
+	if m.closed.Load() {
+		return ErrClosed
+	}
+	if j.path == "" {
+		return errors.New("valuemap: checkpoint file not set")
+	}
+	t, ok := j.w.(truncater)
+	if !ok {
+		return fmt.Errorf("valuemap: journal writer %T cannot be truncated", j.w)
+	}
+	data := make(map[K]V, m.size())
+	for k, v := range m.live() {
+		data[k] = v
+	}
+	err := writeFile(j.path, func(f *os.File) error {
+		return j.codec.Encode(f, data)
+	})
+	if err != nil {
+		return err
+	}
+	if err := t.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := t.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	j.err = nil
+	return nil
+}
+
+// Sync flushes the journal of the map to stable storage if its writer has
+// a Sync method, like *os.File, and returns the first error met while
+// journaling since the last checkpoint.
+//
+// mu is an external mutex to lock the internal map during the flush
+func (m *ValueMap[K, V]) Sync(mu *sync.RWMutex) error {
+	defer m.lock(mu).Unlock()
+	if m.journal == nil {
+		return ErrNotJournaled
+	}
+	if m.closed.Load() {
+		return ErrClosed
+	}
+	return m.journal.sync()
+}
+
+// sync flushes the journal and returns its first error.
+// The caller must hold the write lock.
+func (j *journal[K, V]) sync() error {
+	if j.err != nil {
+		return j.err
+	}
+	if s, ok := j.w.(interface{ Sync() error }); ok {
+		j.err = s.Sync()
+	}
+	return j.err
+}
+
+// closeJournal stops the checkpoint timer, syncs the journal and closes
+// the journal file if the map opened it. Later changes are not journaled.
+func (m *ValueMap[K, V]) closeJournal() error {
+	j := m.journal
+	j.closeOnce.Do(func() {
+		close(j.done)
+		<-j.stopped
+		defer m.lock(j.mu).Unlock()
+		j.closeErr = j.sync()
+		if j.file != nil {
+			j.closeErr = cmp.Or(j.closeErr, j.file.Close())
+		}
+		j.err = ErrClosed
+	})
+	return j.closeErr
+}
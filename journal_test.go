@@ -0,0 +1,169 @@
+package valuemap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+type record struct {
+	op    byte
+	entry map[string]int
+}
+
+func readRecords(t *testing.T, b []byte) []record {
+	t.Helper()
+	var recs []record
+	for len(b) > 0 {
+		n := binary.LittleEndian.Uint32(b)
+		sum := binary.LittleEndian.Uint32(b[4:])
+		payload := b[recordHeaderSize : recordHeaderSize+n]
+		if crc32.Checksum(payload, castagnoli) != sum {
+			t.Fatalf("record %d: checksum mismatch", len(recs))
+		}
+		r := record{op: payload[0]}
+		if len(payload) > 1 {
+			var err error
+			if r.entry, err = (JSONCodec[string, int]{}).Decode(bytes.NewReader(payload[1:])); err != nil {
+				t.Fatal(err)
+			}
+		}
+		recs = append(recs, r)
+		b = b[recordHeaderSize+n:]
+	}
+	return recs
+}
+
+func TestJournal(t *testing.T) {
+	mu := sync.RWMutex{}
+	var buf bytes.Buffer
+	m := NewBounded[string, int](2, WithJournal(&mu, &buf, JSONCodec[string, int]{}))
+
+	m.Set(&mu, "a", 1)
+	m.Set(&mu, "b", 2)
+	m.Delete(&mu, "b")
+	m.Delete(&mu, "missing")
+	m.Set(&mu, "c", 3)
+	m.Set(&mu, "d", 4) // evicts a
+	m.Clear(&mu)
+
+	recs := readRecords(t, buf.Bytes())
+	want := []struct {
+		op  byte
+		key string
+	}{
+		{opSet, "a"}, {opSet, "b"}, {opDelete, "b"}, {opSet, "c"},
+		{opSet, "d"}, {opDelete, "a"}, {opClear, ""},
+	}
+	if len(recs) != len(want) {
+		t.Fatalf("got %d records, want %d", len(recs), len(want))
+	}
+	for i, w := range want {
+		if recs[i].op != w.op {
+			t.Errorf("record %d: op = %d, want %d", i, recs[i].op, w.op)
+		}
+		if _, ok := recs[i].entry[w.key]; w.key != "" && !ok {
+			t.Errorf("record %d: entry = %v, want key %s", i, recs[i].entry, w.key)
+		}
+	}
+	if v := recs[0].entry["a"]; v != 1 {
+		t.Errorf("record 0: value = %d, want 1", v)
+	}
+}
+
+type failingWriter struct{ n int }
+
+func (w *failingWriter) Write(b []byte) (int, error) {
+	if w.n == 0 {
+		return 0, errors.New("disk full")
+	}
+	w.n--
+	return len(b), nil
+}
+
+func TestJournalWriteError(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := NewBounded[string, int](0, WithJournal(&mu, &failingWriter{n: 1}, JSONCodec[string, int]{}))
+	m.Set(&mu, "a", 1)
+	if err := m.Sync(&mu); err != nil {
+		t.Fatalf("Sync() = %v, want nil", err)
+	}
+	m.Set(&mu, "b", 2)
+	if err := m.Sync(&mu); err == nil {
+		t.Error("Sync() = nil, want the write error")
+	}
+	if v, _ := m.Get(&mu, "b"); v != 2 {
+		t.Errorf("Get(b) = %d, want 2", v)
+	}
+	if err := m.Close(); err == nil {
+		t.Error("Close() = nil, want the write error")
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	mu := sync.RWMutex{}
+	dir := t.TempDir()
+	wal, err := os.OpenFile(filepath.Join(dir, "data.wal"), os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+	snapshot := filepath.Join(dir, "data.json")
+	m := NewBounded[string, int](0,
+		WithJournal(&mu, wal, JSONCodec[string, int]{}),
+		WithCheckpoint(snapshot, 0))
+	defer m.Close()
+
+	m.Set(&mu, "a", 1)
+	m.Set(&mu, "b", 2)
+	if err := m.Checkpoint(&mu); err != nil {
+		t.Fatal(err)
+	}
+	m.Delete(&mu, "a")
+	if err := m.Sync(&mu); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(wal.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recs := readRecords(t, b); len(recs) != 1 || recs[0].op != opDelete {
+		t.Errorf("journal after checkpoint = %v, want one delete", recs)
+	}
+	got := New[string, int]()
+	if err := got.LoadFromFile(&mu, snapshot, JSONCodec[string, int]{}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Len(&mu) != 2 {
+		t.Errorf("snapshot Len() = %d, want 2", got.Len(&mu))
+	}
+}
+
+func TestCheckpointErrors(t *testing.T) {
+	mu := sync.RWMutex{}
+	if err := New[string, int]().Checkpoint(&mu); !errors.Is(err, ErrNotJournaled) {
+		t.Errorf("Checkpoint() = %v, want ErrNotJournaled", err)
+	}
+	var buf bytes.Buffer
+	m := NewBounded[string, int](0,
+		WithJournal(&mu, &buf, JSONCodec[string, int]{}),
+		WithCheckpoint(filepath.Join(t.TempDir(), "data.json"), time.Hour))
+	defer m.Close()
+	if err := m.Checkpoint(&mu); err == nil {
+		t.Error("Checkpoint() = nil, want an error for a buffer journal")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("WithCheckpoint without WithJournal did not panic")
+		}
+	}()
+	NewBounded[string, int](0, WithCheckpoint("data.json", 0))
+}
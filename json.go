@@ -0,0 +1,49 @@
+package valuemap
+
+import (
+	"encoding/json"
+	"maps"
+)
+
+// MarshalJSON encodes the map as a JSON object. Keys are encoded as
+// encoding/json encodes map keys: strings are used directly, integers are
+// formatted in decimal, and types implementing encoding.TextMarshaler are
+// marshaled to text. UnmarshalJSON reverses the same rules, so such maps
+// round-trip.
+//
+// Since json.Marshaler methods cannot take the external mutex, MarshalJSON
+// does not lock the map. Callers must hold the read lock of the mutex
+// guarding it, or marshal the result of Freeze instead.
+func (m *ValueMap[K, V]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(maps.Collect(m.live()))
+}
+
+// UnmarshalJSON replaces the contents of the map with the members of a
+// JSON object. The object is decoded completely before the map is changed,
+// so on error the map is left as it was. Like the decoders of
+// encoding/json, it leaves the map untouched for a JSON null.
+//
+// Since json.Unmarshaler methods cannot take the external mutex,
+// UnmarshalJSON does not lock the map. Callers must hold the write lock
+// of the mutex guarding it.
+func (m *ValueMap[K, V]) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	var data map[K]V
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+	m.replace(data)
+	return nil
+}
+
+// replace swaps the contents of the map for data.
+// The caller must hold the write lock.
+func (m *ValueMap[K, V]) replace(data map[K]V) {
+	m.reset()
+	m.grow(len(data))
+	for k, v := range data {
+		m.put(k, v)
+	}
+}
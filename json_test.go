@@ -0,0 +1,83 @@
+package valuemap
+
+import (
+	"encoding/json"
+	"net/netip"
+	"sync"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	type response struct {
+		Counts *ValueMap[string, int] `json:"counts"`
+	}
+
+	mu := sync.RWMutex{}
+	in := response{Counts: FromMap(map[string]int{"a": 1, "b": 2})}
+
+	mu.RLock()
+	b, err := json.Marshal(in)
+	mu.RUnlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"counts":{"a":1,"b":2}}` {
+		t.Errorf("Marshal = %s", b)
+	}
+
+	var out response
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := out.Counts.Get(&mu, "b"); v != 2 {
+		t.Errorf("Get(b) = %d, want 2", v)
+	}
+
+	if err := json.Unmarshal([]byte(`{"a":"x"}`), out.Counts); err == nil {
+		t.Error("Unmarshal of a mistyped value succeeded")
+	}
+	if n := out.Counts.Len(&mu); n != 2 {
+		t.Errorf("Len = %d after failed Unmarshal, want 2", n)
+	}
+
+	// null leaves the map untouched, as it does for Go maps.
+	if err := json.Unmarshal([]byte(`null`), out.Counts); err != nil {
+		t.Fatal(err)
+	}
+	if n := out.Counts.Len(&mu); n != 2 {
+		t.Errorf("Len = %d after unmarshaling null, want 2", n)
+	}
+}
+
+func TestJSONKeys(t *testing.T) {
+	mu := sync.RWMutex{}
+
+	ints := FromMap(map[int]string{1: "one", -2: "minus two"})
+	b, err := json.Marshal(ints)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := New[int, string]()
+	if err := json.Unmarshal(b, got); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := got.Get(&mu, -2); v != "minus two" {
+		t.Errorf("Get(-2) = %q after round trip of %s", v, b)
+	}
+
+	addr := netip.MustParseAddr("10.0.0.1")
+	hosts := FromMap(map[netip.Addr]string{addr: "gateway"})
+	if b, err = json.Marshal(hosts); err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"10.0.0.1":"gateway"}` {
+		t.Errorf("Marshal = %s", b)
+	}
+	back := New[netip.Addr, string]()
+	if err := json.Unmarshal(b, back); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := back.Get(&mu, addr); v != "gateway" {
+		t.Errorf("Get(%v) = %q after round trip", addr, v)
+	}
+}
@@ -0,0 +1,63 @@
+package valuemap
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// encodeKey returns the bytes a DiskValueMap stores a key under. Strings
+// are stored as is and integers as 8 big-endian bytes, with the sign bit
+// of signed integers flipped so that byte order matches numeric order.
+// Strings that are empty or start with a zero byte get a zero byte
+// prepended, so that no key is stored as an empty slice, which backends
+// such as bbolt reject. Other keys are encoded as JSON.
+//
+// Keys whose JSON form would not tell them apart are rejected: those of
+// interface, pointer, channel, func, map and slice kinds, and structs
+// and arrays holding such fields, unexported fields or fields tagged to
+// be omitted from JSON.
+func encodeKey[K comparable](key K) ([]byte, error) {
+	t := reflect.TypeFor[K]()
+	if err := checkKeyType(t); err != nil {
+		return nil, err
+	}
+	v := reflect.ValueOf(key)
+	switch v.Kind() {
+	case reflect.String:
+		s := v.String()
+		if s == "" || s[0] == 0 {
+			return append([]byte{0}, s...), nil
+		}
+		return []byte(s), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return binary.BigEndian.AppendUint64(nil, uint64(v.Int())^(1<<63)), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return binary.BigEndian.AppendUint64(nil, v.Uint()), nil
+	}
+	return json.Marshal(key)
+}
+
+// checkKeyType reports an error if keys of type t cannot be encoded
+// uniquely as JSON.
+func checkKeyType(t reflect.Type) error {
+	switch t.Kind() {
+	case reflect.Interface, reflect.Pointer, reflect.Chan, reflect.Func, reflect.UnsafePointer,
+		reflect.Map, reflect.Slice:
+		return fmt.Errorf("valuemap: key type %v is not supported", t)
+	case reflect.Array:
+		return checkKeyType(t.Elem())
+	case reflect.Struct:
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if !f.IsExported() || f.Tag.Get("json") == "-" {
+				return fmt.Errorf("valuemap: key type %v has field %s not encoded as JSON", t, f.Name)
+			}
+			if err := checkKeyType(f.Type); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
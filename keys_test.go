@@ -0,0 +1,69 @@
+package valuemap
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+func TestEncodeKeyOrder(t *testing.T) {
+	ints := []int{-300, -1, 0, 1, 255, 256, 1 << 40}
+	var encoded [][]byte
+	for _, k := range ints {
+		b, err := encodeKey(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded = append(encoded, b)
+	}
+	if !slices.IsSortedFunc(encoded, bytes.Compare) {
+		t.Errorf("encoded integers are not in numeric order: %x", encoded)
+	}
+
+	type point struct{ X, Y int }
+	if b, err := encodeKey(point{1, 2}); err != nil || string(b) != `{"X":1,"Y":2}` {
+		t.Errorf("encodeKey(point) = %s, %v, want JSON", b, err)
+	}
+	if _, err := encodeKey[any]("a"); err == nil {
+		t.Error("encodeKey[any] error = nil, want unsupported key type")
+	}
+	if _, err := encodeKey(new(int)); err == nil {
+		t.Error("encodeKey(*int) error = nil, want unsupported key type")
+	}
+	type hidden struct{ a, b int }
+	if _, err := encodeKey(hidden{1, 2}); err == nil {
+		t.Error("encodeKey(hidden) error = nil, want unsupported key type")
+	}
+	type ref struct{ P *int }
+	if _, err := encodeKey(ref{}); err == nil {
+		t.Error("encodeKey(ref) error = nil, want unsupported key type")
+	}
+	type tagged struct {
+		A int
+		B int `json:"-"`
+	}
+	if _, err := encodeKey(tagged{}); err == nil {
+		t.Error("encodeKey(tagged) error = nil, want unsupported key type")
+	}
+}
+
+func TestEncodeKeyStrings(t *testing.T) {
+	keys := []string{"", "\x00", "\x00a", "\x01", "a"}
+	var encoded [][]byte
+	for _, k := range keys {
+		b, err := encodeKey(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(b) == 0 {
+			t.Errorf("encodeKey(%q) is empty", k)
+		}
+		encoded = append(encoded, b)
+	}
+	if !slices.IsSortedFunc(encoded, bytes.Compare) {
+		t.Errorf("encoded strings are not in order: %q", encoded)
+	}
+	if b, _ := encodeKey("key"); string(b) != "key" {
+		t.Errorf("encodeKey(key) = %q, want the string itself", b)
+	}
+}
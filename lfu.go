@@ -0,0 +1,133 @@
+package valuemap
+
+import "hash/maphash"
+
+// tinyLFU is an LRU policy with a TinyLFU admission filter. A newly set
+// key is only kept if its estimated frequency beats that of the least
+// recently used key, which would otherwise be evicted in its place.
+type tinyLFU[K comparable] struct {
+	lru       *lru[K]
+	freq      *sketch[K]
+	candidate K
+	hasCand   bool
+}
+
+func newTinyLFU[K comparable](capacity int) *tinyLFU[K] {
+	return &tinyLFU[K]{
+		lru:  newLRU[K](),
+		freq: newSketch[K](capacity),
+	}
+}
+
+func (p *tinyLFU[K]) OnSet(key K) {
+	p.freq.add(key)
+	_, exists := p.lru.elems[key]
+	p.lru.OnSet(key)
+	p.candidate, p.hasCand = key, !exists
+}
+
+func (p *tinyLFU[K]) OnGet(key K) {
+	p.freq.add(key)
+	p.lru.OnGet(key)
+}
+
+func (p *tinyLFU[K]) OnDelete(key K) {
+	p.lru.OnDelete(key)
+	if p.hasCand && p.candidate == key {
+		p.hasCand = false
+	}
+}
+
+func (p *tinyLFU[K]) Victim() (K, bool) {
+	v, ok := p.lru.Victim()
+	if !ok || !p.hasCand || p.candidate == v {
+		return v, ok
+	}
+	// Reject the candidate unless it is used more often than the victim.
+	if p.freq.estimate(p.candidate) <= p.freq.estimate(v) {
+		return p.candidate, true
+	}
+	return v, true
+}
+
+func (p *tinyLFU[K]) Reset() {
+	p.lru.Reset()
+	p.freq.reset()
+	p.hasCand = false
+}
+
+// sketch is a count-min sketch of 4-bit saturating counters that halves
+// all counters after a sample of additions, so old frequencies fade.
+type sketch[K comparable] struct {
+	seed    maphash.Seed
+	rows    [4][]uint8
+	mask    uint64
+	adds    int
+	samples int
+}
+
+func newSketch[K comparable](capacity int) *sketch[K] {
+	// Small caches get wider rows than they need, so that the few keys they
+	// hold rarely share all their counters with another key.
+	width := 64
+	for width < capacity {
+		width <<= 1
+	}
+	s := &sketch[K]{
+		seed:    maphash.MakeSeed(),
+		mask:    uint64(width - 1),
+		samples: 10 * width,
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+// index returns the counter position of a hash in row i. Each row mixes
+// the hash with its own constant, so that keys colliding in one row are
+// unlikely to collide in the others, which double hashing does not ensure
+// for narrow rows.
+func (s *sketch[K]) index(h uint64, i int) uint64 {
+	h += uint64(i+1) * 0x9e3779b97f4a7c15
+	h = (h ^ h>>30) * 0xbf58476d1ce4e5b9
+	h = (h ^ h>>27) * 0x94d049bb133111eb
+	return (h ^ h>>31) & s.mask
+}
+
+func (s *sketch[K]) add(key K) {
+	h := maphash.Comparable(s.seed, key)
+	for i, row := range s.rows {
+		if j := s.index(h, i); row[j] < 15 {
+			row[j]++
+		}
+	}
+	if s.adds++; s.adds >= s.samples {
+		s.age()
+	}
+}
+
+func (s *sketch[K]) estimate(key K) uint8 {
+	h := maphash.Comparable(s.seed, key)
+	est := uint8(15)
+	for i, row := range s.rows {
+		est = min(est, row[s.index(h, i)])
+	}
+	return est
+}
+
+func (s *sketch[K]) age() {
+	for _, row := range s.rows {
+		for j := range row {
+			row[j] >>= 1
+		}
+	}
+	s.adds /= 2
+}
+
+func (s *sketch[K]) reset() {
+	for _, row := range s.rows {
+		clear(row)
+	}
+	s.adds = 0
+}
@@ -0,0 +1,43 @@
+package valuemap
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestTinyLFU(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := NewBounded[string, int](2, WithPolicy(TinyLFU))
+
+	m.Set(&mu, "hot", 1)
+	m.Set(&mu, "warm", 2)
+	for range 5 {
+		m.Get(&mu, "hot")
+		m.Get(&mu, "warm")
+	}
+
+	// A one-off key must not push out frequently used entries.
+	m.Set(&mu, "scan", 3)
+	if _, ok := m.Get(&mu, "scan"); ok {
+		t.Error("rarely used key was admitted")
+	}
+	if n := m.Len(&mu); n != 2 {
+		t.Errorf("Len = %d, want 2", n)
+	}
+
+	// A key that becomes popular is eventually admitted.
+	admitted := false
+	for range 20 {
+		m.Set(&mu, "rising", 4)
+		if _, ok := m.Get(&mu, "rising"); ok {
+			admitted = true
+			break
+		}
+	}
+	if !admitted {
+		t.Error("frequently set key was never admitted")
+	}
+	if n := m.Len(&mu); n != 2 {
+		t.Errorf("Len = %d, want 2", n)
+	}
+}
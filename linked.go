@@ -0,0 +1,198 @@
+package valuemap
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// LinkedValueMap is a map that remembers the order in which keys were
+// first inserted. Keys, Values, Range and JSON encoding follow that order.
+// Setting an existing key keeps its position.
+type LinkedValueMap[K comparable, V any] struct {
+	data  map[K]*list.Element
+	order *list.List
+}
+
+// NewLinked returns a new pointer to a LinkedValueMap.
+func NewLinked[K comparable, V any]() *LinkedValueMap[K, V] {
+	return &LinkedValueMap[K, V]{
+		data:  make(map[K]*list.Element),
+		order: list.New(),
+	}
+}
+
+// Set assigns a value to a key.
+//
+// mu is an external mutex to lock the internal map during value assigning
+func (m *LinkedValueMap[K, V]) Set(mu *sync.RWMutex, key K, value V) {
+	mu.Lock()
+	defer mu.Unlock()
+	m.set(key, value)
+}
+
+func (m *LinkedValueMap[K, V]) set(key K, value V) {
+	if el, ok := m.data[key]; ok {
+		el.Value = &Entry[K, V]{Key: key, Value: value}
+		return
+	}
+	m.data[key] = m.order.PushBack(&Entry[K, V]{Key: key, Value: value})
+}
+
+// Get retrieves a value and a boolean indicating if the key exists.
+//
+// mu is an external mutex to lock the internal map during value retrieval
+func (m *LinkedValueMap[K, V]) Get(mu *sync.RWMutex, key K) (V, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if el, ok := m.data[key]; ok {
+		return el.Value.(*Entry[K, V]).Value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Delete removes a key from the map.
+//
+// mu is an external mutex to lock the internal map during key deletion
+func (m *LinkedValueMap[K, V]) Delete(mu *sync.RWMutex, key K) {
+	mu.Lock()
+	defer mu.Unlock()
+	if el, ok := m.data[key]; ok {
+		m.order.Remove(el)
+		delete(m.data, key)
+	}
+}
+
+// Len returns the number of key-value pairs.
+//
+// mu is an external mutex to lock the internal map during map content counting
+func (m *LinkedValueMap[K, V]) Len(mu *sync.RWMutex) int {
+	mu.RLock()
+	defer mu.RUnlock()
+	return len(m.data)
+}
+
+// Keys returns a slice of all keys in insertion order.
+//
+// mu is an external mutex to lock the internal map during key retrieval
+func (m *LinkedValueMap[K, V]) Keys(mu *sync.RWMutex) []K {
+	keys := make([]K, 0)
+	m.Range(mu, func(k K, _ V) bool {
+		keys = append(keys, k)
+		return true
+	})
+	return keys
+}
+
+// Values returns a slice of all values in insertion order.
+//
+// mu is an external mutex to lock the internal map during value retrieval
+func (m *LinkedValueMap[K, V]) Values(mu *sync.RWMutex) []V {
+	values := make([]V, 0)
+	m.Range(mu, func(_ K, v V) bool {
+		values = append(values, v)
+		return true
+	})
+	return values
+}
+
+// Range calls fn sequentially for each key and value in insertion order.
+// If fn returns false, Range stops the iteration.
+//
+// mu is an external mutex to lock the internal map during iteration,
+// so fn must not call methods that lock mu
+func (m *LinkedValueMap[K, V]) Range(mu *sync.RWMutex, fn func(key K, value V) bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	m.each(fn)
+}
+
+func (m *LinkedValueMap[K, V]) each(fn func(key K, value V) bool) {
+	for el := m.order.Front(); el != nil; el = el.Next() {
+		e := el.Value.(*Entry[K, V])
+		if !fn(e.Key, e.Value) {
+			return
+		}
+	}
+}
+
+// MarshalJSON encodes the map as a JSON object whose members
+// appear in insertion order. Keys are encoded as encoding/json
+// encodes map keys.
+//
+// It does not lock the map, so callers must hold the read lock
+// of the mutex guarding it.
+func (m *LinkedValueMap[K, V]) MarshalJSON() ([]byte, error) {
+	var (
+		buf bytes.Buffer
+		err error
+	)
+	buf.WriteByte('{')
+	m.each(func(k K, v V) bool {
+		var member []byte
+		if member, err = json.Marshal(map[K]V{k: v}); err != nil {
+			return false
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		// Strip the braces of the single-member object.
+		buf.Write(member[1 : len(member)-1])
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON replaces the contents of the map with the members
+// of a JSON object, inserted in the order they appear. A JSON null
+// leaves the map untouched.
+//
+// It does not lock the map, so callers must hold the write lock
+// of the mutex guarding it.
+func (m *LinkedValueMap[K, V]) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if tok, err := dec.Token(); err != nil {
+		return err
+	} else if tok != json.Delim('{') {
+		return fmt.Errorf("valuemap: cannot unmarshal %v into LinkedValueMap", tok)
+	}
+	fresh := NewLinked[K, V]()
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return err
+		}
+		// Decode the member through a single-entry map so that
+		// keys follow encoding/json map key rules.
+		name, err := json.Marshal(tok)
+		if err != nil {
+			return err
+		}
+		member := make(map[K]V, 1)
+		if err := json.Unmarshal(fmt.Appendf(nil, "{%s:%s}", name, raw), &member); err != nil {
+			return err
+		}
+		for k, v := range member {
+			fresh.set(k, v)
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	*m = *fresh
+	return nil
+}
@@ -0,0 +1,44 @@
+package valuemap
+
+import (
+	"encoding/json"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestLinkedValueMap(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := NewLinked[string, int]()
+	m.Set(&mu, "c", 1)
+	m.Set(&mu, "a", 2)
+	m.Set(&mu, "b", 3)
+	m.Set(&mu, "c", 4)
+	m.Delete(&mu, "a")
+
+	if keys := m.Keys(&mu); !slices.Equal(keys, []string{"c", "b"}) {
+		t.Errorf("Keys = %v", keys)
+	}
+	if values := m.Values(&mu); !slices.Equal(values, []int{4, 3}) {
+		t.Errorf("Values = %v", values)
+	}
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"c":4,"b":3}` {
+		t.Errorf("MarshalJSON = %s", b)
+	}
+
+	n := NewLinked[int, string]()
+	if err := json.Unmarshal([]byte(`{"3":"x","1":"y","2":"z"}`), n); err != nil {
+		t.Fatal(err)
+	}
+	if keys := n.Keys(&mu); !slices.Equal(keys, []int{3, 1, 2}) {
+		t.Errorf("Keys after UnmarshalJSON = %v", keys)
+	}
+	if err := json.Unmarshal([]byte(`null`), n); err != nil || n.Len(&mu) != 3 {
+		t.Errorf("UnmarshalJSON(null) = %v, leaving %d entries, want the map untouched", err, n.Len(&mu))
+	}
+}
@@ -0,0 +1,158 @@
+package valuemap
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by GetContext for a key that is not in the map
+// and that the loader, if any, did not find. Loaders return it, or an
+// error wrapping it, for keys that do not exist.
+var ErrNotFound = errors.New("valuemap: key not found")
+
+// WithLoader makes the map a read-through cache: when Get or GetContext
+// does not find a key, it calls load to fetch its value and stores it.
+// Concurrent misses of the same key share a single call of load, so a
+// burst of requests for a key that just expired reaches the backing store
+// once. load is called without the external mutex locked, so it can take
+// its time, but if the key is assigned while it runs, that value is kept
+// and returned instead of the loaded one. The types of load must match the
+// map, otherwise the constructor panics.
+//
+// load runs with the context of the call that started it, without its
+// cancellation, since other calls may be waiting for the same result. A
+// call whose context is done stops waiting and returns the context error.
+// Get passes context.Background; use GetContext to pass a context and
+// see the errors of load.
+func WithLoader[K comparable, V any](load func(ctx context.Context, key K) (V, error)) Option {
+	return optionFunc(func(c *config) {
+		c.loader = load
+	})
+}
+
+// loader holds the load function of a map created with WithLoader and its
+// calls in progress, which have their own lock since they run unlocked.
+type loader[K comparable, V any] struct {
+	load        func(ctx context.Context, key K) (V, error)
+	loadMany    func(ctx context.Context, keys []K) (map[K]V, error)
+	notFoundTTL time.Duration
+	errorTTL    time.Duration
+	mu          sync.Mutex
+	calls       map[K]*loadCall[V]
+	computed    bool          // values are new rather than loaded, so they are written to the store
+	failed      map[K]failure // cached failures, if negative caching is on
+}
+
+// loadCall is a call of the load function that callers wait for.
+type loadCall[V any] struct {
+	done     chan struct{}
+	value    V
+	err      error
+	assigned bool // the key was assigned while loading
+	waiters  int  // callers of GetOrCompute waiting for the call
+	cancel   context.CancelFunc
+}
+
+func newLoader[K comparable, V any](load func(ctx context.Context, key K) (V, error)) *loader[K, V] {
+	return &loader[K, V]{load: load, calls: make(map[K]*loadCall[V])}
+}
+
+// GetContext retrieves the value of a key. If the key is not present and
+// the map was created with WithLoader, the value is loaded and stored, and
+// the error of the loader is returned if it fails. Otherwise a missing key
+// is reported as ErrNotFound.
+//
+// mu is an external mutex to lock the internal map during value retrieval,
+// and while a loaded value is stored. It is not held while the loader runs
+func (m *ValueMap[K, V]) GetContext(ctx context.Context, mu *sync.RWMutex, key K) (V, error) {
+	v, ok, reload := m.get(mu, key)
+	if reload {
+		m.revalidate(mu, key)
+	}
+	if ok {
+		return v, nil
+	}
+	if m.loader == nil {
+		var zero V
+		return zero, ErrNotFound
+	}
+	return m.load(ctx, mu, key)
+}
+
+// load loads the value of a missing key, joining the call in progress for
+// the same key if there is one.
+func (m *ValueMap[K, V]) load(ctx context.Context, mu *sync.RWMutex, key K) (V, error) {
+	if err := m.failure(key); err != nil {
+		var zero V
+		return zero, err
+	}
+	c := m.call(ctx, mu, key)
+	select {
+	case <-c.done:
+		return c.value, c.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// call returns the call in progress that loads key, starting it if there
+// is none.
+func (m *ValueMap[K, V]) call(ctx context.Context, mu *sync.RWMutex, key K) *loadCall[V] {
+	l := m.loader
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c, ok := l.calls[key]
+	if !ok {
+		c = &loadCall[V]{done: make(chan struct{})}
+		l.calls[key] = c
+		detached := context.WithoutCancel(ctx)
+		go m.run(l, mu, key, c, func() (V, error) { return l.load(detached, key) })
+	}
+	return c
+}
+
+// supersede records that key was assigned, so that the value of a call in
+// progress does not replace the assigned one, and forgets its failure.
+// The caller must hold the write lock.
+func (l *loader[K, V]) supersede(key K) {
+	l.mu.Lock()
+	if c, ok := l.calls[key]; ok {
+		c.assigned = true
+	}
+	delete(l.failed, key)
+	l.mu.Unlock()
+}
+
+// run calls load for a call of l and stores the value it returns.
+func (m *ValueMap[K, V]) run(l *loader[K, V], mu *sync.RWMutex, key K, c *loadCall[V], load func() (V, error)) {
+	defer close(c.done)
+	c.value, c.err = load()
+	defer m.lock(mu).Unlock()
+	m.settle(l, key, c)
+}
+
+// settle stores the result of a call of l, or caches its failure, and
+// forgets the call. The caller must hold the write lock.
+func (m *ValueMap[K, V]) settle(l *loader[K, V], key K, c *loadCall[V]) {
+	if c.err == nil {
+		if v, ok := m.lookup(key); ok && c.assigned {
+			c.value = v
+		} else if l.computed {
+			m.store(key, c.value)
+		} else {
+			m.put(key, c.value)
+		}
+	} else if !c.assigned {
+		m.fail(l, key, c.err)
+	}
+	// The call is forgotten under the write lock, so that a later miss
+	// cannot start a new call before the loaded value is visible.
+	l.mu.Lock()
+	if l.calls[key] == c {
+		delete(l.calls, key)
+	}
+	l.mu.Unlock()
+}
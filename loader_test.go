@@ -0,0 +1,102 @@
+package valuemap
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithLoader(t *testing.T) {
+	mu := sync.RWMutex{}
+	var calls atomic.Int32
+	release := make(chan struct{})
+	m := New[string, int](WithLoader(func(ctx context.Context, key string) (int, error) {
+		calls.Add(1)
+		<-release
+		if key == "missing" {
+			return 0, ErrNotFound
+		}
+		return len(key), nil
+	}))
+
+	// Concurrent misses share one call.
+	var wg sync.WaitGroup
+	results := make([]int, 10)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = m.Get(&mu, "abc")
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if n := calls.Load(); n != 1 {
+		t.Errorf("loader called %d times, want 1", n)
+	}
+	for _, v := range results {
+		if v != 3 {
+			t.Fatalf("results = %v, want all 3", results)
+		}
+	}
+
+	// The loaded value is stored.
+	if v, err := m.GetContext(context.Background(), &mu, "abc"); v != 3 || err != nil || calls.Load() != 1 {
+		t.Errorf("GetContext(abc) = %d, %v after %d calls, want the stored 3", v, err, calls.Load())
+	}
+	if _, err := m.GetContext(context.Background(), &mu, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetContext(missing) error = %v, want ErrNotFound", err)
+	}
+	if _, ok := m.Get(&mu, "missing"); ok || m.Len(&mu) != 1 {
+		t.Error("a failed load was stored")
+	}
+}
+
+func TestWithLoaderKeepsConcurrentSet(t *testing.T) {
+	mu := sync.RWMutex{}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	m := New[string, int](WithLoader(func(ctx context.Context, key string) (int, error) {
+		close(started)
+		<-release
+		return 1, nil
+	}))
+
+	done := make(chan int)
+	go func() {
+		v, _ := m.Get(&mu, "a")
+		done <- v
+	}()
+	<-started
+	m.Set(&mu, "a", 2)
+	close(release)
+	if v := <-done; v != 2 {
+		t.Errorf("Get(a) = %d, want the value set while loading", v)
+	}
+	if v, _ := m.Get(&mu, "a"); v != 2 {
+		t.Errorf("stored %d, want 2", v)
+	}
+}
+
+func TestGetContextCanceled(t *testing.T) {
+	mu := sync.RWMutex{}
+	release := make(chan struct{})
+	m := New[string, int](WithLoader(func(ctx context.Context, key string) (int, error) {
+		<-release
+		return 1, ctx.Err()
+	}))
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := m.GetContext(ctx, &mu, "a"); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetContext error = %v, want context.Canceled", err)
+	}
+	if _, err := New[string, int]().GetContext(context.Background(), &mu, "a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetContext error = %v without a loader, want ErrNotFound", err)
+	}
+}
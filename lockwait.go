@@ -0,0 +1,19 @@
+package valuemap
+
+import "time"
+
+// ObserveLockWait registers fn to be called with the time each method of
+// the map spent waiting to lock the external mutex, and whether it locked
+// it for writing. A lock taken without waiting is reported as zero, without
+// reading the clock. fn is called with the mutex locked, so it must be fast
+// and must not call methods that lock it. It replaces the function
+// registered before, if any, and a nil fn stops the reports.
+//
+// ObserveLockWait does not lock the map, so it can be called at any time.
+func (m *ValueMap[K, V]) ObserveLockWait(fn func(write bool, wait time.Duration)) {
+	if fn == nil {
+		m.lockWait.Store(nil)
+		return
+	}
+	m.lockWait.Store(&fn)
+}
@@ -0,0 +1,47 @@
+package valuemap
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestObserveLockWait(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := New[string, int]()
+
+	type wait struct {
+		write bool
+		d     time.Duration
+	}
+	var waits []wait
+	m.ObserveLockWait(func(write bool, d time.Duration) {
+		waits = append(waits, wait{write, d})
+	})
+
+	m.Set(&mu, "a", 1)
+	m.Get(&mu, "a")
+	if len(waits) != 2 || !waits[0].write || waits[0].d != 0 || waits[1].write || waits[1].d != 0 {
+		t.Fatalf("waits = %v, want an uncontended write and read", waits)
+	}
+
+	mu.Lock()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.Get(&mu, "a")
+	}()
+	time.Sleep(20 * time.Millisecond)
+	mu.Unlock()
+	<-done
+	if last := waits[len(waits)-1]; last.write || last.d < 10*time.Millisecond {
+		t.Errorf("wait = %v, want a read that waited for the writer", last)
+	}
+
+	m.ObserveLockWait(nil)
+	n := len(waits)
+	m.Set(&mu, "b", 2)
+	if len(waits) != n {
+		t.Error("wait reported after the observer was removed")
+	}
+}
@@ -0,0 +1,59 @@
+package valuemap
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+)
+
+// LogValuer returns a slog.LogValuer that logs the map as a group holding
+// the number of entries under "len" and the entries under "entries", keyed
+// by the fmt representation of their keys in sorted order. Only the first
+// maxEntries entries are logged, or all of them if maxEntries is zero or
+// less. If redact is not nil, the value it returns is logged instead of
+// each value, so that sensitive values can be masked or left out.
+//
+// The map is read when the record is handled, not when LogValuer is called.
+//
+// mu is an external mutex to lock the internal map whenever the map is logged
+func (m *ValueMap[K, V]) LogValuer(mu *sync.RWMutex, maxEntries int, redact func(key K, value V) any) slog.LogValuer {
+	return logValuer[K, V]{m: m, mu: mu, max: maxEntries, redact: redact}
+}
+
+type logValuer[K comparable, V any] struct {
+	m      *ValueMap[K, V]
+	mu     *sync.RWMutex
+	max    int
+	redact func(key K, value V) any
+}
+
+// LogValue implements slog.LogValuer.
+func (l logValuer[K, V]) LogValue() slog.Value {
+	type entry struct {
+		key   string
+		value slog.Value
+	}
+	unlock := l.m.readLock(l.mu).Unlock
+	entries := make([]entry, 0, l.m.size())
+	for k, v := range l.m.live() {
+		var value any = v
+		if l.redact != nil {
+			value = l.redact(k, v)
+		}
+		entries = append(entries, entry{fmt.Sprint(k), slog.AnyValue(value)})
+	}
+	unlock()
+
+	n := len(entries)
+	slices.SortFunc(entries, func(a, b entry) int { return cmp.Compare(a.key, b.key) })
+	if l.max > 0 && n > l.max {
+		entries = entries[:l.max]
+	}
+	attrs := make([]slog.Attr, len(entries))
+	for i, e := range entries {
+		attrs[i] = slog.Attr{Key: e.key, Value: e.value}
+	}
+	return slog.GroupValue(slog.Int("len", n), slog.Attr{Key: "entries", Value: slog.GroupValue(attrs...)})
+}
@@ -0,0 +1,45 @@
+package valuemap
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestLogValuer(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := FromMap(map[string]string{"user": "ann", "password": "hunter2", "host": "db"})
+	redact := func(key, value string) any {
+		if key == "password" {
+			return "***"
+		}
+		return value
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	logger.Info("config", "m", m.LogValuer(&mu, 2, redact))
+
+	want := "level=INFO msg=config m.len=3 m.entries.host=db m.entries.password=***\n"
+	if buf.String() != want {
+		t.Errorf("logged %q, want %q", buf.String(), want)
+	}
+	if strings.Contains(buf.String(), "hunter2") {
+		t.Error("redacted value was logged")
+	}
+
+	buf.Reset()
+	logger.Info("config", "m", m.LogValuer(&mu, 0, nil))
+	if !strings.Contains(buf.String(), "m.entries.user=ann") {
+		t.Errorf("logged %q, want all entries", buf.String())
+	}
+}
@@ -0,0 +1,190 @@
+package valuemap
+
+import (
+	"cmp"
+	"sync"
+	"time"
+)
+
+// LWWEntry is the replicated state of one key of an LWWValueMap.
+// Its fields are exported so that states can be encoded and sent
+// between replicas.
+type LWWEntry[V any] struct {
+	Value   V
+	Time    int64  // write timestamp in nanoseconds since the Unix epoch
+	Node    string // replica that made the write
+	Deleted bool   // the entry is a tombstone
+}
+
+// newer reports whether e wins over o. Entries are ordered by time, then
+// by node, then deletions over assignments, which is a total order for
+// the entries of replicas with distinct node names.
+func (e LWWEntry[V]) newer(o LWWEntry[V]) bool {
+	if c := cmp.Compare(e.Time, o.Time); c != 0 {
+		return c > 0
+	}
+	if c := cmp.Compare(e.Node, o.Node); c != 0 {
+		return c > 0
+	}
+	return e.Deleted && !o.Deleted
+}
+
+// LWWState is the replicated state of an LWWValueMap, tombstones included.
+type LWWState[K comparable, V any] map[K]LWWEntry[V]
+
+// LWWValueMap is a last-writer-wins element map, a conflict-free replicated
+// data type. Every replica records the time and node of each write, and
+// deletions leave tombstones, so replicas modified independently can
+// exchange their State and Merge it in any order, any number of times,
+// and end up with the same contents: for each key, the latest write wins,
+// whether it assigned or deleted the key.
+//
+// Timestamps come from the wall clock of each replica, kept monotonic and
+// moved past every merged timestamp, so a write always wins over the writes
+// its replica has seen. Writes made concurrently on replicas with skewed
+// clocks resolve in favor of the later clock.
+type LWWValueMap[K comparable, V any] struct {
+	node    string
+	entries LWWState[K, V]
+	last    int64
+}
+
+// NewLWW returns a new pointer to an LWWValueMap for the replica named
+// node. Every replica must have a distinct name.
+func NewLWW[K comparable, V any](node string) *LWWValueMap[K, V] {
+	return &LWWValueMap[K, V]{node: node, entries: make(LWWState[K, V])}
+}
+
+// tick returns a timestamp later than any seen by the map.
+// The caller must hold the write lock.
+func (m *LWWValueMap[K, V]) tick() int64 {
+	m.last = max(time.Now().UnixNano(), m.last+1)
+	return m.last
+}
+
+// Set assigns a value to a key.
+//
+// mu is an external mutex to lock the internal map during value assigning
+func (m *LWWValueMap[K, V]) Set(mu *sync.RWMutex, key K, value V) {
+	mu.Lock()
+	defer mu.Unlock()
+	m.entries[key] = LWWEntry[V]{Value: value, Time: m.tick(), Node: m.node}
+}
+
+// Delete removes a key, leaving a tombstone that makes the deletion win
+// over earlier assignments of other replicas.
+//
+// mu is an external mutex to lock the internal map during key deletion
+func (m *LWWValueMap[K, V]) Delete(mu *sync.RWMutex, key K) {
+	mu.Lock()
+	defer mu.Unlock()
+	m.entries[key] = LWWEntry[V]{Time: m.tick(), Node: m.node, Deleted: true}
+}
+
+// Get retrieves a value by key.
+//
+// mu is an external mutex to lock the internal map during value retrieval
+func (m *LWWValueMap[K, V]) Get(mu *sync.RWMutex, key K) (V, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	e, ok := m.entries[key]
+	if !ok || e.Deleted {
+		var zero V
+		return zero, false
+	}
+	return e.Value, true
+}
+
+// Len returns the number of keys present, not counting tombstones.
+//
+// mu is an external mutex to lock the internal map during length retrieval
+func (m *LWWValueMap[K, V]) Len(mu *sync.RWMutex) int {
+	mu.RLock()
+	defer mu.RUnlock()
+	n := 0
+	for _, e := range m.entries {
+		if !e.Deleted {
+			n++
+		}
+	}
+	return n
+}
+
+// Range calls fn sequentially for each key and value present in the map.
+// If fn returns false, Range stops the iteration.
+//
+// mu is an external mutex to lock the internal map during iteration,
+// so fn must not call methods that lock mu
+func (m *LWWValueMap[K, V]) Range(mu *sync.RWMutex, fn func(key K, value V) bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	for k, e := range m.entries {
+		if !e.Deleted && !fn(k, e.Value) {
+			return
+		}
+	}
+}
+
+// Raw returns a copy of the keys and values present in the map.
+//
+// mu is an external mutex to lock the internal map during copying
+func (m *LWWValueMap[K, V]) Raw(mu *sync.RWMutex) map[K]V {
+	mu.RLock()
+	defer mu.RUnlock()
+	data := make(map[K]V, len(m.entries))
+	for k, e := range m.entries {
+		if !e.Deleted {
+			data[k] = e.Value
+		}
+	}
+	return data
+}
+
+// State returns a copy of the replicated state of the map, to be sent to
+// other replicas and merged there.
+//
+// mu is an external mutex to lock the internal map during copying
+func (m *LWWValueMap[K, V]) State(mu *sync.RWMutex) LWWState[K, V] {
+	mu.RLock()
+	defer mu.RUnlock()
+	s := make(LWWState[K, V], len(m.entries))
+	for k, e := range m.entries {
+		s[k] = e
+	}
+	return s
+}
+
+// Merge merges the state of another replica into the map, keeping for
+// each key the latest of the two entries. Merging is commutative,
+// associative and idempotent.
+//
+// mu is an external mutex to lock the internal map during merging
+func (m *LWWValueMap[K, V]) Merge(mu *sync.RWMutex, s LWWState[K, V]) {
+	mu.Lock()
+	defer mu.Unlock()
+	for k, e := range s {
+		if cur, ok := m.entries[k]; !ok || e.newer(cur) {
+			m.entries[k] = e
+		}
+		m.last = max(m.last, e.Time)
+	}
+}
+
+// Prune drops the tombstones written before t and returns how many were
+// dropped. A replica that has not yet seen a pruned deletion can bring the
+// deleted entry back when merged, so t must be older than the time it takes
+// every replica to merge the states of the others.
+//
+// mu is an external mutex to lock the internal map during pruning
+func (m *LWWValueMap[K, V]) Prune(mu *sync.RWMutex, t time.Time) int {
+	mu.Lock()
+	defer mu.Unlock()
+	n := 0
+	for k, e := range m.entries {
+		if e.Deleted && e.Time < t.UnixNano() {
+			delete(m.entries, k)
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,96 @@
+package valuemap
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLWWConcurrentDelete(t *testing.T) {
+	var mua, mub sync.RWMutex
+	a, b := NewLWW[string, int]("a"), NewLWW[string, int]("b")
+	a.Set(&mua, "x", 1)
+	b.Merge(&mub, a.State(&mua))
+
+	// b deletes x after seeing a's write; a's old write must not revive it.
+	b.Delete(&mub, "x")
+	b.Set(&mub, "y", 2)
+	a.Set(&mua, "z", 3)
+
+	a.Merge(&mua, b.State(&mub))
+	b.Merge(&mub, a.State(&mua))
+	if _, ok := a.Get(&mua, "x"); ok {
+		t.Error("deleted key x is present on a")
+	}
+	if !maps.Equal(a.Raw(&mua), b.Raw(&mub)) {
+		t.Errorf("replicas diverged: %v and %v", a.Raw(&mua), b.Raw(&mub))
+	}
+	if a.Len(&mua) != 2 {
+		t.Errorf("Len() = %d, want 2", a.Len(&mua))
+	}
+}
+
+func TestLWWMergeOrder(t *testing.T) {
+	mu := sync.RWMutex{}
+	states := []LWWState[string, int]{
+		{"k": {Value: 1, Time: 10, Node: "a"}, "j": {Value: 5, Time: 1, Node: "a"}},
+		{"k": {Value: 2, Time: 10, Node: "b"}},
+		{"k": {Time: 10, Node: "b", Deleted: true}, "j": {Value: 6, Time: 2, Node: "c"}},
+	}
+	orders := [][]int{{0, 1, 2}, {2, 1, 0}, {1, 2, 0, 2, 1}}
+	var results []LWWState[string, int]
+	for _, order := range orders {
+		m := NewLWW[string, int]("r")
+		for _, i := range order {
+			m.Merge(&mu, states[i])
+		}
+		results = append(results, m.State(&mu))
+	}
+	for i, r := range results[1:] {
+		if !maps.Equal(r, results[0]) {
+			t.Errorf("merge order %v gave %v, want %v", orders[i+1], r, results[0])
+		}
+	}
+	if e := results[0]["k"]; !e.Deleted {
+		t.Errorf("k = %+v, want the deletion to win the tie", e)
+	}
+	if e := results[0]["j"]; e.Value != 6 {
+		t.Errorf("j = %+v, want the later write", e)
+	}
+}
+
+func TestLWWStateEncoding(t *testing.T) {
+	mu := sync.RWMutex{}
+	a := NewLWW[string, int]("a")
+	a.Set(&mu, "x", 1)
+	a.Delete(&mu, "y")
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(a.State(&mu)); err != nil {
+		t.Fatal(err)
+	}
+	var s LWWState[string, int]
+	if err := json.NewDecoder(&buf).Decode(&s); err != nil {
+		t.Fatal(err)
+	}
+	b := NewLWW[string, int]("b")
+	b.Merge(&mu, s)
+	if v, _ := b.Get(&mu, "x"); v != 1 {
+		t.Errorf("Get(x) = %d, want 1", v)
+	}
+
+	// A write after merging wins over the merged entries.
+	b.Set(&mu, "y", 2)
+	a.Merge(&mu, b.State(&mu))
+	if v, ok := a.Get(&mu, "y"); !ok || v != 2 {
+		t.Errorf("Get(y) = %d, %v, want 2, true", v, ok)
+	}
+
+	a.Delete(&mu, "x")
+	if n := a.Prune(&mu, time.Now().Add(time.Hour)); n != 1 {
+		t.Errorf("Prune() = %d, want 1", n)
+	}
+}
@@ -0,0 +1,72 @@
+package valuemap
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrConflict is returned, wrapped with the key, by MergeWith with the
+// ErrorOnConflict strategy for a key present in both maps.
+var ErrConflict = errors.New("valuemap: conflicting key")
+
+// MergeStrategy decides the value of a key present in both maps merged by
+// MergeWith, from its existing value and the incoming one. An error aborts
+// the merge.
+type MergeStrategy[K comparable, V any] func(key K, existing, incoming V) (V, error)
+
+// Overwrite is the MergeStrategy of Merge: incoming values replace
+// existing ones.
+func Overwrite[K comparable, V any](key K, existing, incoming V) (V, error) {
+	return incoming, nil
+}
+
+// KeepExisting is a MergeStrategy that keeps existing values, so that
+// only keys missing from the map are added.
+func KeepExisting[K comparable, V any](key K, existing, incoming V) (V, error) {
+	return existing, nil
+}
+
+// ErrorOnConflict is a MergeStrategy that aborts the merge if a key is
+// present in both maps, whatever their values.
+func ErrorOnConflict[K comparable, V any](key K, existing, incoming V) (V, error) {
+	return existing, fmt.Errorf("%w %v", ErrConflict, key)
+}
+
+// Resolve returns a MergeStrategy that calls fn to combine the values of
+// the keys present in both maps, such as by adding counters or joining
+// lists.
+func Resolve[K comparable, V any](fn func(key K, existing, incoming V) V) MergeStrategy[K, V] {
+	return func(key K, existing, incoming V) (V, error) {
+		return fn(key, existing, incoming), nil
+	}
+}
+
+// MergeWith adds the keys of another ValueMap to this one, using strategy
+// to decide the value of the keys present in both:
+//
+//	m.MergeWith(&mu, other, valuemap.KeepExisting)
+//	m.MergeWith(&mu, other, valuemap.Resolve(func(k string, a, b int) int { return a + b }))
+//
+// The merge is done in one step: if strategy returns an error, the map is
+// left unchanged and the error is returned.
+//
+// mu is an external mutex to lock the internal map during value merging,
+// so strategy must not call methods that lock mu
+func (m *ValueMap[K, V]) MergeWith(mu *sync.RWMutex, other *ValueMap[K, V], strategy MergeStrategy[K, V]) error {
+	defer m.lock(mu).Unlock()
+	merged := make(map[K]V, other.size())
+	for k, v := range other.live() {
+		if cur, ok := m.lookup(k); ok {
+			var err error
+			if v, err = strategy(k, cur, v); err != nil {
+				return err
+			}
+		}
+		merged[k] = v
+	}
+	for k, v := range merged {
+		m.store(k, v)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package valuemap
+
+import "sync"
+
+// Side is the value of a key on one side of a three-way merge, or its
+// absence if Present is false.
+type Side[V any] struct {
+	Value   V
+	Present bool
+}
+
+// Merge3Func merges the changes made to theirs since base into the map,
+// which holds our own changes since base, as a version control system
+// merges branches. base is the common ancestor of both, such as the
+// contents of the map when it was last synchronized with theirs, taken
+// with Raw. For each key:
+//
+//   - if theirs did not change it, our value is kept;
+//   - if only theirs changed it, their value, or deletion, is applied;
+//   - if both changed it the same way, it is kept;
+//   - otherwise, conflict is called with the three sides and decides the
+//     value of the key, or its deletion by returning false.
+//
+// Values are compared with eq. The merge is done in one step, so readers
+// see the map either before or after it, and the changes it made to the
+// map are returned, for example to be sent to other replicas with
+// ApplyPatch.
+//
+// mu is an external mutex to lock the internal map during the merge, so
+// conflict must not call methods that lock mu
+func (m *ValueMap[K, V]) Merge3Func(mu *sync.RWMutex, base, theirs map[K]V, eq func(a, b V) bool, conflict func(key K, base, ours, theirs Side[V]) (V, bool)) Changeset[K, V] {
+	defer m.lock(mu).Unlock()
+	same := func(a, b Side[V]) bool {
+		return a.Present == b.Present && (!a.Present || eq(a.Value, b.Value))
+	}
+	c := Changeset[K, V]{
+		Added:   make(map[K]V),
+		Removed: make(map[K]V),
+		Changed: make(map[K]Change[V]),
+	}
+	merge := func(k K) {
+		var b, o, t Side[V]
+		b.Value, b.Present = base[k]
+		o.Value, o.Present = m.lookup(k)
+		t.Value, t.Present = theirs[k]
+		var res Side[V]
+		switch {
+		case same(b, t), same(o, t):
+			return
+		case same(b, o):
+			res = t
+		default:
+			res.Value, res.Present = conflict(k, b, o, t)
+		}
+		switch {
+		case same(o, res):
+		case !res.Present:
+			m.remove(k)
+			c.Removed[k] = o.Value
+		case o.Present:
+			m.store(k, res.Value)
+			c.Changed[k] = Change[V]{Old: o.Value, New: res.Value}
+		default:
+			m.store(k, res.Value)
+			c.Added[k] = res.Value
+		}
+	}
+	// Keys in neither base nor theirs were only added by us.
+	for k := range base {
+		merge(k)
+	}
+	for k := range theirs {
+		if _, ok := base[k]; !ok {
+			merge(k)
+		}
+	}
+	return c
+}
+
+// Merge3 is Merge3Func for comparable values, compared with ==.
+//
+// mu is an external mutex to lock the internal map during the merge, so
+// conflict must not call methods that lock mu
+func Merge3[K, V comparable](m *ValueMap[K, V], mu *sync.RWMutex, base, theirs map[K]V, conflict func(key K, base, ours, theirs Side[V]) (V, bool)) Changeset[K, V] {
+	return m.Merge3Func(mu, base, theirs, equal[V], conflict)
+}
@@ -0,0 +1,48 @@
+package valuemap
+
+import (
+	"maps"
+	"sync"
+	"testing"
+)
+
+func TestMerge3(t *testing.T) {
+	mu := sync.RWMutex{}
+	base := map[string]int{"same": 1, "ours": 1, "theirs": 1, "both": 1, "agree": 1, "gone": 1, "edited": 1}
+	ours := FromMap(base)
+	ours.Set(&mu, "ours", 2)
+	ours.Set(&mu, "both", 2)
+	ours.Set(&mu, "agree", 3)
+	ours.Delete(&mu, "edited")
+	ours.Set(&mu, "new", 1)
+
+	theirs := maps.Clone(base)
+	theirs["theirs"] = 2
+	theirs["both"] = 3
+	theirs["agree"] = 3
+	theirs["edited"] = 5
+	delete(theirs, "gone")
+	theirs["added"] = 7
+
+	var conflicts []string
+	c := Merge3(ours, &mu, base, theirs, func(key string, b, o, t Side[int]) (int, bool) {
+		conflicts = append(conflicts, key)
+		if !o.Present {
+			return t.Value, true
+		}
+		return o.Value + t.Value, true
+	})
+
+	want := map[string]int{"same": 1, "ours": 2, "theirs": 2, "both": 5, "agree": 3, "edited": 5, "new": 1, "added": 7}
+	if got := ours.Raw(&mu); !maps.Equal(got, want) {
+		t.Errorf("merged = %v, want %v", got, want)
+	}
+	if len(conflicts) != 2 {
+		t.Errorf("conflicts = %v, want both and edited", conflicts)
+	}
+	if !maps.Equal(c.Added, map[string]int{"added": 7, "edited": 5}) ||
+		!maps.Equal(c.Removed, map[string]int{"gone": 1}) ||
+		!maps.Equal(c.Changed, map[string]Change[int]{"theirs": {Old: 1, New: 2}, "both": {Old: 2, New: 5}}) {
+		t.Errorf("changeset = %+v", c)
+	}
+}
@@ -0,0 +1,39 @@
+package valuemap
+
+import (
+	"errors"
+	"maps"
+	"sync"
+	"testing"
+)
+
+func TestMergeWith(t *testing.T) {
+	mu := sync.RWMutex{}
+	incoming := FromMap(map[string]int{"a": 10, "c": 3})
+	tests := []struct {
+		name     string
+		strategy MergeStrategy[string, int]
+		want     map[string]int
+	}{
+		{"overwrite", Overwrite[string, int], map[string]int{"a": 10, "b": 2, "c": 3}},
+		{"keep existing", KeepExisting[string, int], map[string]int{"a": 1, "b": 2, "c": 3}},
+		{"resolve", Resolve(func(k string, a, b int) int { return a + b }), map[string]int{"a": 11, "b": 2, "c": 3}},
+	}
+	for _, tt := range tests {
+		m := FromMap(map[string]int{"a": 1, "b": 2})
+		if err := m.MergeWith(&mu, incoming, tt.strategy); err != nil {
+			t.Errorf("%s: MergeWith error = %v", tt.name, err)
+		}
+		if got := m.Raw(&mu); !maps.Equal(got, tt.want) {
+			t.Errorf("%s: merged = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	m := FromMap(map[string]int{"a": 1, "b": 2})
+	if err := m.MergeWith(&mu, incoming, ErrorOnConflict); !errors.Is(err, ErrConflict) {
+		t.Errorf("MergeWith error = %v, want ErrConflict", err)
+	}
+	if got := m.Raw(&mu); !maps.Equal(got, map[string]int{"a": 1, "b": 2}) {
+		t.Errorf("map = %v after a failed merge, want it unchanged", got)
+	}
+}
@@ -0,0 +1,326 @@
+package valuemap
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"sync"
+)
+
+// ErrReadOnly is reported by the write methods of a map opened read-only.
+var ErrReadOnly = errors.New("valuemap: map is read-only")
+
+// minMappedSize is the smallest size a MappedValueMap grows its file to.
+const minMappedSize = 64 << 10
+
+// MappedValueMap is an experimental map that keeps its entries in a
+// memory-mapped file, holding only the keys and the offsets of their
+// entries in memory. Values are decoded from the mapping on each read, so
+// the map can hold more data than fits in RAM, and other processes can open
+// the same file read-only to share it.
+//
+// The file is an append-only log of records, framed and checksummed like
+// the records of WithJournal and encoded with the Codec given to
+// OpenMapped. Every change appends a record, so overwritten and deleted
+// entries keep taking space in the file. Opening the file replays the log
+// up to the first incomplete or damaged record; opening it for writing
+// also truncates the file there, so that the records after it can never
+// be replayed again.
+//
+// Memory mapping is only supported on Unix systems; elsewhere OpenMapped
+// returns errors.ErrUnsupported. Since the methods do not return errors,
+// the first failed write stops further writes, and the error is reported
+// by Err and Close.
+type MappedValueMap[K comparable, V any] struct {
+	f        *os.File
+	codec    Codec[K, V]
+	data     []byte // mapping of the file
+	size     int    // length of the valid records at the start of data
+	index    map[K]span
+	readOnly bool
+	buf      bytes.Buffer
+	err      error
+}
+
+// span locates the payload of a record in the file.
+type span struct {
+	off, n int
+}
+
+// OpenMapped opens the map stored in the file at path for reading and
+// writing, creating the file if it does not exist. Entries are encoded with c.
+// Only one process may open a file for writing at a time.
+func OpenMapped[K comparable, V any](path string, c Codec[K, V]) (*MappedValueMap[K, V], error) {
+	return openMapped(path, c, false)
+}
+
+// OpenMappedReadOnly opens the map stored in the file at path for reading.
+// Call Reload to see entries written since by another process.
+func OpenMappedReadOnly[K comparable, V any](path string, c Codec[K, V]) (*MappedValueMap[K, V], error) {
+	return openMapped(path, c, true)
+}
+
+func openMapped[K comparable, V any](path string, c Codec[K, V], readOnly bool) (*MappedValueMap[K, V], error) {
+	if !mmapSupported {
+		return nil, errors.ErrUnsupported
+	}
+	flag := os.O_RDWR | os.O_CREATE
+	if readOnly {
+		flag = os.O_RDONLY
+	}
+	f, err := os.OpenFile(path, flag, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	m := &MappedValueMap[K, V]{f: f, codec: c, index: make(map[K]span), readOnly: readOnly}
+	if err := m.load(); err != nil {
+		m.remap(0)
+		f.Close()
+		return nil, err
+	}
+	if !readOnly && len(m.data) > m.size {
+		// Drop the damaged tail and the space reserved for growth, so that
+		// the file is grown again with zeros and no stale record remains
+		// after the ones written from now on.
+		if err := m.truncate(); err != nil {
+			m.remap(0)
+			f.Close()
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// load maps the whole file and indexes the records not indexed yet.
+func (m *MappedValueMap[K, V]) load() error {
+	fi, err := m.f.Stat()
+	if err != nil {
+		return err
+	}
+	if n := int(fi.Size()); n != len(m.data) {
+		if err := m.remap(n); err != nil {
+			return err
+		}
+	}
+	for {
+		payload, size, ok := nextRecord(m.data[m.size:])
+		if !ok {
+			return nil
+		}
+		op, key, _, ok := decodeRecord(payload, m.codec)
+		if !ok {
+			return nil
+		}
+		switch op {
+		case opSet:
+			m.index[key] = span{off: m.size + recordHeaderSize, n: len(payload)}
+		case opDelete:
+			delete(m.index, key)
+		case opClear:
+			clear(m.index)
+		}
+		m.size += size
+	}
+}
+
+// truncate shrinks the file and its mapping to the valid records.
+func (m *MappedValueMap[K, V]) truncate() error {
+	if err := m.remap(0); err != nil {
+		return err
+	}
+	if err := m.f.Truncate(int64(m.size)); err != nil {
+		return err
+	}
+	return m.remap(m.size)
+}
+
+// remap replaces the mapping with one of n bytes.
+func (m *MappedValueMap[K, V]) remap(n int) error {
+	if m.data != nil {
+		if err := munmap(m.data); err != nil {
+			return err
+		}
+		m.data = nil
+	}
+	if n == 0 {
+		return nil
+	}
+	data, err := mmap(m.f, n)
+	if err != nil {
+		return err
+	}
+	m.data = data
+	return nil
+}
+
+// write appends a record to the file and returns the location of its
+// payload. The caller must hold the write lock.
+func (m *MappedValueMap[K, V]) write(op byte, entry map[K]V) (span, bool) {
+	if m.err != nil {
+		return span{}, false
+	}
+	if m.readOnly {
+		m.err = ErrReadOnly
+		return span{}, false
+	}
+	m.buf.Reset()
+	if m.err = appendRecord(&m.buf, op, entry, m.codec); m.err != nil {
+		return span{}, false
+	}
+	rec := m.buf.Bytes()
+	if need := m.size + len(rec); need > len(m.data) {
+		n := max(2*len(m.data), need, minMappedSize)
+		if m.err = m.f.Truncate(int64(n)); m.err != nil {
+			return span{}, false
+		}
+		if m.err = m.remap(n); m.err != nil {
+			return span{}, false
+		}
+	}
+	if _, m.err = m.f.WriteAt(rec, int64(m.size)); m.err != nil {
+		return span{}, false
+	}
+	sp := span{off: m.size + recordHeaderSize, n: len(rec) - recordHeaderSize}
+	m.size += len(rec)
+	return sp, true
+}
+
+// value decodes the value stored at sp.
+func (m *MappedValueMap[K, V]) value(sp span) (V, bool) {
+	_, _, v, ok := decodeRecord(m.data[sp.off:sp.off+sp.n], m.codec)
+	return v, ok
+}
+
+// Set assigns a value to a key.
+//
+// mu is an external mutex to lock the internal map during value assigning
+func (m *MappedValueMap[K, V]) Set(mu *sync.RWMutex, key K, value V) {
+	mu.Lock()
+	defer mu.Unlock()
+	if sp, ok := m.write(opSet, map[K]V{key: value}); ok {
+		m.index[key] = sp
+	}
+}
+
+// Get retrieves a value by key.
+//
+// mu is an external mutex to lock the internal map during value retrieval
+func (m *MappedValueMap[K, V]) Get(mu *sync.RWMutex, key K) (V, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	sp, ok := m.index[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	return m.value(sp)
+}
+
+// Delete removes a key.
+//
+// mu is an external mutex to lock the internal map during key deletion
+func (m *MappedValueMap[K, V]) Delete(mu *sync.RWMutex, key K) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := m.index[key]; !ok {
+		return
+	}
+	var zero V
+	if _, ok := m.write(opDelete, map[K]V{key: zero}); ok {
+		delete(m.index, key)
+	}
+}
+
+// Len returns the number of entries.
+//
+// mu is an external mutex to lock the internal map during length retrieval
+func (m *MappedValueMap[K, V]) Len(mu *sync.RWMutex) int {
+	mu.RLock()
+	defer mu.RUnlock()
+	return len(m.index)
+}
+
+// Keys returns a slice of all keys.
+//
+// mu is an external mutex to lock the internal map during key retrieval
+func (m *MappedValueMap[K, V]) Keys(mu *sync.RWMutex) []K {
+	mu.RLock()
+	defer mu.RUnlock()
+	keys := make([]K, 0, len(m.index))
+	for k := range m.index {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Values returns a slice of all values.
+//
+// mu is an external mutex to lock the internal map during value retrieval
+func (m *MappedValueMap[K, V]) Values(mu *sync.RWMutex) []V {
+	mu.RLock()
+	defer mu.RUnlock()
+	values := make([]V, 0, len(m.index))
+	for _, sp := range m.index {
+		if v, ok := m.value(sp); ok {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// Range calls fn sequentially for each key and value present in the map.
+// If fn returns false, Range stops the iteration.
+//
+// mu is an external mutex to lock the internal map during iteration,
+// so fn must not call methods that lock mu
+func (m *MappedValueMap[K, V]) Range(mu *sync.RWMutex, fn func(key K, value V) bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	for k, sp := range m.index {
+		v, ok := m.value(sp)
+		if ok && !fn(k, v) {
+			return
+		}
+	}
+}
+
+// Clear removes all entries.
+//
+// mu is an external mutex to lock the internal map during clearing
+func (m *MappedValueMap[K, V]) Clear(mu *sync.RWMutex) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := m.write(opClear, nil); ok {
+		clear(m.index)
+	}
+}
+
+// Reload maps records appended to the file by another process since the
+// map was opened or last reloaded. It is meant for maps opened read-only.
+//
+// mu is an external mutex to lock the internal map during reloading
+func (m *MappedValueMap[K, V]) Reload(mu *sync.RWMutex) error {
+	mu.Lock()
+	defer mu.Unlock()
+	return m.load()
+}
+
+// Err returns the error that stopped writes to the map, if any.
+//
+// mu is an external mutex to lock the internal map during error retrieval
+func (m *MappedValueMap[K, V]) Err(mu *sync.RWMutex) error {
+	mu.RLock()
+	defer mu.RUnlock()
+	return m.err
+}
+
+// Close unmaps and closes the file, first trimming the space reserved for
+// growth if the map was opened for writing. It returns the error that
+// stopped writes, if any. The map must not be used afterwards.
+func (m *MappedValueMap[K, V]) Close() error {
+	err := m.remap(0)
+	if !m.readOnly {
+		err = errors.Join(err, m.f.Truncate(int64(m.size)))
+	}
+	return errors.Join(m.err, err, m.f.Close())
+}
@@ -0,0 +1,18 @@
+//go:build !unix
+
+package valuemap
+
+import (
+	"errors"
+	"os"
+)
+
+const mmapSupported = false
+
+func mmap(f *os.File, n int) ([]byte, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func munmap(b []byte) error {
+	return nil
+}
@@ -0,0 +1,179 @@
+//go:build unix
+
+package valuemap
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMappedValueMap(t *testing.T) {
+	mu := sync.RWMutex{}
+	path := filepath.Join(t.TempDir(), "data.map")
+	c := JSONCodec[int, string]{}
+
+	m, err := OpenMapped(path, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	big := strings.Repeat("x", 1000)
+	for i := range 200 { // grows the file past minMappedSize
+		m.Set(&mu, i, big)
+	}
+	m.Set(&mu, 1, "one")
+	m.Delete(&mu, 2)
+	if v, ok := m.Get(&mu, 1); !ok || v != "one" {
+		t.Errorf("Get(1) = %q, %v, want one, true", v, ok)
+	}
+	if v, _ := m.Get(&mu, 150); v != big {
+		t.Errorf("Get(150) returned %d bytes, want %d", len(v), len(big))
+	}
+	if m.Len(&mu) != 199 {
+		t.Errorf("Len() = %d, want 199", m.Len(&mu))
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err = OpenMapped(path, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if _, ok := m.Get(&mu, 2); ok {
+		t.Error("deleted key 2 is present after reopening")
+	}
+	if v, _ := m.Get(&mu, 1); v != "one" {
+		t.Errorf("Get(1) = %q after reopening, want one", v)
+	}
+	m.Clear(&mu)
+	if m.Len(&mu) != 0 || len(m.Keys(&mu)) != 0 {
+		t.Errorf("Len() = %d after Clear, want 0", m.Len(&mu))
+	}
+}
+
+func TestMappedValueMapReadOnly(t *testing.T) {
+	var wmu, rmu sync.RWMutex
+	path := filepath.Join(t.TempDir(), "data.map")
+	c := GobCodec[string, int]{}
+
+	w, err := OpenMapped(path, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.Set(&wmu, "a", 1)
+
+	r, err := OpenMappedReadOnly(path, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if v, _ := r.Get(&rmu, "a"); v != 1 {
+		t.Errorf("Get(a) = %d, want 1", v)
+	}
+
+	w.Set(&wmu, "b", 2)
+	if _, ok := r.Get(&rmu, "b"); ok {
+		t.Error("reader saw b before Reload")
+	}
+	if err := r.Reload(&rmu); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := r.Get(&rmu, "b"); v != 2 {
+		t.Errorf("Get(b) = %d after Reload, want 2", v)
+	}
+	sum := 0
+	r.Range(&rmu, func(_ string, v int) bool {
+		sum += v
+		return true
+	})
+	if sum != 3 {
+		t.Errorf("sum of values = %d, want 3", sum)
+	}
+
+	r.Set(&rmu, "c", 3)
+	if err := r.Err(&rmu); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Err() = %v, want ErrReadOnly", err)
+	}
+}
+
+func TestMappedValueMapTornWrite(t *testing.T) {
+	mu := sync.RWMutex{}
+	path := filepath.Join(t.TempDir(), "data.map")
+	c := JSONCodec[string, int]{}
+
+	m, err := OpenMapped(path, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Set(&mu, "a", 1)
+	m.Set(&mu, "b", 2)
+	m.Close()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, b[:len(b)-2], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m, err = OpenMapped(path, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if m.Len(&mu) != 1 {
+		t.Errorf("Len() = %d, want 1", m.Len(&mu))
+	}
+	m.Set(&mu, "c", 3)
+	if v, _ := m.Get(&mu, "c"); v != 3 {
+		t.Errorf("Get(c) = %d, want 3", v)
+	}
+}
+
+func TestMappedValueMapDamagedTail(t *testing.T) {
+	mu := sync.RWMutex{}
+	path := filepath.Join(t.TempDir(), "data.map")
+	c := JSONCodec[string, string]{}
+
+	m, err := OpenMapped(path, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Set(&mu, "a", "1")
+	damaged := m.size + recordHeaderSize + 2
+	m.Set(&mu, "b", "2")
+	m.Set(&mu, "c", "3")
+	if _, err := m.f.WriteAt([]byte{^m.data[damaged]}, int64(damaged)); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err = OpenMapped(path, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := m.Keys(&mu); len(keys) != 1 || keys[0] != "a" {
+		t.Fatalf("Keys() = %v after damage, want [a]", keys)
+	}
+	// Write a record the size of b, then crash without Close.
+	m.Set(&mu, "d", "4")
+	m.remap(0)
+	m.f.Close()
+
+	m, err = OpenMapped(path, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if _, ok := m.Get(&mu, "c"); ok || m.Len(&mu) != 2 {
+		t.Errorf("Keys() = %v, want a and d only", m.Keys(&mu))
+	}
+}
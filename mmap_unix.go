@@ -0,0 +1,18 @@
+//go:build unix
+
+package valuemap
+
+import (
+	"os"
+	"syscall"
+)
+
+const mmapSupported = true
+
+func mmap(f *os.File, n int) ([]byte, error) {
+	return syscall.Mmap(int(f.Fd()), 0, n, syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+func munmap(b []byte) error {
+	return syscall.Munmap(b)
+}
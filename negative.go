@@ -0,0 +1,87 @@
+package valuemap
+
+import (
+	"errors"
+	"time"
+)
+
+// WithNegativeCache makes a map created with WithLoader remember the keys
+// that its loader failed to load, so that they are not loaded again at
+// every lookup. Keys not found, for which the loader returned ErrNotFound
+// or an error wrapping it, are remembered for notFoundTTL, and keys whose
+// load failed with another error for errorTTL. Until then, Get and
+// GetContext return the same result at once without calling the loader.
+// A duration of zero or less disables caching of that kind of failure.
+//
+// Assigning a key forgets its failure, and so does Clear for all keys.
+// Failures are otherwise dropped when they are looked up after their TTL,
+// and by DeleteExpired, so a map with many distinct missing keys should
+// have a janitor. Using it without a loader, either from WithLoader or
+// from a store, panics.
+func WithNegativeCache(notFoundTTL, errorTTL time.Duration) Option {
+	return optionFunc(func(c *config) {
+		c.notFoundTTL = notFoundTTL
+		c.errorTTL = errorTTL
+	})
+}
+
+// failure is a cached load failure of a key.
+type failure struct {
+	err   error
+	until time.Time
+}
+
+// failure returns the cached load failure of a key, if any.
+func (m *ValueMap[K, V]) failure(key K) error {
+	l := m.loader
+	if l.failed == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, ok := l.failed[key]
+	if !ok {
+		return nil
+	}
+	if !m.now().Before(f.until) {
+		delete(l.failed, key)
+		return nil
+	}
+	return f.err
+}
+
+// fail caches the failure of a call of l for a key, if l caches them.
+// The caller must hold the write lock.
+func (m *ValueMap[K, V]) fail(l *loader[K, V], key K, err error) {
+	ttl := l.errorTTL
+	if errors.Is(err, ErrNotFound) {
+		ttl = l.notFoundTTL
+	}
+	if l.failed == nil || ttl <= 0 {
+		return
+	}
+	l.mu.Lock()
+	l.failed[key] = failure{err: err, until: m.now().Add(ttl)}
+	l.mu.Unlock()
+}
+
+// forgetFailures drops the cached load failures, all of them or only
+// those whose TTL has passed.
+func (m *ValueMap[K, V]) forgetFailures(all bool) {
+	l := m.loader
+	if l == nil || l.failed == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if all {
+		clear(l.failed)
+		return
+	}
+	now := m.now()
+	for k, f := range l.failed {
+		if !now.Before(f.until) {
+			delete(l.failed, k)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package valuemap
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithNegativeCache(t *testing.T) {
+	mu := sync.RWMutex{}
+	clock := NewManualClock(time.Unix(0, 0))
+	errDown := errors.New("unavailable")
+	calls := map[string]int{}
+	m := New[string, int](
+		WithClock(clock),
+		WithLoader(func(ctx context.Context, key string) (int, error) {
+			calls[key]++
+			switch key {
+			case "missing":
+				return 0, ErrNotFound
+			case "broken":
+				return 0, errDown
+			}
+			return 1, nil
+		}),
+		WithNegativeCache(time.Minute, time.Second),
+	)
+
+	for range 3 {
+		if _, err := m.GetContext(context.Background(), &mu, "missing"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("GetContext(missing) error = %v, want ErrNotFound", err)
+		}
+		if _, err := m.GetContext(context.Background(), &mu, "broken"); !errors.Is(err, errDown) {
+			t.Errorf("GetContext(broken) error = %v, want %v", err, errDown)
+		}
+	}
+	if calls["missing"] != 1 || calls["broken"] != 1 {
+		t.Errorf("loader calls = %v, want one per key", calls)
+	}
+
+	// Errors are cached for less time than missing keys.
+	clock.Advance(2 * time.Second)
+	m.Get(&mu, "missing")
+	m.Get(&mu, "broken")
+	if calls["missing"] != 1 || calls["broken"] != 2 {
+		t.Errorf("loader calls = %v after the error TTL", calls)
+	}
+
+	// Assigning a key forgets its failure.
+	m.Set(&mu, "missing", 2)
+	m.Delete(&mu, "missing")
+	m.Get(&mu, "missing")
+	if calls["missing"] != 2 {
+		t.Errorf("loader calls = %v after an assignment", calls)
+	}
+
+	clock.Advance(time.Hour)
+	m.DeleteExpired(&mu)
+	if n := len(m.loader.failed); n != 0 {
+		t.Errorf("%d failures left after DeleteExpired", n)
+	}
+}
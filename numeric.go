@@ -0,0 +1,36 @@
+package valuemap
+
+import "sync"
+
+// Number is a constraint that permits any integer or floating-point type.
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64
+}
+
+// Add adds delta to the value of a key and returns the new value.
+// A missing key is treated as zero.
+//
+// mu is an external mutex to lock the internal map during the addition
+func Add[K comparable, V Number](m *ValueMap[K, V], mu *sync.RWMutex, key K, delta V) V {
+	defer m.lock(mu).Unlock()
+	cur, _ := m.lookup(key)
+	v := cur + delta
+	m.store(key, v)
+	return v
+}
+
+// Increment adds one to the value of a key and returns the new value.
+//
+// mu is an external mutex to lock the internal map during the addition
+func Increment[K comparable, V Number](m *ValueMap[K, V], mu *sync.RWMutex, key K) V {
+	return Add(m, mu, key, 1)
+}
+
+// Decrement subtracts one from the value of a key and returns the new value.
+//
+// mu is an external mutex to lock the internal map during the subtraction
+func Decrement[K comparable, V Number](m *ValueMap[K, V], mu *sync.RWMutex, key K) V {
+	return Add(m, mu, key, V(0)-1)
+}
@@ -0,0 +1,33 @@
+package valuemap
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestAdd(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := New[string, int]()
+
+	var wg sync.WaitGroup
+	for range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			Increment(m, &mu, "hits")
+		}()
+	}
+	wg.Wait()
+
+	if v := Add(m, &mu, "hits", 10); v != 110 {
+		t.Errorf("Add = %d, want 110", v)
+	}
+	if v := Decrement(m, &mu, "hits"); v != 109 {
+		t.Errorf("Decrement = %d, want 109", v)
+	}
+
+	f := New[string, float64]()
+	if v := Add(f, &mu, "x", 1.5); v != 1.5 {
+		t.Errorf("Add = %v, want 1.5", v)
+	}
+}
@@ -0,0 +1,176 @@
+package valuemap
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Option configures a ValueMap at construction.
+type Option interface {
+	apply(c *config)
+}
+
+type optionFunc func(c *config)
+
+func (f optionFunc) apply(c *config) {
+	f(c)
+}
+
+// config collects the settings of the options passed to a constructor.
+// Settings that depend on the key or value type are kept as any and
+// asserted to the types of the map being built by typed.
+type config struct {
+	capacity int
+	shards   int
+
+	bounded            bool
+	maxEntries         int
+	policy             Policy
+	customPolicy       any // EvictionPolicy[K]
+	maxCost            int64
+	costFn             any // func(K, V) int64
+	onEvict            any // func(K, V, RemovalReason)
+	tracer             Tracer
+	clock              Clock
+	loader             any // func(context.Context, K) (V, error)
+	bulkLoader         any // BulkLoader[K, V]
+	maxStale           time.Duration
+	refreshAhead       float64
+	notFoundTTL        time.Duration
+	errorTTL           time.Duration
+	store              any // Store[K, V]
+	writeBehind        bool
+	flushInterval      time.Duration
+	hotKeys            int
+	revisions          bool
+	historyDepth       int
+	tombstoneRetention time.Duration
+	reverseIndex       func() any // returns a valueIndex[K, V]
+	audit              any        // func(AuditRecord[K, V])
+	auditW             io.Writer
+
+	ttlMu           *sync.RWMutex
+	ttl             time.Duration
+	cleanupInterval time.Duration
+	sliding         bool
+
+	autosaveMu       *sync.RWMutex
+	autosavePath     string
+	autosaveInterval time.Duration
+	autosaveCodec    any // Codec[K, V]
+
+	journalMu          *sync.RWMutex
+	journalW           io.Writer
+	journalCodec       any // Codec[K, V]
+	checkpointPath     string
+	checkpointInterval time.Duration
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, o := range opts {
+		o.apply(c)
+	}
+	return c
+}
+
+// configure applies the settings shared by all constructors.
+func (m *ValueMap[K, V]) configure(c *config) {
+	m.tracer = c.tracer
+	m.clock = c.clock
+	if c.revisions {
+		m.revs = &revisions[K]{byKey: make(map[K]uint64)}
+	}
+	if c.historyDepth > 0 {
+		m.hist = &history[K, V]{depth: c.historyDepth, byKey: make(map[K][]V)}
+	}
+	if c.audit != nil {
+		m.audit = typed[func(AuditRecord[K, V])](c.audit, "audit function")
+	}
+	if c.auditW != nil {
+		m.auditLog = &auditLog{w: c.auditW}
+		log := auditTo[K, V](m.auditLog)
+		if fn := m.audit; fn != nil {
+			m.audit = func(r AuditRecord[K, V]) {
+				fn(r)
+				log(r)
+			}
+		} else {
+			m.audit = log
+		}
+	}
+	if c.tombstoneRetention > 0 {
+		m.graves().retention = c.tombstoneRetention
+	}
+	if c.reverseIndex != nil {
+		m.reverse = typed[valueIndex[K, V]](c.reverseIndex(), "reverse index")
+		for k, v := range m.each() {
+			m.reverse.add(k, v)
+		}
+	}
+	if c.hotKeys > 0 {
+		m.hot = newHotKeys[K](c.hotKeys)
+	}
+	if c.store != nil {
+		m.startWriter(c)
+	}
+	if c.loader != nil {
+		m.loader = newLoader(typed[func(context.Context, K) (V, error)](c.loader, "loader"))
+	} else if m.writer != nil {
+		m.loader = newLoader(m.writer.load)
+	}
+	if c.bulkLoader != nil {
+		bl := typed[BulkLoader[K, V]](c.bulkLoader, "bulk loader")
+		if m.loader == nil {
+			m.loader = newLoader(bulkLoad(bl))
+		}
+		m.loader.loadMany = bl.LoadMany
+	}
+	if c.notFoundTTL > 0 || c.errorTTL > 0 {
+		if m.loader == nil {
+			panic("valuemap: WithNegativeCache requires a loader")
+		}
+		m.loader.notFoundTTL = c.notFoundTTL
+		m.loader.errorTTL = c.errorTTL
+		m.loader.failed = make(map[K]failure)
+	}
+	if c.maxStale > 0 {
+		if m.ttl == nil || m.loader == nil {
+			panic("valuemap: WithStaleWhileRevalidate requires WithTTL and a loader")
+		}
+		m.ttl.stale = c.maxStale
+	}
+	if c.refreshAhead != 0 {
+		if m.ttl == nil || m.loader == nil {
+			panic("valuemap: WithRefreshAhead requires WithTTL and a loader")
+		}
+		if c.refreshAhead < 0 || c.refreshAhead >= 1 {
+			panic(fmt.Sprintf("valuemap: refresh-ahead fraction %v is not between 0 and 1", c.refreshAhead))
+		}
+		m.ttl.refresh = c.refreshAhead
+	}
+	if c.onEvict != nil {
+		m.onEvict = typed[func(K, V, RemovalReason)](c.onEvict, "eviction callback")
+	}
+	if c.journalCodec != nil {
+		m.startJournal(c)
+	} else if c.checkpointPath != "" {
+		panic("valuemap: WithCheckpoint requires WithJournal")
+	}
+	if c.autosaveCodec != nil {
+		m.startAutosave(c)
+	}
+}
+
+// typed asserts that an option value matches the types of the map being
+// built, and panics otherwise since the options were combined wrongly.
+func typed[T any](v any, what string) T {
+	t, ok := v.(T)
+	if !ok {
+		panic(fmt.Sprintf("valuemap: %s %T does not match the types of the map", what, v))
+	}
+	return t
+}
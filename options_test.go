@@ -0,0 +1,58 @@
+package valuemap
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewOptions(t *testing.T) {
+	mu := sync.RWMutex{}
+	var evicted []string
+	m := New[string, int](
+		WithCapacity(4),
+		WithMaxEntries(2),
+		WithTTL(&mu, time.Hour, 0),
+		WithOnEvict(func(key string, _ int, reason RemovalReason) {
+			evicted = append(evicted, key+" "+reason.String())
+		}),
+	)
+	defer m.Close()
+
+	m.Set(&mu, "a", 1)
+	m.SetWithTTL(&mu, "b", 2, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	m.Set(&mu, "c", 3)
+	m.Set(&mu, "d", 4)
+	if n := m.DeleteExpired(&mu); n != 0 {
+		t.Errorf("DeleteExpired() = %d, want b already gone", n)
+	}
+	if got := m.Len(&mu); got != 2 {
+		t.Errorf("Len() = %d, want the limit of 2", got)
+	}
+	if want := []string{"a evicted", "b expired"}; !slices.Equal(evicted, want) {
+		t.Errorf("evicted %q, want %q", evicted, want)
+	}
+	if d, ok := m.TTL(&mu, "d"); !ok || d <= 0 {
+		t.Errorf("TTL(d) = %v, %v, want the default lifetime", d, ok)
+	}
+}
+
+func TestNewBoundingOptions(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := New[string, int](WithPolicy(TinyLFU))
+	for _, k := range []string{"a", "b", "c"} {
+		m.Set(&mu, k, 1)
+	}
+	if m.Len(&mu) != 3 {
+		t.Errorf("Len() = %d, want WithPolicy alone to leave the map unbounded", m.Len(&mu))
+	}
+
+	m = New[string, int](WithMaxCost(10, func(_ string, v int) int64 { return int64(v) }))
+	m.Set(&mu, "a", 6)
+	m.Set(&mu, "b", 6)
+	if m.Len(&mu) != 1 {
+		t.Errorf("Len() = %d, want WithMaxCost alone to bound the map", m.Len(&mu))
+	}
+}
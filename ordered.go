@@ -0,0 +1,156 @@
+package valuemap
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+)
+
+// OrderedValueMap is a map that keeps its entries sorted by key,
+// supporting range queries that a hash map cannot answer.
+// It is backed by a sorted slice, so writes are O(n) and lookups O(log n).
+type OrderedValueMap[K cmp.Ordered, V any] struct {
+	entries []Entry[K, V]
+}
+
+// NewOrdered returns a new pointer to an OrderedValueMap.
+func NewOrdered[K cmp.Ordered, V any]() *OrderedValueMap[K, V] {
+	return &OrderedValueMap[K, V]{}
+}
+
+// search returns the position of key and whether it is present.
+func (m *OrderedValueMap[K, V]) search(key K) (int, bool) {
+	return slices.BinarySearchFunc(m.entries, key, func(e Entry[K, V], k K) int {
+		return cmp.Compare(e.Key, k)
+	})
+}
+
+// Set assigns a value to a key.
+//
+// mu is an external mutex to lock the internal map during value assigning
+func (m *OrderedValueMap[K, V]) Set(mu *sync.RWMutex, key K, value V) {
+	mu.Lock()
+	defer mu.Unlock()
+	i, ok := m.search(key)
+	if ok {
+		m.entries[i].Value = value
+		return
+	}
+	m.entries = slices.Insert(m.entries, i, Entry[K, V]{Key: key, Value: value})
+}
+
+// Get retrieves a value and a boolean indicating if the key exists.
+//
+// mu is an external mutex to lock the internal map during value retrieval
+func (m *OrderedValueMap[K, V]) Get(mu *sync.RWMutex, key K) (V, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if i, ok := m.search(key); ok {
+		return m.entries[i].Value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Delete removes a key from the map.
+//
+// mu is an external mutex to lock the internal map during key deletion
+func (m *OrderedValueMap[K, V]) Delete(mu *sync.RWMutex, key K) {
+	mu.Lock()
+	defer mu.Unlock()
+	if i, ok := m.search(key); ok {
+		m.entries = slices.Delete(m.entries, i, i+1)
+	}
+}
+
+// Len returns the number of key-value pairs.
+//
+// mu is an external mutex to lock the internal map during map content counting
+func (m *OrderedValueMap[K, V]) Len(mu *sync.RWMutex) int {
+	mu.RLock()
+	defer mu.RUnlock()
+	return len(m.entries)
+}
+
+// Keys returns a slice of all keys in ascending order.
+//
+// mu is an external mutex to lock the internal map during key retrieval
+func (m *OrderedValueMap[K, V]) Keys(mu *sync.RWMutex) []K {
+	mu.RLock()
+	defer mu.RUnlock()
+	keys := make([]K, len(m.entries))
+	for i, e := range m.entries {
+		keys[i] = e.Key
+	}
+	return keys
+}
+
+// Min returns the entry with the smallest key.
+//
+// mu is an external mutex to lock the internal map during entry retrieval
+func (m *OrderedValueMap[K, V]) Min(mu *sync.RWMutex) (K, V, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	return m.at(0)
+}
+
+// Max returns the entry with the largest key.
+//
+// mu is an external mutex to lock the internal map during entry retrieval
+func (m *OrderedValueMap[K, V]) Max(mu *sync.RWMutex) (K, V, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	return m.at(len(m.entries) - 1)
+}
+
+// Floor returns the entry with the largest key less than or equal to key.
+//
+// mu is an external mutex to lock the internal map during entry retrieval
+func (m *OrderedValueMap[K, V]) Floor(mu *sync.RWMutex, key K) (K, V, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	i, ok := m.search(key)
+	if !ok {
+		i--
+	}
+	return m.at(i)
+}
+
+// Ceiling returns the entry with the smallest key greater than or equal to key.
+//
+// mu is an external mutex to lock the internal map during entry retrieval
+func (m *OrderedValueMap[K, V]) Ceiling(mu *sync.RWMutex, key K) (K, V, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	i, _ := m.search(key)
+	return m.at(i)
+}
+
+// RangeBetween calls fn in ascending key order for each entry whose key
+// lies between from and to, inclusive. If fn returns false, RangeBetween
+// stops the iteration.
+//
+// mu is an external mutex to lock the internal map during iteration,
+// so fn must not call methods that lock mu
+func (m *OrderedValueMap[K, V]) RangeBetween(mu *sync.RWMutex, from, to K, fn func(key K, value V) bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	i, _ := m.search(from)
+	for ; i < len(m.entries) && m.entries[i].Key <= to; i++ {
+		if !fn(m.entries[i].Key, m.entries[i].Value) {
+			return
+		}
+	}
+}
+
+func (m *OrderedValueMap[K, V]) at(i int) (K, V, bool) {
+	if i < 0 || i >= len(m.entries) {
+		var (
+			zk K
+			zv V
+		)
+		return zk, zv, false
+	}
+	e := m.entries[i]
+	return e.Key, e.Value, true
+}
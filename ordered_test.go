@@ -0,0 +1,47 @@
+package valuemap
+
+import (
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestOrderedValueMap(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := NewOrdered[int, string]()
+	for _, k := range []int{50, 10, 40, 20, 30} {
+		m.Set(&mu, k, "v")
+	}
+	m.Delete(&mu, 40)
+
+	if keys := m.Keys(&mu); !slices.Equal(keys, []int{10, 20, 30, 50}) {
+		t.Errorf("Keys = %v", keys)
+	}
+	if k, _, _ := m.Min(&mu); k != 10 {
+		t.Errorf("Min = %d, want 10", k)
+	}
+	if k, _, _ := m.Max(&mu); k != 50 {
+		t.Errorf("Max = %d, want 50", k)
+	}
+	if k, _, ok := m.Floor(&mu, 45); !ok || k != 30 {
+		t.Errorf("Floor(45) = %d, %v; want 30, true", k, ok)
+	}
+	if _, _, ok := m.Floor(&mu, 5); ok {
+		t.Error("Floor(5) found an entry")
+	}
+	if k, _, ok := m.Ceiling(&mu, 20); !ok || k != 20 {
+		t.Errorf("Ceiling(20) = %d, %v; want 20, true", k, ok)
+	}
+	if _, _, ok := m.Ceiling(&mu, 51); ok {
+		t.Error("Ceiling(51) found an entry")
+	}
+
+	var got []int
+	m.RangeBetween(&mu, 15, 50, func(k int, _ string) bool {
+		got = append(got, k)
+		return true
+	})
+	if !slices.Equal(got, []int{20, 30, 50}) {
+		t.Errorf("RangeBetween = %v", got)
+	}
+}
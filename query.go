@@ -0,0 +1,136 @@
+package valuemap
+
+import (
+	"slices"
+	"sync"
+)
+
+// Query is a query over the entries of a ValueMap, built by chaining its
+// methods from ValueMap.Query and run by Run:
+//
+//	servers := m.Query(&mu).
+//		Where("byRegion", "eu-west").
+//		Filter(func(id int, s Server) bool { return s.Up }).
+//		Sort(func(a, b valuemap.Entry[int, Server]) bool { return a.Value.Load < b.Value.Load }).
+//		Limit(10).
+//		Run()
+//
+// A Query is not safe for concurrent use, but it can be run several times.
+type Query[K comparable, V any] struct {
+	m      *ValueMap[K, V]
+	mu     *sync.RWMutex
+	wheres []where
+	preds  []func(key K, value V) bool
+	less   func(a, b Entry[K, V]) bool
+	limit  int
+}
+
+// where is a condition of a query on a secondary index.
+type where struct {
+	index string
+	term  string
+}
+
+// Query returns a new query over the entries of the map, which matches
+// all of them until conditions are added.
+//
+// mu is an external mutex to lock the internal map while the query runs.
+// It is not held while the filters and the ordering functions run
+func (m *ValueMap[K, V]) Query(mu *sync.RWMutex) *Query[K, V] {
+	return &Query[K, V]{m: m, mu: mu}
+}
+
+// Where restricts the query to the entries for which the secondary index
+// called index, added with AddIndex, computed term. Run panics if the map
+// has no such index.
+func (q *Query[K, V]) Where(index, term string) *Query[K, V] {
+	q.wheres = append(q.wheres, where{index, term})
+	return q
+}
+
+// Filter restricts the query to the entries for which pred returns true.
+// Unlike Where, it is checked against every candidate entry.
+func (q *Query[K, V]) Filter(pred func(key K, value V) bool) *Query[K, V] {
+	q.preds = append(q.preds, pred)
+	return q
+}
+
+// Sort orders the results of the query using less. Without Sort, they are
+// in no particular order.
+func (q *Query[K, V]) Sort(less func(a, b Entry[K, V]) bool) *Query[K, V] {
+	q.less = less
+	return q
+}
+
+// Limit keeps at most the first n results of the query. A limit of zero or
+// less keeps them all.
+func (q *Query[K, V]) Limit(n int) *Query[K, V] {
+	q.limit = n
+	return q
+}
+
+// Run runs the query and returns its results. The candidate entries are
+// found under the lock of the map: with the smallest of the indexes named
+// by Where, checked against the other ones, or by a snapshot of the whole
+// map if the query has no Where condition. The filters, ordering and limit
+// are then applied to them without the lock.
+func (q *Query[K, V]) Run() []Entry[K, V] {
+	entries := q.candidates()
+	if len(q.preds) > 0 {
+		entries = slices.DeleteFunc(entries, func(e Entry[K, V]) bool {
+			for _, pred := range q.preds {
+				if !pred(e.Key, e.Value) {
+					return true
+				}
+			}
+			return false
+		})
+	}
+	if q.less != nil {
+		slices.SortStableFunc(entries, func(a, b Entry[K, V]) int {
+			switch {
+			case q.less(a, b):
+				return -1
+			case q.less(b, a):
+				return 1
+			}
+			return 0
+		})
+	}
+	if q.limit > 0 && len(entries) > q.limit {
+		entries = slices.Clip(entries[:q.limit])
+	}
+	return entries
+}
+
+// candidates returns the entries that match the Where conditions of the
+// query, or all the entries if it has none.
+func (q *Query[K, V]) candidates() []Entry[K, V] {
+	m := q.m
+	defer m.readLock(q.mu).Unlock()
+	if len(q.wheres) == 0 {
+		entries := make([]Entry[K, V], 0, m.size())
+		for k, v := range m.live() {
+			entries = append(entries, Entry[K, V]{Key: k, Value: v})
+		}
+		return entries
+	}
+
+	indexes := make([]*fieldIndex[K, V], len(q.wheres))
+	best := 0
+	for i, w := range q.wheres {
+		indexes[i] = m.index(w.index)
+		if len(indexes[i].byValue[w.term]) < len(indexes[best].byValue[q.wheres[best].term]) {
+			best = i
+		}
+	}
+	entries := m.byIndex(indexes[best], q.wheres[best].term)
+	return slices.DeleteFunc(entries, func(e Entry[K, V]) bool {
+		for i, w := range q.wheres {
+			if i != best && indexes[i].fn(e.Key, e.Value) != w.term {
+				return true
+			}
+		}
+		return false
+	})
+}
@@ -0,0 +1,56 @@
+package valuemap
+
+import (
+	"slices"
+	"sync"
+	"testing"
+)
+
+type host struct {
+	Region string
+	Tier   string
+	Load   int
+}
+
+func TestQuery(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := FromMap(map[string]host{
+		"a": {"eu", "web", 5},
+		"b": {"eu", "db", 1},
+		"c": {"eu", "web", 3},
+		"d": {"us", "web", 2},
+		"e": {"eu", "web", 4},
+	})
+	m.AddIndex(&mu, "region", func(k string, h host) string { return h.Region })
+	m.AddIndex(&mu, "tier", func(k string, h host) string { return h.Tier })
+	keys := func(entries []Entry[string, host]) []string {
+		var ks []string
+		for _, e := range entries {
+			ks = append(ks, e.Key)
+		}
+		return ks
+	}
+	byLoad := func(a, b Entry[string, host]) bool { return a.Value.Load < b.Value.Load }
+
+	got := keys(m.Query(&mu).Where("region", "eu").Where("tier", "web").Sort(byLoad).Run())
+	if !slices.Equal(got, []string{"c", "e", "a"}) {
+		t.Errorf("eu web hosts by load = %v, want [c e a]", got)
+	}
+	got = keys(m.Query(&mu).Where("tier", "web").Filter(func(k string, h host) bool { return h.Load > 2 }).Sort(byLoad).Limit(2).Run())
+	if !slices.Equal(got, []string{"c", "e"}) {
+		t.Errorf("two least loaded web hosts above 2 = %v, want [c e]", got)
+	}
+
+	// Queries without Where scan the map.
+	q := m.Query(&mu).Sort(byLoad).Limit(1)
+	if got := keys(q.Run()); !slices.Equal(got, []string{"b"}) {
+		t.Errorf("least loaded host = %v, want [b]", got)
+	}
+	m.Set(&mu, "f", host{"us", "db", 0})
+	if got := keys(q.Run()); !slices.Equal(got, []string{"f"}) {
+		t.Errorf("least loaded host = %v after Set, want [f]", got)
+	}
+	if got := m.Query(&mu).Where("region", "ap").Run(); len(got) != 0 {
+		t.Errorf("ap hosts = %v, want none", keys(got))
+	}
+}
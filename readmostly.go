@@ -0,0 +1,136 @@
+package valuemap
+
+import (
+	"maps"
+	"sync"
+	"sync/atomic"
+)
+
+// ReadMostlyValueMap is a map for workloads dominated by reads. Reads load
+// an immutable map through an atomic pointer and take no lock at all.
+// Writes copy the whole map and publish the copy, so each write is O(n);
+// use SetMany and DeleteMany to batch them.
+//
+// Reads take no mutex. Writes take an external mutex that serializes
+// writers; readers never wait for it.
+type ReadMostlyValueMap[K comparable, V any] struct {
+	data atomic.Pointer[map[K]V]
+}
+
+// NewReadMostly returns a new pointer to a ReadMostlyValueMap.
+func NewReadMostly[K comparable, V any]() *ReadMostlyValueMap[K, V] {
+	m := &ReadMostlyValueMap[K, V]{}
+	m.data.Store(&map[K]V{})
+	return m
+}
+
+// load returns the current immutable map.
+func (m *ReadMostlyValueMap[K, V]) load() map[K]V {
+	return *m.data.Load()
+}
+
+// Get retrieves a value and a boolean indicating if the key exists.
+func (m *ReadMostlyValueMap[K, V]) Get(key K) (V, bool) {
+	v, ok := m.load()[key]
+	return v, ok
+}
+
+// Len returns the number of key-value pairs.
+func (m *ReadMostlyValueMap[K, V]) Len() int {
+	return len(m.load())
+}
+
+// Keys returns a slice of all keys.
+func (m *ReadMostlyValueMap[K, V]) Keys() []K {
+	data := m.load()
+	keys := make([]K, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Values returns a slice of all values.
+func (m *ReadMostlyValueMap[K, V]) Values() []V {
+	data := m.load()
+	values := make([]V, 0, len(data))
+	for _, v := range data {
+		values = append(values, v)
+	}
+	return values
+}
+
+// Range calls fn sequentially for each key and value of the current
+// contents of the map. If fn returns false, Range stops the iteration.
+// Writes made while Range runs are not visible to it.
+func (m *ReadMostlyValueMap[K, V]) Range(fn func(key K, value V) bool) {
+	for k, v := range m.load() {
+		if !fn(k, v) {
+			return
+		}
+	}
+}
+
+// Raw returns a read-only copy of the internal map.
+func (m *ReadMostlyValueMap[K, V]) Raw() map[K]V {
+	return maps.Clone(m.load())
+}
+
+// write publishes a modified copy of the map.
+func (m *ReadMostlyValueMap[K, V]) write(mu *sync.RWMutex, fn func(data map[K]V)) {
+	mu.Lock()
+	defer mu.Unlock()
+	cp := maps.Clone(m.load())
+	if cp == nil {
+		cp = make(map[K]V)
+	}
+	fn(cp)
+	m.data.Store(&cp)
+}
+
+// Set assigns a value to a key.
+//
+// mu is an external mutex to serialize writers during value assigning
+func (m *ReadMostlyValueMap[K, V]) Set(mu *sync.RWMutex, key K, value V) {
+	m.write(mu, func(data map[K]V) {
+		data[key] = value
+	})
+}
+
+// SetMany assigns all the key-value pairs of entries with a single copy.
+//
+// mu is an external mutex to serialize writers during value assigning
+func (m *ReadMostlyValueMap[K, V]) SetMany(mu *sync.RWMutex, entries map[K]V) {
+	m.write(mu, func(data map[K]V) {
+		maps.Copy(data, entries)
+	})
+}
+
+// Delete removes a key from the map.
+//
+// mu is an external mutex to serialize writers during key deletion
+func (m *ReadMostlyValueMap[K, V]) Delete(mu *sync.RWMutex, key K) {
+	m.write(mu, func(data map[K]V) {
+		delete(data, key)
+	})
+}
+
+// DeleteMany removes the given keys with a single copy.
+//
+// mu is an external mutex to serialize writers during key deletion
+func (m *ReadMostlyValueMap[K, V]) DeleteMany(mu *sync.RWMutex, keys []K) {
+	m.write(mu, func(data map[K]V) {
+		for _, k := range keys {
+			delete(data, k)
+		}
+	})
+}
+
+// Clear removes all entries from the map.
+//
+// mu is an external mutex to serialize writers during map clearing
+func (m *ReadMostlyValueMap[K, V]) Clear(mu *sync.RWMutex) {
+	mu.Lock()
+	defer mu.Unlock()
+	m.data.Store(&map[K]V{})
+}
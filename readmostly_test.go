@@ -0,0 +1,45 @@
+package valuemap
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestReadMostlyValueMap(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := NewReadMostly[string, int]()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := range 100 {
+			m.Set(&mu, "a", i)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for range 100 {
+			m.Get("a")
+		}
+	}()
+	wg.Wait()
+
+	if v, ok := m.Get("a"); !ok || v != 99 {
+		t.Errorf("Get(a) = %d, %v; want 99, true", v, ok)
+	}
+
+	raw := m.Raw()
+	m.SetMany(&mu, map[string]int{"b": 1, "c": 2})
+	if len(raw) != 1 {
+		t.Error("write modified a previously returned copy")
+	}
+	m.DeleteMany(&mu, []string{"a", "b"})
+	if n := m.Len(); n != 1 {
+		t.Errorf("Len = %d, want 1", n)
+	}
+	m.Clear(&mu)
+	if n := len(m.Keys()); n != 0 {
+		t.Errorf("len(Keys) = %d after Clear, want 0", n)
+	}
+}
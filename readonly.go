@@ -0,0 +1,91 @@
+package valuemap
+
+import (
+	"encoding/json"
+	"iter"
+	"maps"
+	"sync"
+)
+
+// ReadOnlyValueMap is an immutable copy of a ValueMap. It has no methods
+// that write, so handing it out cannot give the receiver mutation ability.
+// Since it never changes, its methods take no mutex.
+type ReadOnlyValueMap[K comparable, V any] struct {
+	data map[K]V
+}
+
+// Freeze returns a read-only copy of the map. Like Clone, it shares storage
+// with the map until the map is next written to, so taking it costs O(1)
+// and the copy is only made if the map changes while the frozen map is in
+// use. The result is a consistent point-in-time snapshot that can be
+// iterated, compared and serialized while the map keeps changing.
+//
+// mu is an external mutex to lock the internal map during freezing
+func (m *ValueMap[K, V]) Freeze(mu *sync.RWMutex) *ReadOnlyValueMap[K, V] {
+	return &ReadOnlyValueMap[K, V]{data: m.Clone(mu).data}
+}
+
+// Get retrieves a value and a boolean indicating if the key exists.
+func (r *ReadOnlyValueMap[K, V]) Get(key K) (V, bool) {
+	v, ok := r.data[key]
+	return v, ok
+}
+
+// Len returns the number of key-value pairs.
+func (r *ReadOnlyValueMap[K, V]) Len() int {
+	return len(r.data)
+}
+
+// Keys returns a slice of all keys.
+func (r *ReadOnlyValueMap[K, V]) Keys() []K {
+	keys := make([]K, 0, len(r.data))
+	for k := range r.data {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Values returns a slice of all values.
+func (r *ReadOnlyValueMap[K, V]) Values() []V {
+	values := make([]V, 0, len(r.data))
+	for _, v := range r.data {
+		values = append(values, v)
+	}
+	return values
+}
+
+// Range calls fn sequentially for each key and value present in the map.
+// If fn returns false, Range stops the iteration.
+func (r *ReadOnlyValueMap[K, V]) Range(fn func(key K, value V) bool) {
+	for k, v := range r.data {
+		if !fn(k, v) {
+			return
+		}
+	}
+}
+
+// All returns an iterator over the key-value pairs of the map.
+func (r *ReadOnlyValueMap[K, V]) All() iter.Seq2[K, V] {
+	return maps.All(r.data)
+}
+
+// Entries returns a slice of all key-value pairs.
+func (r *ReadOnlyValueMap[K, V]) Entries() []Entry[K, V] {
+	entries := make([]Entry[K, V], 0, len(r.data))
+	for k, v := range r.data {
+		entries = append(entries, Entry[K, V]{Key: k, Value: v})
+	}
+	return entries
+}
+
+// Raw returns a copy of the map as a plain map.
+func (r *ReadOnlyValueMap[K, V]) Raw() map[K]V {
+	return maps.Clone(r.data)
+}
+
+// MarshalJSON encodes the map as a JSON object. Keys are encoded as
+// encoding/json encodes map keys. No locking is needed since the map
+// never changes.
+func (r *ReadOnlyValueMap[K, V]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.data)
+}
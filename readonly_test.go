@@ -0,0 +1,43 @@
+package valuemap
+
+import (
+	"encoding/json"
+	"maps"
+	"sync"
+	"testing"
+)
+
+func TestFreeze(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := FromMap(map[string]int{"a": 1})
+
+	r := m.Freeze(&mu)
+	m.Set(&mu, "a", 2)
+	m.Set(&mu, "b", 3)
+
+	if v, _ := r.Get("a"); v != 1 {
+		t.Errorf("frozen Get(a) = %d, want 1", v)
+	}
+	if n := r.Len(); n != 1 {
+		t.Errorf("frozen Len = %d, want 1", n)
+	}
+}
+
+func TestFreezeSnapshot(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := FromMap(map[string]int{"a": 1, "b": 2})
+
+	snap := m.Freeze(&mu)
+	m.Delete(&mu, "a")
+
+	if got := maps.Collect(snap.All()); len(got) != 2 {
+		t.Errorf("snapshot All = %v, want 2 entries", got)
+	}
+	b, err := json.Marshal(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"a":1,"b":2}` {
+		t.Errorf("snapshot MarshalJSON = %s", b)
+	}
+}
@@ -0,0 +1,118 @@
+package valuemap
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// OpenJournaled opens a journaled map stored at path, which is created if
+// it does not exist. The snapshot is kept in the file at path and the
+// journal in path+".wal", both encoded with c. The returned map journals
+// its changes and checkpoints to path; pass WithCheckpoint with the same
+// path to checkpoint periodically. Close the map to sync and close the
+// journal.
+//
+// Other options are applied as by New, and the recovered entries are then
+// treated as if they had just been set: since the journal does not record
+// lifetimes, they all get the default one of WithTTL from the time the map
+// is opened, and a bounded map evicts those beyond its limits, journaling
+// their deletion.
+//
+// Recovery loads the snapshot, then replays the journal record by record.
+// A record is applied only if it is complete and its checksum matches.
+// Replay stops at the first record that is not, and the journal is
+// truncated there, so that new records follow the last good one:
+//
+//   - a record cut short by a crash during its write is discarded, which
+//     loses at most the change that was being journaled;
+//   - a damaged record in the middle of the journal discards it and every
+//     later record, since they may depend on it.
+//
+// Changes journaled after the last checkpoint are therefore recovered as
+// long as the journal reached the disk, which Sync guarantees. Replaying
+// a journal that was not emptied after its checkpoint is harmless, as
+// described at Checkpoint. A snapshot that cannot be read or decoded is
+// an error.
+//
+// mu is an external mutex to lock the internal map during checkpoints.
+// It must be the same mutex passed to the other methods
+func OpenJournaled[K comparable, V any](mu *sync.RWMutex, path string, c Codec[K, V], opts ...Option) (*ValueMap[K, V], error) {
+	data, err := loadSnapshot(path, c)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path+".wal", os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	b, err := io.ReadAll(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if good := replay(b, data, c); good < int64(len(b)) {
+		if err := f.Truncate(good); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+
+	opts = append([]Option{WithJournal(mu, f, c), WithCheckpoint(path, 0)}, opts...)
+	cfg := newConfig(opts)
+	m := &ValueMap[K, V]{data: data}
+	m.grow(cfg.capacity)
+	m.setup(cfg)
+	m.journal.file = f
+	return m, nil
+}
+
+// loadSnapshot decodes the file at path, or returns an empty map
+// if it does not exist.
+func loadSnapshot[K comparable, V any](path string, c Codec[K, V]) (map[K]V, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return make(map[K]V), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := c.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		data = make(map[K]V)
+	}
+	return data, nil
+}
+
+// replay applies the journal records in b to data and returns the length
+// of the valid prefix of b.
+func replay[K comparable, V any](b []byte, data map[K]V, c Codec[K, V]) int64 {
+	var good int64
+	for {
+		payload, size, ok := nextRecord(b)
+		if !ok {
+			return good
+		}
+		op, key, value, ok := decodeRecord(payload, c)
+		if !ok {
+			return good
+		}
+		switch op {
+		case opSet:
+			data[key] = value
+		case opDelete:
+			delete(data, key)
+		case opClear:
+			clear(data)
+		}
+		b = b[size:]
+		good += int64(size)
+	}
+}
@@ -0,0 +1,192 @@
+package valuemap
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func openTest(t *testing.T, mu *sync.RWMutex, path string) *ValueMap[string, int] {
+	t.Helper()
+	m, err := OpenJournaled(mu, path, JSONCodec[string, int]{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestOpenJournaled(t *testing.T) {
+	mu := sync.RWMutex{}
+	path := filepath.Join(t.TempDir(), "data.json")
+
+	m := openTest(t, &mu, path)
+	m.Set(&mu, "a", 1)
+	m.Set(&mu, "b", 2)
+	if err := m.Checkpoint(&mu); err != nil {
+		t.Fatal(err)
+	}
+	m.Set(&mu, "c", 3)
+	m.Delete(&mu, "a")
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	m = openTest(t, &mu, path)
+	defer m.Close()
+	if got := m.Raw(&mu); len(got) != 2 || got["b"] != 2 || got["c"] != 3 {
+		t.Errorf("recovered %v, want map[b:2 c:3]", got)
+	}
+	m.Set(&mu, "d", 4)
+	if err := m.Sync(&mu); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenJournaledTornWrite(t *testing.T) {
+	mu := sync.RWMutex{}
+	path := filepath.Join(t.TempDir(), "data.json")
+
+	m := openTest(t, &mu, path)
+	m.Set(&mu, "a", 1)
+	m.Set(&mu, "b", 2)
+	m.Close()
+
+	wal := path + ".wal"
+	b, err := os.ReadFile(wal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(wal, b[:len(b)-3], 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m = openTest(t, &mu, path)
+	if got := m.Raw(&mu); len(got) != 1 || got["a"] != 1 {
+		t.Errorf("recovered %v, want map[a:1]", got)
+	}
+	m.Set(&mu, "c", 3)
+	m.Close()
+
+	m = openTest(t, &mu, path)
+	defer m.Close()
+	if got := m.Raw(&mu); len(got) != 2 || got["c"] != 3 {
+		t.Errorf("recovered %v after truncation, want map[a:1 c:3]", got)
+	}
+}
+
+func TestOpenJournaledCorruptRecord(t *testing.T) {
+	mu := sync.RWMutex{}
+	path := filepath.Join(t.TempDir(), "data.json")
+
+	m := openTest(t, &mu, path)
+	m.Set(&mu, "a", 1)
+	m.Set(&mu, "b", 2)
+	m.Set(&mu, "c", 3)
+	m.Close()
+
+	wal := path + ".wal"
+	b, err := os.ReadFile(wal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := recordHeaderSize + int(b[0])
+	b[first+recordHeaderSize+2] ^= 0xff // damage the second record
+	if err := os.WriteFile(wal, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m = openTest(t, &mu, path)
+	defer m.Close()
+	if got := m.Raw(&mu); len(got) != 1 || got["a"] != 1 {
+		t.Errorf("recovered %v, want map[a:1]", got)
+	}
+	fi, err := os.Stat(wal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != int64(first) {
+		t.Errorf("journal size = %d, want %d", fi.Size(), first)
+	}
+}
+
+func TestOpenJournaledBadSnapshot(t *testing.T) {
+	mu := sync.RWMutex{}
+	path := filepath.Join(t.TempDir(), "data.json")
+	if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenJournaled(&mu, path, JSONCodec[string, int]{}); err == nil {
+		t.Error("OpenJournaled() error = nil, want decode error")
+	}
+}
+
+func TestOpenJournaledOptions(t *testing.T) {
+	mu := sync.RWMutex{}
+	path := filepath.Join(t.TempDir(), "data.json")
+	m := openTest(t, &mu, path)
+	m.Set(&mu, "a", 1)
+	m.Set(&mu, "b", 2)
+	m.Close()
+
+	clock := NewManualClock(time.Unix(0, 0))
+	m, err := OpenJournaled(&mu, path, JSONCodec[string, int]{},
+		WithTTL(&mu, time.Second, 0),
+		WithClock(clock),
+		WithLoader(func(ctx context.Context, key string) (int, error) {
+			return 0, errors.New("unavailable")
+		}),
+		WithStaleWhileRevalidate(time.Second),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := m.Len(&mu); n != 2 {
+		t.Errorf("Len() = %d, want 2 recovered entries", n)
+	}
+	clock.Advance(3 * time.Second)
+	if n := m.DeleteExpired(&mu); n != 2 {
+		t.Errorf("DeleteExpired removed %d recovered entries, want 2", n)
+	}
+	m.Close()
+
+	m = openTest(t, &mu, path)
+	m.Set(&mu, "a", 1)
+	m.Set(&mu, "b", 2)
+	m.Set(&mu, "c", 3)
+	m.Close()
+
+	m, err = OpenJournaled(&mu, path, JSONCodec[string, int]{}, WithMaxEntries(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := m.Len(&mu); n != 2 {
+		t.Errorf("Len() = %d, want 2 after evicting beyond the bound", n)
+	}
+	m.Close()
+	m = openTest(t, &mu, path)
+	defer m.Close()
+	if n := m.Len(&mu); n != 2 {
+		t.Errorf("Len() = %d after reopening, want the eviction journaled", n)
+	}
+}
+
+func TestOpenJournaledReverseIndex(t *testing.T) {
+	mu := sync.RWMutex{}
+	path := filepath.Join(t.TempDir(), "data.json")
+	m := openTest(t, &mu, path)
+	m.Set(&mu, "a", 1)
+	m.Close()
+
+	m, err := OpenJournaled(&mu, path, JSONCodec[string, int]{}, WithReverseIndex[string, int]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if got := FindByValue(m, &mu, 1); len(got) != 1 || got[0] != "a" {
+		t.Errorf("FindByValue(1) = %v, want [a] from the recovered entries", got)
+	}
+}
@@ -0,0 +1,52 @@
+package valuemap
+
+// RemovalReason tells why an entry left a map.
+type RemovalReason int
+
+const (
+	// ReasonDeleted means the entry was removed by a delete operation,
+	// such as Delete, Pop or DeleteFunc.
+	ReasonDeleted RemovalReason = iota + 1
+	// ReasonExpired means the lifetime of the entry ran out.
+	ReasonExpired
+	// ReasonEvicted means the entry was evicted to keep a bounded map
+	// within its limits.
+	ReasonEvicted
+	// ReasonCleared means the entry was removed by Clear.
+	ReasonCleared
+)
+
+// String returns the name of the reason.
+func (r RemovalReason) String() string {
+	switch r {
+	case ReasonDeleted:
+		return "deleted"
+	case ReasonExpired:
+		return "expired"
+	case ReasonEvicted:
+		return "evicted"
+	case ReasonCleared:
+		return "cleared"
+	}
+	return "unknown"
+}
+
+// WithOnEvict registers fn to be called whenever an entry leaves the map,
+// along with the reason. Expired entries are reported when they are found,
+// which is when the janitor or DeleteExpired runs, or when their key is
+// written to. fn is called with the external mutex locked for writing,
+// so it must not call methods that lock it. The types of fn must match
+// the map, otherwise the constructor panics.
+func WithOnEvict[K comparable, V any](fn func(key K, value V, reason RemovalReason)) Option {
+	return optionFunc(func(c *config) {
+		c.onEvict = fn
+	})
+}
+
+// removed reports the removal of an entry. The caller must hold the write lock.
+func (m *ValueMap[K, V]) removed(key K, value V, reason RemovalReason) {
+	m.ops.removal(reason, 1)
+	if m.onEvict != nil {
+		m.onEvict(key, value, reason)
+	}
+}
@@ -0,0 +1,41 @@
+package valuemap
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOnEvict(t *testing.T) {
+	mu := sync.RWMutex{}
+	got := make(map[string]RemovalReason)
+	onEvict := WithOnEvict(func(k string, _ int, r RemovalReason) {
+		got[k] = r
+	})
+
+	m := NewBounded[string, int](2, onEvict)
+	m.Set(&mu, "a", 1)
+	m.Set(&mu, "b", 2)
+	m.Set(&mu, "c", 3)
+	m.Delete(&mu, "b")
+	m.Clear(&mu)
+
+	want := map[string]RemovalReason{"a": ReasonEvicted, "b": ReasonDeleted, "c": ReasonCleared}
+	for k, r := range want {
+		if got[k] != r {
+			t.Errorf("reason for %s = %v, want %v", k, got[k], r)
+		}
+	}
+
+	clear(got)
+	e := NewWithTTL[string, int](&mu, time.Millisecond, 0, onEvict)
+	defer e.Close()
+	e.Set(&mu, "x", 1)
+	e.Set(&mu, "y", 2)
+	time.Sleep(5 * time.Millisecond)
+	e.Set(&mu, "y", 3)
+	e.DeleteExpired(&mu)
+	if got["x"] != ReasonExpired || got["y"] != ReasonExpired {
+		t.Errorf("reasons = %v, want both expired", got)
+	}
+}
@@ -0,0 +1,133 @@
+package valuemap
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// ErrDuplicateValue is returned, wrapped with the value, by Invert for a
+// value held by more than one key.
+var ErrDuplicateValue = errors.New("valuemap: duplicate value")
+
+// Invert returns a new ValueMap that maps the values of m to their keys,
+// for bidirectional lookups such as from IDs to names and back. It returns
+// ErrDuplicateValue if two keys hold the same value, since the inverted
+// map could keep only one of them; use InvertAll to keep them all.
+//
+// mu is an external mutex to lock the internal map during the inversion
+func Invert[K, V comparable](m *ValueMap[K, V], mu *sync.RWMutex) (*ValueMap[V, K], error) {
+	defer m.readLock(mu).Unlock()
+	res := make(map[V]K, m.size())
+	for k, v := range m.live() {
+		if _, ok := res[v]; ok {
+			return nil, fmt.Errorf("%w %v", ErrDuplicateValue, v)
+		}
+		res[v] = k
+	}
+	return &ValueMap[V, K]{data: res}, nil
+}
+
+// InvertAll is like Invert, but maps every value to all the keys that hold
+// it, in no particular order, so it does not fail on duplicate values.
+//
+// mu is an external mutex to lock the internal map during the inversion
+func InvertAll[K, V comparable](m *ValueMap[K, V], mu *sync.RWMutex) *ValueMap[V, []K] {
+	defer m.readLock(mu).Unlock()
+	res := make(map[V][]K)
+	for k, v := range m.live() {
+		res[v] = append(res[v], k)
+	}
+	return &ValueMap[V, []K]{data: res}
+}
+
+// WithReverseIndex makes the map keep an index from its values to their
+// keys, updated with every change, so that FindByValue finds the keys of a
+// value in constant time instead of scanning the map. The index costs an
+// entry per key, and its types must match the map, otherwise the
+// constructor panics.
+func WithReverseIndex[K, V comparable]() Option {
+	return optionFunc(func(c *config) {
+		c.reverseIndex = func() any {
+			return &reverseIndex[K, V]{byValue: make(postings[V, K])}
+		}
+	})
+}
+
+// valueIndex is an index over the values of a map, kept up to date by put,
+// removeFor and reset. The caller must hold the write lock to call its
+// methods, except find which needs only the read lock.
+type valueIndex[K comparable, V any] interface {
+	add(key K, value V)
+	remove(key K, value V)
+	find(value V) []K
+	clear()
+	compact()
+}
+
+// reverseIndex is the index of a map created with WithReverseIndex.
+type reverseIndex[K, V comparable] struct {
+	byValue postings[V, K]
+}
+
+func (r *reverseIndex[K, V]) add(key K, value V)    { r.byValue.add(value, key) }
+func (r *reverseIndex[K, V]) remove(key K, value V) { r.byValue.remove(value, key) }
+func (r *reverseIndex[K, V]) find(value V) []K      { return r.byValue.keys(value) }
+func (r *reverseIndex[K, V]) clear()                { clear(r.byValue) }
+func (r *reverseIndex[K, V]) compact()              { r.byValue = shrink(r.byValue) }
+
+// postings maps the terms of an index to the set of keys they occur in.
+type postings[T, K comparable] map[T]map[K]struct{}
+
+func (p postings[T, K]) add(term T, key K) {
+	set, ok := p[term]
+	if !ok {
+		set = make(map[K]struct{})
+		p[term] = set
+	}
+	set[key] = struct{}{}
+}
+
+func (p postings[T, K]) remove(term T, key K) {
+	set := p[term]
+	delete(set, key)
+	if len(set) == 0 {
+		delete(p, term)
+	}
+}
+
+func (p postings[T, K]) keys(term T) []K {
+	set := p[term]
+	keys := make([]K, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// FindByValue returns the keys that hold value, in no particular order.
+// Maps created with WithReverseIndex look them up in their index; others
+// are scanned.
+//
+// mu is an external mutex to lock the internal map during the search
+func FindByValue[K, V comparable](m *ValueMap[K, V], mu *sync.RWMutex, value V) []K {
+	defer m.readLock(mu).Unlock()
+	if m.reverse == nil {
+		keys := make([]K, 0)
+		for k, v := range m.live() {
+			if v == value {
+				keys = append(keys, k)
+			}
+		}
+		return keys
+	}
+	keys := m.reverse.find(value)
+	if m.ttl != nil {
+		now := m.now()
+		keys = slices.DeleteFunc(keys, func(k K) bool {
+			return m.ttl.expired(k, now)
+		})
+	}
+	return keys
+}
@@ -0,0 +1,77 @@
+package valuemap
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestInvert(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := FromMap(map[int]string{1: "ann", 2: "bob"})
+	inv, err := Invert(m, &mu)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := inv.Raw(&mu); !maps.Equal(got, map[string]int{"ann": 1, "bob": 2}) {
+		t.Errorf("Invert = %v", got)
+	}
+
+	m.Set(&mu, 3, "ann")
+	if _, err := Invert(m, &mu); !errors.Is(err, ErrDuplicateValue) {
+		t.Errorf("Invert error = %v with a duplicate value, want ErrDuplicateValue", err)
+	}
+	all := InvertAll(m, &mu).Raw(&mu)
+	slices.Sort(all["ann"])
+	if !slices.Equal(all["ann"], []int{1, 3}) || !slices.Equal(all["bob"], []int{2}) {
+		t.Errorf("InvertAll = %v", all)
+	}
+}
+
+func TestFindByValue(t *testing.T) {
+	mu := sync.RWMutex{}
+	clock := NewManualClock(time.Unix(0, 0))
+	m := New[string, int](WithReverseIndex[string, int](), WithTTL(&mu, time.Minute, 0), WithClock(clock), WithMaxEntries(3))
+	plain := New[string, int]()
+	find := func(m *ValueMap[string, int], v int) []string {
+		keys := FindByValue(m, &mu, v)
+		slices.Sort(keys)
+		return keys
+	}
+
+	for _, m := range []*ValueMap[string, int]{m, plain} {
+		m.Set(&mu, "a", 1)
+		m.Set(&mu, "b", 1)
+		m.Set(&mu, "c", 2)
+		m.Set(&mu, "b", 2)
+		if got := find(m, 1); !slices.Equal(got, []string{"a"}) {
+			t.Errorf("FindByValue(1) = %v, want [a]", got)
+		}
+		if got := find(m, 2); !slices.Equal(got, []string{"b", "c"}) {
+			t.Errorf("FindByValue(2) = %v, want [b c]", got)
+		}
+	}
+
+	m.Delete(&mu, "c")
+	m.Set(&mu, "d", 3)
+	m.Set(&mu, "e", 3) // evicts a
+	if got := find(m, 1); len(got) != 0 {
+		t.Errorf("FindByValue(1) = %v after eviction, want none", got)
+	}
+	if got := find(m, 2); !slices.Equal(got, []string{"b"}) {
+		t.Errorf("FindByValue(2) = %v after Delete, want [b]", got)
+	}
+	clock.Advance(time.Minute)
+	if got := find(m, 3); len(got) != 0 {
+		t.Errorf("FindByValue(3) = %v after expiry, want none", got)
+	}
+	m.Set(&mu, "d", 4)
+	m.Clear(&mu)
+	m.Set(&mu, "f", 4)
+	if got := find(m, 4); !slices.Equal(got, []string{"f"}) {
+		t.Errorf("FindByValue(4) = %v after Clear, want [f]", got)
+	}
+}
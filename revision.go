@@ -0,0 +1,74 @@
+package valuemap
+
+import "sync"
+
+// revisions holds the revisions of a map created with WithRevisions.
+type revisions[K comparable] struct {
+	last  uint64 // revision of the latest assignment
+	byKey map[K]uint64
+}
+
+// WithRevisions makes the map number its assignments: every assignment of
+// a key gives the entry the next revision of the map, starting at 1, so a
+// revision identifies a value of a key even if the key is deleted and
+// assigned again. GetWithRevision returns the revision of an entry, and
+// SetIfRevision assigns a key only if its revision is the one the caller
+// read, for optimistic concurrency control across process boundaries,
+// such as HTTP clients sending the revision as an ETag.
+func WithRevisions() Option {
+	return optionFunc(func(c *config) {
+		c.revisions = true
+	})
+}
+
+// revise gives key the next revision.
+// The caller must hold the write lock.
+func (r *revisions[K]) revise(key K) {
+	r.last++
+	r.byKey[key] = r.last
+}
+
+// GetWithRevision retrieves a value, its revision and a boolean indicating
+// if the key exists. The revision of a missing key is 0. Maps created
+// without WithRevisions panic.
+//
+// mu is an external mutex to lock the internal map during value retrieval
+func (m *ValueMap[K, V]) GetWithRevision(mu *sync.RWMutex, key K) (value V, rev uint64, ok bool) {
+	m.mustRevise()
+	defer m.rlock(mu).Unlock()
+	value, ok = m.lookup(key)
+	m.read(key, ok)
+	if !ok {
+		return value, 0, false
+	}
+	m.access(key)
+	return value, m.revs.byKey[key], true
+}
+
+// SetIfRevision assigns a value to a key only if the revision of the key
+// is rev, that is, if it was not assigned since GetWithRevision returned
+// rev. A rev of 0 assigns the key only if it is not present. It returns
+// the new revision of the key and true if the value was assigned, or the
+// current revision and false otherwise. Maps created without
+// WithRevisions panic.
+//
+// mu is an external mutex to lock the internal map during the check and assignment
+func (m *ValueMap[K, V]) SetIfRevision(mu *sync.RWMutex, key K, value V, rev uint64) (uint64, bool) {
+	m.mustRevise()
+	defer m.lock(mu).Unlock()
+	var cur uint64
+	if _, ok := m.lookup(key); ok {
+		cur = m.revs.byKey[key]
+	}
+	if cur != rev {
+		return cur, false
+	}
+	m.store(key, value)
+	return m.revs.byKey[key], true
+}
+
+func (m *ValueMap[K, V]) mustRevise() {
+	if m.revs == nil {
+		panic("valuemap: revisions require WithRevisions")
+	}
+}
@@ -0,0 +1,60 @@
+package valuemap
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestRevisions(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := New[string, int](WithRevisions())
+
+	rev, ok := m.SetIfRevision(&mu, "a", 1, 0)
+	if !ok || rev != 1 {
+		t.Fatalf("SetIfRevision(a, 0) = %d, %v, want 1, true", rev, ok)
+	}
+	if rev, ok := m.SetIfRevision(&mu, "a", 2, 0); ok || rev != 1 {
+		t.Errorf("SetIfRevision(a, 0) = %d, %v on a present key, want 1, false", rev, ok)
+	}
+	m.Set(&mu, "b", 1)
+	if v, rev, ok := m.GetWithRevision(&mu, "a"); v != 1 || rev != 1 || !ok {
+		t.Errorf("GetWithRevision(a) = %d, %d, %v, want 1, 1, true", v, rev, ok)
+	}
+
+	// A concurrent writer makes the read revision stale.
+	_, read, _ := m.GetWithRevision(&mu, "a")
+	m.Set(&mu, "a", 5)
+	if rev, ok := m.SetIfRevision(&mu, "a", 6, read); ok || rev != 3 {
+		t.Errorf("SetIfRevision with a stale revision = %d, %v, want 3, false", rev, ok)
+	}
+	if rev, ok := m.SetIfRevision(&mu, "a", 6, 3); !ok || rev != 4 {
+		t.Errorf("SetIfRevision(a, 3) = %d, %v, want 4, true", rev, ok)
+	}
+
+	// Deleting and assigning again gives a new revision.
+	m.Delete(&mu, "a")
+	if _, rev, ok := m.GetWithRevision(&mu, "a"); rev != 0 || ok {
+		t.Errorf("GetWithRevision of a deleted key = %d, %v, want 0, false", rev, ok)
+	}
+	if rev, ok := m.SetIfRevision(&mu, "a", 7, 4); ok {
+		t.Errorf("SetIfRevision of a deleted key = %d, true, want false", rev)
+	}
+	m.Set(&mu, "a", 7)
+	if _, rev, _ := m.GetWithRevision(&mu, "a"); rev != 5 {
+		t.Errorf("GetWithRevision(a) revision = %d, want 5", rev)
+	}
+	m.Clear(&mu)
+	if n := len(m.revs.byKey); n != 0 {
+		t.Errorf("%d revisions left after Clear", n)
+	}
+}
+
+func TestRevisionsRequireOption(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("GetWithRevision did not panic")
+		}
+	}()
+	mu := sync.RWMutex{}
+	New[string, int]().GetWithRevision(&mu, "a")
+}
@@ -0,0 +1,103 @@
+package valuemap
+
+import "sync"
+
+// Union returns a new ValueMap with the entries of a and b. The value of a
+// key present in both is decided by resolve, called with its value in a and
+// in b. Each map is copied under its own lock, so the result is computed
+// from a consistent snapshot of each, and the maps can share a mutex or be
+// the same map.
+//
+// aMu is an external mutex to lock a while it is copied, and bMu likewise
+// for b
+func Union[K comparable, V any](a *ValueMap[K, V], aMu *sync.RWMutex, b *ValueMap[K, V], bMu *sync.RWMutex, resolve func(key K, a, b V) V) *ValueMap[K, V] {
+	res := a.Raw(aMu)
+	for k, v := range b.Raw(bMu) {
+		if cur, ok := res[k]; ok {
+			v = resolve(k, cur, v)
+		}
+		res[k] = v
+	}
+	return &ValueMap[K, V]{data: res}
+}
+
+// Intersect returns a new ValueMap with the entries of a whose keys are
+// also in b. The maps are copied as by Union.
+//
+// aMu is an external mutex to lock a while it is copied, and bMu likewise
+// for b
+func Intersect[K comparable, V any](a *ValueMap[K, V], aMu *sync.RWMutex, b *ValueMap[K, V], bMu *sync.RWMutex) *ValueMap[K, V] {
+	res := a.Raw(aMu)
+	other := b.Raw(bMu)
+	for k := range res {
+		if _, ok := other[k]; !ok {
+			delete(res, k)
+		}
+	}
+	return &ValueMap[K, V]{data: res}
+}
+
+// Difference returns a new ValueMap with the entries of a whose keys are
+// not in b. The maps are copied as by Union.
+//
+// aMu is an external mutex to lock a while it is copied, and bMu likewise
+// for b
+func Difference[K comparable, V any](a *ValueMap[K, V], aMu *sync.RWMutex, b *ValueMap[K, V], bMu *sync.RWMutex) *ValueMap[K, V] {
+	res := a.Raw(aMu)
+	for k := range b.Raw(bMu) {
+		delete(res, k)
+	}
+	return &ValueMap[K, V]{data: res}
+}
+
+// SymmetricDifference returns a new ValueMap with the entries of a whose
+// keys are not in b, and the entries of b whose keys are not in a. The
+// maps are copied as by Union.
+//
+// aMu is an external mutex to lock a while it is copied, and bMu likewise
+// for b
+func SymmetricDifference[K comparable, V any](a *ValueMap[K, V], aMu *sync.RWMutex, b *ValueMap[K, V], bMu *sync.RWMutex) *ValueMap[K, V] {
+	res := a.Raw(aMu)
+	for k, v := range b.Raw(bMu) {
+		if _, ok := res[k]; ok {
+			delete(res, k)
+		} else {
+			res[k] = v
+		}
+	}
+	return &ValueMap[K, V]{data: res}
+}
+
+// ExtraKeys returns the keys present in the map but not in other, in no
+// particular order. The keys of each map are copied under its own lock, so
+// the maps can share a mutex.
+//
+// mu is an external mutex to lock the internal map during key retrieval,
+// and otherMu likewise for other
+func (m *ValueMap[K, V]) ExtraKeys(mu *sync.RWMutex, other *ValueMap[K, V], otherMu *sync.RWMutex) []K {
+	return keysNotIn(m.Keys(mu), other.Keys(otherMu))
+}
+
+// MissingKeys returns the keys present in other but not in the map, in no
+// particular order. It is the converse of ExtraKeys.
+//
+// mu is an external mutex to lock the internal map during key retrieval,
+// and otherMu likewise for other
+func (m *ValueMap[K, V]) MissingKeys(mu *sync.RWMutex, other *ValueMap[K, V], otherMu *sync.RWMutex) []K {
+	return keysNotIn(other.Keys(otherMu), m.Keys(mu))
+}
+
+// keysNotIn returns the keys of keys that are not in exclude.
+func keysNotIn[K comparable](keys, exclude []K) []K {
+	set := make(map[K]struct{}, len(exclude))
+	for _, k := range exclude {
+		set[k] = struct{}{}
+	}
+	res := make([]K, 0)
+	for _, k := range keys {
+		if _, ok := set[k]; !ok {
+			res = append(res, k)
+		}
+	}
+	return res
+}
@@ -0,0 +1,54 @@
+package valuemap
+
+import (
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestSetOperations(t *testing.T) {
+	mu := sync.RWMutex{}
+	a := FromMap(map[string]int{"x": 1, "y": 2})
+	b := FromMap(map[string]int{"y": 20, "z": 3})
+
+	sum := func(k string, a, b int) int { return a + b }
+	if got := Union(a, &mu, b, &mu, sum).Raw(&mu); !maps.Equal(got, map[string]int{"x": 1, "y": 22, "z": 3}) {
+		t.Errorf("Union = %v", got)
+	}
+	if got := Intersect(a, &mu, b, &mu).Raw(&mu); !maps.Equal(got, map[string]int{"y": 2}) {
+		t.Errorf("Intersect = %v", got)
+	}
+	if got := Difference(a, &mu, b, &mu).Raw(&mu); !maps.Equal(got, map[string]int{"x": 1}) {
+		t.Errorf("Difference = %v", got)
+	}
+
+	// The results do not share storage with the operands.
+	Difference(a, &mu, b, &mu).Set(&mu, "w", 0)
+	if a.Len(&mu) != 2 {
+		t.Error("the result of Difference shares storage with a")
+	}
+}
+
+func TestKeyComparison(t *testing.T) {
+	mu := sync.RWMutex{}
+	a := FromMap(map[string]int{"x": 1, "y": 2, "v": 0})
+	b := FromMap(map[string]int{"y": 20, "z": 3, "w": 4})
+
+	if got := SymmetricDifference(a, &mu, b, &mu).Raw(&mu); !maps.Equal(got, map[string]int{"x": 1, "v": 0, "z": 3, "w": 4}) {
+		t.Errorf("SymmetricDifference = %v", got)
+	}
+	extra := a.ExtraKeys(&mu, b, &mu)
+	slices.Sort(extra)
+	if !slices.Equal(extra, []string{"v", "x"}) {
+		t.Errorf("ExtraKeys = %v, want [v x]", extra)
+	}
+	missing := a.MissingKeys(&mu, b, &mu)
+	slices.Sort(missing)
+	if !slices.Equal(missing, []string{"w", "z"}) {
+		t.Errorf("MissingKeys = %v, want [w z]", missing)
+	}
+	if got := a.ExtraKeys(&mu, a, &mu); len(got) != 0 {
+		t.Errorf("ExtraKeys of the map itself = %v, want none", got)
+	}
+}
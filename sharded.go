@@ -0,0 +1,310 @@
+package valuemap
+
+import (
+	"hash/maphash"
+	"sync"
+)
+
+// ShardedValueMap spreads its entries over independently locked shards
+// selected by key hash, so writers to different shards do not contend.
+//
+// Unlike ValueMap, it owns its locks, because a single external mutex
+// would serialize all shards again. Its methods therefore take no mutex;
+// AsMap adapts it to the Map interface.
+//
+// Operations on a single key are atomic, as they are on a ValueMap.
+// Operations on several keys or on the whole map, such as SetMany,
+// GetMany, DeleteMany, DeleteFunc, Merge, Clone, Len, Keys, Values, Range,
+// Clear and Raw, lock one shard at a time, so they are atomic within each
+// shard but not across shards: other goroutines can see or make changes
+// to some shards while the operation is in progress on others.
+type ShardedValueMap[K comparable, V any] struct {
+	seed   maphash.Seed
+	shards []shard[K, V]
+}
+
+type shard[K comparable, V any] struct {
+	mu sync.RWMutex
+	m  *ValueMap[K, V]
+}
+
+// NewSharded returns a new pointer to a ShardedValueMap with n shards.
+// An n of zero or less means one shard.
+func NewSharded[K comparable, V any](n int) *ShardedValueMap[K, V] {
+	s := &ShardedValueMap[K, V]{
+		seed:   maphash.MakeSeed(),
+		shards: make([]shard[K, V], max(n, 1)),
+	}
+	for i := range s.shards {
+		s.shards[i].m = New[K, V]()
+	}
+	return s
+}
+
+// index returns the index of the shard holding key.
+func (s *ShardedValueMap[K, V]) index(key K) int {
+	return int(maphash.Comparable(s.seed, key) % uint64(len(s.shards)))
+}
+
+// shard returns the map and mutex of the shard holding key.
+func (s *ShardedValueMap[K, V]) shard(key K) (*ValueMap[K, V], *sync.RWMutex) {
+	sh := &s.shards[s.index(key)]
+	return sh.m, &sh.mu
+}
+
+// Set assigns a value to a key.
+func (s *ShardedValueMap[K, V]) Set(key K, value V) {
+	m, mu := s.shard(key)
+	m.Set(mu, key, value)
+}
+
+// Get retrieves a value and a boolean indicating if the key exists.
+func (s *ShardedValueMap[K, V]) Get(key K) (V, bool) {
+	m, mu := s.shard(key)
+	return m.Get(mu, key)
+}
+
+// GetOrSet returns the existing value for the key if present.
+// Otherwise, it stores and returns the given value.
+func (s *ShardedValueMap[K, V]) GetOrSet(key K, value V) (actual V, loaded bool) {
+	m, mu := s.shard(key)
+	return m.GetOrSet(mu, key, value)
+}
+
+// SetIfAbsent assigns a value to a key only if the key is not present.
+func (s *ShardedValueMap[K, V]) SetIfAbsent(key K, value V) bool {
+	m, mu := s.shard(key)
+	return m.SetIfAbsent(mu, key, value)
+}
+
+// SetIfPresent assigns a value to a key only if the key is already present.
+func (s *ShardedValueMap[K, V]) SetIfPresent(key K, value V) bool {
+	m, mu := s.shard(key)
+	return m.SetIfPresent(mu, key, value)
+}
+
+// Swap assigns a value to a key and returns the previous value, if any.
+func (s *ShardedValueMap[K, V]) Swap(key K, value V) (previous V, existed bool) {
+	m, mu := s.shard(key)
+	return m.Swap(mu, key, value)
+}
+
+// Update performs a read-modify-write on the value of a key.
+// See ValueMap.Update.
+func (s *ShardedValueMap[K, V]) Update(key K, fn func(current V, exists bool) (V, bool)) (V, bool) {
+	m, mu := s.shard(key)
+	return m.Update(mu, key, fn)
+}
+
+// ComputeIfAbsent returns the value of a key, first storing the result of
+// factory if the key is not present. See ValueMap.ComputeIfAbsent.
+func (s *ShardedValueMap[K, V]) ComputeIfAbsent(key K, factory func(key K) V) V {
+	m, mu := s.shard(key)
+	return m.ComputeIfAbsent(mu, key, factory)
+}
+
+// ComputeIfPresent replaces the value of a present key with the result of
+// fn, or removes the key if fn returns false. See ValueMap.ComputeIfPresent.
+func (s *ShardedValueMap[K, V]) ComputeIfPresent(key K, fn func(key K, value V) (V, bool)) (V, bool) {
+	m, mu := s.shard(key)
+	return m.ComputeIfPresent(mu, key, fn)
+}
+
+// CompareAndSwapFunc assigns new to a key only if its current value equals
+// old according to eq. See ValueMap.CompareAndSwapFunc.
+func (s *ShardedValueMap[K, V]) CompareAndSwapFunc(key K, old, new V, eq func(a, b V) bool) bool {
+	m, mu := s.shard(key)
+	return m.CompareAndSwapFunc(mu, key, old, new, eq)
+}
+
+// CompareAndDeleteFunc removes a key only if its current value equals old
+// according to eq. See ValueMap.CompareAndDeleteFunc.
+func (s *ShardedValueMap[K, V]) CompareAndDeleteFunc(key K, old V, eq func(a, b V) bool) bool {
+	m, mu := s.shard(key)
+	return m.CompareAndDeleteFunc(mu, key, old, eq)
+}
+
+// CompareAndSwapSharded is CompareAndSwap for a ShardedValueMap.
+func CompareAndSwapSharded[K, V comparable](s *ShardedValueMap[K, V], key K, old, new V) bool {
+	return s.CompareAndSwapFunc(key, old, new, equal[V])
+}
+
+// CompareAndDeleteSharded is CompareAndDelete for a ShardedValueMap.
+func CompareAndDeleteSharded[K, V comparable](s *ShardedValueMap[K, V], key K, old V) bool {
+	return s.CompareAndDeleteFunc(key, old, equal[V])
+}
+
+// Delete removes a key from the map.
+func (s *ShardedValueMap[K, V]) Delete(key K) {
+	m, mu := s.shard(key)
+	m.Delete(mu, key)
+}
+
+// Pop removes a key from the map and returns its value, if any.
+func (s *ShardedValueMap[K, V]) Pop(key K) (V, bool) {
+	m, mu := s.shard(key)
+	return m.Pop(mu, key)
+}
+
+// SetMany assigns multiple key-value pairs, locking each shard once.
+func (s *ShardedValueMap[K, V]) SetMany(entries map[K]V) {
+	groups := make([]map[K]V, len(s.shards))
+	for k, v := range entries {
+		i := s.index(k)
+		if groups[i] == nil {
+			groups[i] = make(map[K]V)
+		}
+		groups[i][k] = v
+	}
+	for i, g := range groups {
+		if g != nil {
+			s.shards[i].m.SetMany(&s.shards[i].mu, g)
+		}
+	}
+}
+
+// GetMany returns the values of the given keys that are present,
+// locking each shard once.
+func (s *ShardedValueMap[K, V]) GetMany(keys []K) map[K]V {
+	out := make(map[K]V, len(keys))
+	for i, g := range s.group(keys) {
+		if g != nil {
+			for k, v := range s.shards[i].m.GetMany(&s.shards[i].mu, g) {
+				out[k] = v
+			}
+		}
+	}
+	return out
+}
+
+// DeleteMany removes multiple keys, locking each shard once, and returns
+// how many were present.
+func (s *ShardedValueMap[K, V]) DeleteMany(keys []K) int {
+	n := 0
+	for i, g := range s.group(keys) {
+		if g != nil {
+			n += s.shards[i].m.DeleteMany(&s.shards[i].mu, g)
+		}
+	}
+	return n
+}
+
+// group splits keys by the shard holding them.
+func (s *ShardedValueMap[K, V]) group(keys []K) [][]K {
+	groups := make([][]K, len(s.shards))
+	for _, k := range keys {
+		i := s.index(k)
+		groups[i] = append(groups[i], k)
+	}
+	return groups
+}
+
+// DeleteFunc removes the entries for which del returns true, shard by
+// shard, and returns how many were removed. del must not call methods of
+// the map.
+func (s *ShardedValueMap[K, V]) DeleteFunc(del func(key K, value V) bool) int {
+	n := 0
+	for i := range s.shards {
+		n += s.shards[i].m.DeleteFunc(&s.shards[i].mu, del)
+	}
+	return n
+}
+
+// Merge adds or overwrites keys from another ShardedValueMap into this one.
+// The entries of other are copied shard by shard before they are stored.
+func (s *ShardedValueMap[K, V]) Merge(other *ShardedValueMap[K, V]) {
+	s.SetMany(other.Raw())
+}
+
+// Clone returns a copy of the map with the same number of shards.
+// Each shard is cloned as with ValueMap.Clone.
+func (s *ShardedValueMap[K, V]) Clone() *ShardedValueMap[K, V] {
+	c := &ShardedValueMap[K, V]{seed: s.seed, shards: make([]shard[K, V], len(s.shards))}
+	for i := range s.shards {
+		c.shards[i].m = s.shards[i].m.Clone(&s.shards[i].mu)
+	}
+	return c
+}
+
+// Len returns the number of key-value pairs, summed shard by shard.
+func (s *ShardedValueMap[K, V]) Len() int {
+	n := 0
+	for i := range s.shards {
+		n += s.shards[i].m.Len(&s.shards[i].mu)
+	}
+	return n
+}
+
+// Keys returns a slice of all keys, collected shard by shard.
+func (s *ShardedValueMap[K, V]) Keys() []K {
+	var keys []K
+	for i := range s.shards {
+		keys = append(keys, s.shards[i].m.Keys(&s.shards[i].mu)...)
+	}
+	return keys
+}
+
+// Values returns a slice of all values, collected shard by shard.
+func (s *ShardedValueMap[K, V]) Values() []V {
+	var values []V
+	for i := range s.shards {
+		values = append(values, s.shards[i].m.Values(&s.shards[i].mu)...)
+	}
+	return values
+}
+
+// Range calls fn sequentially for each key and value present in the map,
+// locking one shard at a time. If fn returns false, Range stops the iteration.
+// fn must not write to the map.
+func (s *ShardedValueMap[K, V]) Range(fn func(key K, value V) bool) {
+	for i := range s.shards {
+		cont := true
+		s.shards[i].m.Range(&s.shards[i].mu, func(k K, v V) bool {
+			cont = fn(k, v)
+			return cont
+		})
+		if !cont {
+			return
+		}
+	}
+}
+
+// Clear removes all entries from the map, shard by shard.
+func (s *ShardedValueMap[K, V]) Clear() {
+	for i := range s.shards {
+		s.shards[i].m.Clear(&s.shards[i].mu)
+	}
+}
+
+// Raw returns a copy of all entries, collected shard by shard.
+func (s *ShardedValueMap[K, V]) Raw() map[K]V {
+	cp := make(map[K]V)
+	s.Range(func(k K, v V) bool {
+		cp[k] = v
+		return true
+	})
+	return cp
+}
+
+// AsMap returns the map as a Map. The shards own their locks, so the
+// mutexes passed to the methods of the result are ignored and may be nil.
+func (s *ShardedValueMap[K, V]) AsMap() Map[K, V] {
+	return shardedMap[K, V]{s}
+}
+
+// shardedMap adapts a ShardedValueMap to Map.
+type shardedMap[K comparable, V any] struct {
+	s *ShardedValueMap[K, V]
+}
+
+func (m shardedMap[K, V]) Set(_ *sync.RWMutex, key K, value V)  { m.s.Set(key, value) }
+func (m shardedMap[K, V]) Get(_ *sync.RWMutex, key K) (V, bool) { return m.s.Get(key) }
+func (m shardedMap[K, V]) Delete(_ *sync.RWMutex, key K)        { m.s.Delete(key) }
+func (m shardedMap[K, V]) Len(_ *sync.RWMutex) int              { return m.s.Len() }
+func (m shardedMap[K, V]) Keys(_ *sync.RWMutex) []K             { return m.s.Keys() }
+func (m shardedMap[K, V]) Values(_ *sync.RWMutex) []V           { return m.s.Values() }
+func (m shardedMap[K, V]) Clear(_ *sync.RWMutex)                { m.s.Clear() }
+
+func (m shardedMap[K, V]) Range(_ *sync.RWMutex, fn func(key K, value V) bool) {
+	m.s.Range(fn)
+}
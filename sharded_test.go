@@ -0,0 +1,83 @@
+package valuemap
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestShardedValueMap(t *testing.T) {
+	s := NewSharded[string, int](8)
+
+	var wg sync.WaitGroup
+	for g := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 100 {
+				s.Set(strconv.Itoa(g*100+i), i)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := s.Len(); n != 1600 {
+		t.Errorf("Len = %d, want 1600", n)
+	}
+	if v, ok := s.Get("1505"); !ok || v != 5 {
+		t.Errorf("Get(1505) = %d, %v; want 5, true", v, ok)
+	}
+	if v, ok := s.Pop("1505"); !ok || v != 5 {
+		t.Errorf("Pop(1505) = %d, %v; want 5, true", v, ok)
+	}
+	if n := len(s.Raw()); n != 1599 {
+		t.Errorf("len(Raw) = %d, want 1599", n)
+	}
+	s.Clear()
+	if n := len(s.Keys()); n != 0 {
+		t.Errorf("len(Keys) = %d after Clear, want 0", n)
+	}
+}
+
+func TestShardedValueMapMany(t *testing.T) {
+	s := NewSharded[int, string](4)
+	s.SetMany(map[int]string{1: "a", 2: "b", 3: "c", 4: "d"})
+	if got := s.GetMany([]int{1, 3, 5}); len(got) != 2 || got[1] != "a" || got[3] != "c" {
+		t.Errorf("GetMany = %v, want 1 and 3", got)
+	}
+	if n := s.DeleteMany([]int{1, 5}); n != 1 {
+		t.Errorf("DeleteMany = %d, want 1", n)
+	}
+	if n := s.DeleteFunc(func(k int, _ string) bool { return k%2 == 0 }); n != 2 {
+		t.Errorf("DeleteFunc = %d, want 2", n)
+	}
+
+	if v := s.ComputeIfAbsent(7, func(int) string { return "g" }); v != "g" {
+		t.Errorf("ComputeIfAbsent = %q, want g", v)
+	}
+	if _, ok := s.ComputeIfPresent(8, func(int, string) (string, bool) { return "h", true }); ok {
+		t.Error("ComputeIfPresent stored an absent key")
+	}
+	if !CompareAndSwapSharded(s, 7, "g", "G") || CompareAndSwapSharded(s, 7, "g", "x") {
+		t.Error("CompareAndSwapSharded did not compare the current value")
+	}
+	if !CompareAndDeleteSharded(s, 7, "G") {
+		t.Error("CompareAndDeleteSharded did not delete a matching value")
+	}
+
+	c := s.Clone()
+	c.Set(9, "i")
+	if _, ok := s.Get(9); ok {
+		t.Error("writing to the clone changed the original")
+	}
+	s.Merge(c)
+	if got := s.Raw(); len(got) != 2 || got[3] != "c" || got[9] != "i" {
+		t.Errorf("Raw after Merge = %v, want 3 and 9", got)
+	}
+
+	var m Map[int, string] = s.AsMap()
+	m.Set(nil, 10, "j")
+	if v, _ := s.Get(10); v != "j" || m.Len(nil) != 3 {
+		t.Errorf("AsMap did not write through: Get(10) = %q, Len = %d", v, m.Len(nil))
+	}
+}
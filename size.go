@@ -0,0 +1,149 @@
+package valuemap
+
+import (
+	"reflect"
+	"sync"
+)
+
+// SizeBytes returns an estimate of the memory held by the entries of the
+// map, in bytes. If sizer is not nil, it is the sum of what sizer returns
+// for each entry. Otherwise SizeBytes walks the keys and values with
+// reflection: it counts the slots of the internal map, and the memory
+// reachable from each key and value through strings, slices, maps,
+// pointers, interfaces and channels. Memory reachable several times, from
+// one entry or from several, is counted once. The estimate ignores
+// allocator rounding, and it is slow for large maps of pointer-rich values,
+// so a sizer that knows the values is preferable for frequent calls.
+//
+// mu is an external mutex to lock the internal map during the estimate,
+// so sizer must not call methods that lock mu
+func (m *ValueMap[K, V]) SizeBytes(mu *sync.RWMutex, sizer func(key K, value V) int) int {
+	defer m.readLock(mu).Unlock()
+	n := 0
+	if sizer != nil {
+		for k, v := range m.live() {
+			n += sizer(k, v)
+		}
+		return n
+	}
+	s := sizing{seen: make(map[uintptr]bool)}
+	kt, vt := reflect.TypeFor[K](), reflect.TypeFor[V]()
+	for k, v := range m.live() {
+		n += s.indirect(reflect.ValueOf(&k).Elem()) + s.indirect(reflect.ValueOf(&v).Elem())
+	}
+	return n + mapSlots(m.count(), kt, vt)
+}
+
+// mapSlots estimates the size of the table of a Go map with n entries.
+// Each slot holds a key and a value plus a control byte, and tables are
+// kept at most 7/8 full.
+func mapSlots(n int, kt, vt reflect.Type) int {
+	return n * (int(kt.Size()+vt.Size()) + 1) * 8 / 7
+}
+
+// sizing estimates the memory reachable from values, remembering what it
+// has counted.
+type sizing struct {
+	seen map[uintptr]bool
+}
+
+// first reports whether the memory at p is counted for the first time.
+func (s *sizing) first(p uintptr) bool {
+	if p == 0 || s.seen[p] {
+		return false
+	}
+	s.seen[p] = true
+	return true
+}
+
+// indirect returns the size of the memory reachable from v, not counting
+// v itself.
+func (s *sizing) indirect(v reflect.Value) int {
+	switch v.Kind() {
+	case reflect.String:
+		if v.Len() > 0 && s.first(uintptr(v.UnsafePointer())) {
+			return v.Len()
+		}
+	case reflect.Slice:
+		if v.Cap() == 0 || !s.first(uintptr(v.UnsafePointer())) {
+			return 0
+		}
+		return v.Cap()*int(v.Type().Elem().Size()) + s.elems(v)
+	case reflect.Array:
+		return s.elems(v)
+	case reflect.Struct:
+		if flat(v.Type()) {
+			return 0
+		}
+		n := 0
+		for i := range v.NumField() {
+			n += s.indirect(v.Field(i))
+		}
+		return n
+	case reflect.Pointer:
+		if v.IsNil() || !s.first(v.Pointer()) {
+			return 0
+		}
+		return int(v.Type().Elem().Size()) + s.indirect(v.Elem())
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		e := v.Elem()
+		if e.Kind() == reflect.Pointer {
+			return s.indirect(e)
+		}
+		// Other dynamic values are boxed in memory of their own.
+		return int(e.Type().Size()) + s.indirect(e)
+	case reflect.Map:
+		if v.IsNil() || !s.first(v.Pointer()) {
+			return 0
+		}
+		t := v.Type()
+		n := mapSlots(v.Len(), t.Key(), t.Elem())
+		if !flat(t.Key()) || !flat(t.Elem()) {
+			for it := v.MapRange(); it.Next(); {
+				n += s.indirect(it.Key()) + s.indirect(it.Value())
+			}
+		}
+		return n
+	case reflect.Chan:
+		if !v.IsNil() && s.first(v.Pointer()) {
+			return v.Cap() * int(v.Type().Elem().Size())
+		}
+	}
+	return 0
+}
+
+// elems returns the size of the memory reachable from the elements of an
+// array or slice.
+func (s *sizing) elems(v reflect.Value) int {
+	if flat(v.Type().Elem()) {
+		return 0
+	}
+	n := 0
+	for i := range v.Len() {
+		n += s.indirect(v.Index(i))
+	}
+	return n
+}
+
+// flat reports whether values of t reach no other memory.
+func flat(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	case reflect.Array:
+		return flat(t.Elem())
+	case reflect.Struct:
+		for i := range t.NumField() {
+			if !flat(t.Field(i).Type) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
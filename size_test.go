@@ -0,0 +1,45 @@
+package valuemap
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSizeBytes(t *testing.T) {
+	mu := sync.RWMutex{}
+	shared := make([]byte, 0, 100)
+	m := FromMap(map[string][]byte{
+		"ab": make([]byte, 10),
+		"cd": shared,
+		"ef": shared,
+	})
+
+	// Three slots of a string and a slice header, the key bytes, and the
+	// backing arrays, with the shared one counted once.
+	slots := 3 * (16 + 24 + 1) * 8 / 7
+	if got, want := m.SizeBytes(&mu, nil), slots+3*2+10+100; got != want {
+		t.Errorf("SizeBytes(nil) = %d, want %d", got, want)
+	}
+	if got := m.SizeBytes(&mu, func(k string, v []byte) int { return len(k) + len(v) }); got != 16 {
+		t.Errorf("SizeBytes(sizer) = %d, want 16", got)
+	}
+}
+
+func TestSizeBytesNested(t *testing.T) {
+	type node struct {
+		Name string
+		Next *node
+		Tags map[string]int
+	}
+	mu := sync.RWMutex{}
+	loop := &node{Name: "loop"}
+	loop.Next = loop
+	m := FromMap(map[int]any{1: loop, 2: 42})
+
+	// Two slots of an int and an interface, the node once despite the
+	// cycle, its name, and the boxed int.
+	want := 2*(8+16+1)*8/7 + 32 + 4 + 8
+	if got := m.SizeBytes(&mu, nil); got != want {
+		t.Errorf("SizeBytes(nil) = %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,48 @@
+package valuemap
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// WithStaleWhileRevalidate makes Get and GetContext of a map created with
+// WithTTL and WithLoader return the value of an expired entry at once,
+// while the loader fetches a fresh one in the background, as long as the
+// entry expired less than maxStale ago. Entries that expired longer ago
+// are loaded as missing keys. Latency-sensitive callers get slightly
+// stale data instead of waiting for the loader.
+//
+// Stale entries are kept until maxStale has passed, and only Get and
+// GetContext see them; the other methods treat them as expired. If the
+// background load fails, the stale value is served until the next Get
+// starts another one. Using it without WithTTL and a loader, either from
+// WithLoader or from a store, panics.
+func WithStaleWhileRevalidate(maxStale time.Duration) Option {
+	return optionFunc(func(c *config) {
+		c.maxStale = maxStale
+	})
+}
+
+// WithRefreshAhead makes Get and GetContext of a map created with WithTTL
+// and WithLoader start reloading an entry in the background when it is
+// read after fraction of its lifetime has passed, so that frequently read
+// keys are refreshed before they expire and are never missed. The current
+// value is returned meanwhile, and concurrent reads start a single reload.
+// With a fraction of 0.8 and a TTL of a minute, entries read 48 seconds or
+// more after they were assigned are reloaded.
+//
+// Entries that are not read in that last part of their lifetime expire as
+// usual. fraction must be greater than 0 and less than 1, and using it
+// without WithTTL and a loader panics.
+func WithRefreshAhead(fraction float64) Option {
+	return optionFunc(func(c *config) {
+		c.refreshAhead = fraction
+	})
+}
+
+// revalidate starts loading a key that was served stale or is due for a
+// refresh, unless a load of the key is already in progress.
+func (m *ValueMap[K, V]) revalidate(mu *sync.RWMutex, key K) {
+	m.call(context.Background(), mu, key)
+}
@@ -0,0 +1,112 @@
+package valuemap
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithStaleWhileRevalidate(t *testing.T) {
+	mu := sync.RWMutex{}
+	clock := NewManualClock(time.Unix(0, 0))
+	loads := make(chan int)
+	m := New[string, int](
+		WithTTL(&mu, time.Second, 0),
+		WithClock(clock),
+		WithLoader(func(ctx context.Context, key string) (int, error) {
+			return <-loads, nil
+		}),
+		WithStaleWhileRevalidate(5*time.Second),
+	)
+	m.Set(&mu, "a", 1)
+
+	// An expired entry is served while it is reloaded.
+	clock.Advance(2 * time.Second)
+	if v, ok := m.Get(&mu, "a"); v != 1 || !ok {
+		t.Errorf("Get(a) = %d, %v, want the stale 1", v, ok)
+	}
+	if n := m.DeleteExpired(&mu); n != 0 {
+		t.Errorf("DeleteExpired removed %d stale entries", n)
+	}
+	if v, _ := m.Get(&mu, "a"); v != 1 {
+		t.Errorf("Get(a) = %d, want the stale 1 during the reload", v)
+	}
+	loads <- 2
+	for {
+		if v, _ := m.Get(&mu, "a"); v == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// An entry expired longer than the staleness is loaded.
+	clock.Advance(7 * time.Second)
+	if n := m.DeleteExpired(&mu); n != 1 {
+		t.Errorf("DeleteExpired removed %d entries, want 1", n)
+	}
+	go func() { loads <- 3 }()
+	if v, _ := m.GetContext(context.Background(), &mu, "a"); v != 3 {
+		t.Errorf("GetContext(a) = %d, want the loaded 3", v)
+	}
+}
+
+func TestWithStaleWhileRevalidateRequiresLoader(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("New did not panic")
+		}
+	}()
+	mu := sync.RWMutex{}
+	New[string, int](WithTTL(&mu, time.Second, 0), WithStaleWhileRevalidate(time.Second))
+}
+
+func TestWithRefreshAhead(t *testing.T) {
+	mu := sync.RWMutex{}
+	clock := NewManualClock(time.Unix(0, 0))
+	loads := make(chan int)
+	m := New[string, int](
+		WithTTL(&mu, 10*time.Second, 0),
+		WithClock(clock),
+		WithLoader(func(ctx context.Context, key string) (int, error) {
+			return <-loads, nil
+		}),
+		WithRefreshAhead(0.8),
+	)
+	m.Set(&mu, "a", 1)
+
+	clock.Advance(7 * time.Second)
+	if v, _ := m.Get(&mu, "a"); v != 1 {
+		t.Errorf("Get(a) = %d, want 1", v)
+	}
+	clock.Advance(time.Second)
+	if v, _ := m.Get(&mu, "a"); v != 1 {
+		t.Errorf("Get(a) = %d, want 1 during the refresh", v)
+	}
+	loads <- 2
+	for {
+		if v, _ := m.Get(&mu, "a"); v == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if d, _ := m.TTL(&mu, "a"); d != 10*time.Second {
+		t.Errorf("TTL(a) = %v after the refresh, want 10s", d)
+	}
+
+	// A value assigned during the refresh is kept.
+	clock.Advance(9 * time.Second)
+	m.Get(&mu, "a")
+	m.Set(&mu, "a", 3)
+	loads <- 4
+	for m.Len(&mu) > 0 && func() bool {
+		m.loader.mu.Lock()
+		defer m.loader.mu.Unlock()
+		return len(m.loader.calls) > 0
+	}() {
+		time.Sleep(time.Millisecond)
+	}
+	if v, _ := m.Get(&mu, "a"); v != 3 {
+		t.Errorf("Get(a) = %d, want the assigned 3", v)
+	}
+}
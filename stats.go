@@ -0,0 +1,80 @@
+package valuemap
+
+import "sync/atomic"
+
+// counters counts the operations on a map. They are updated atomically,
+// because reads only hold the read lock.
+type counters struct {
+	hits, misses atomic.Uint64
+	sets         atomic.Uint64
+	deletes      atomic.Uint64
+	evictions    atomic.Uint64
+	expirations  atomic.Uint64
+	clears       atomic.Uint64
+}
+
+// read counts a lookup of a key.
+func (c *counters) read(hit bool) {
+	if hit {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+}
+
+// removal counts n entries removed for the given reason.
+func (c *counters) removal(reason RemovalReason, n uint64) {
+	switch reason {
+	case ReasonDeleted:
+		c.deletes.Add(n)
+	case ReasonEvicted:
+		c.evictions.Add(n)
+	case ReasonExpired:
+		c.expirations.Add(n)
+	case ReasonCleared:
+		c.clears.Add(n)
+	}
+}
+
+// Stats holds the operation counters of a map.
+type Stats struct {
+	// Gets counts the lookups of Get, GetMany, GetOrSet and ComputeIfAbsent,
+	// and Hits and Misses those that found their key and those that did not.
+	Gets, Hits, Misses uint64
+	// Sets counts the values assigned to keys.
+	Sets uint64
+	// Deletes, Evictions, Expirations and Clears count the entries removed
+	// for each RemovalReason.
+	Deletes, Evictions, Expirations, Clears uint64
+}
+
+// HitRatio returns the fraction of lookups that found their key, or zero
+// if there were none.
+func (s Stats) HitRatio() float64 {
+	if s.Gets == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Gets)
+}
+
+// Stats returns the operation counters of the map, and resets them to zero
+// if reset is true, so that each call returns the counts since the last.
+// The counters are updated atomically, so Stats does not lock the map, and
+// the counters it returns can be a few operations apart from each other.
+func (m *ValueMap[K, V]) Stats(reset bool) Stats {
+	load := (*atomic.Uint64).Load
+	if reset {
+		load = func(c *atomic.Uint64) uint64 { return c.Swap(0) }
+	}
+	s := Stats{
+		Hits:        load(&m.ops.hits),
+		Misses:      load(&m.ops.misses),
+		Sets:        load(&m.ops.sets),
+		Deletes:     load(&m.ops.deletes),
+		Evictions:   load(&m.ops.evictions),
+		Expirations: load(&m.ops.expirations),
+		Clears:      load(&m.ops.clears),
+	}
+	s.Gets = s.Hits + s.Misses
+	return s
+}
@@ -0,0 +1,45 @@
+package valuemap
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := NewBounded[string, int](1)
+	m.Set(&mu, "a", 1)
+	m.Set(&mu, "b", 2) // evicts a
+	m.Get(&mu, "a")
+	m.Get(&mu, "b")
+	m.Delete(&mu, "b")
+	m.Set(&mu, "c", 3)
+	m.Clear(&mu)
+
+	want := Stats{Gets: 2, Hits: 1, Misses: 1, Sets: 3, Deletes: 1, Evictions: 1, Clears: 1}
+	if got := m.Stats(true); got != want {
+		t.Errorf("Stats(true) = %+v, want %+v", got, want)
+	}
+	if got := want.HitRatio(); got != 0.5 {
+		t.Errorf("HitRatio() = %v, want 0.5", got)
+	}
+	if got := m.Stats(false); got != (Stats{}) {
+		t.Errorf("Stats(false) = %+v after reset, want zero", got)
+	}
+	if got := (Stats{}).HitRatio(); got != 0 {
+		t.Errorf("HitRatio() = %v without lookups, want 0", got)
+	}
+}
+
+func TestStatsExpirations(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := NewWithTTL[string, int](&mu, 0, 0)
+	m.SetWithTTL(&mu, "a", 1, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	m.Get(&mu, "a")
+	m.DeleteExpired(&mu)
+	if got := m.Stats(false); got.Expirations != 1 || got.Misses != 1 {
+		t.Errorf("Stats(false) = %+v, want 1 expiration and 1 miss", got)
+	}
+}
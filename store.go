@@ -0,0 +1,264 @@
+package valuemap
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNoStore is returned by FlushWrites for maps created without
+// WithWriteThrough or WithWriteBehind.
+var ErrNoStore = errors.New("valuemap: map has no store")
+
+// Store is a backing store, such as a database table, that a map created
+// with WithWriteThrough or WithWriteBehind keeps up to date. Load returns
+// ErrNotFound, or an error wrapping it, for keys that do not exist.
+type Store[K comparable, V any] interface {
+	Load(ctx context.Context, key K) (V, error)
+	Save(ctx context.Context, key K, value V) error
+	Delete(ctx context.Context, key K) error
+}
+
+// BatchStore is a Store that can apply several writes at once, for example
+// in a single transaction. The write-behind queue uses it to flush all the
+// pending writes in one call.
+type BatchStore[K comparable, V any] interface {
+	Store[K, V]
+	WriteBatch(ctx context.Context, writes []StoreWrite[K, V]) error
+}
+
+// StoreWrite is a pending write of a key: the assignment of Value, or its
+// deletion if Deleted is true.
+type StoreWrite[K comparable, V any] struct {
+	Key     K
+	Value   V
+	Deleted bool
+}
+
+// WithWriteThrough makes the map save every assignment to store, and
+// delete from it the keys removed with Delete and the other deleting
+// methods, before the method returns. The map acts as a cache in front of
+// store: Load is used to fetch missing keys as with WithLoader, unless a
+// loader is given, and evictions, expirations, Clear, Replace and the
+// values loaded or decoded into the map are not written back.
+//
+// store is called with the external mutex locked, so it blocks the other
+// methods until it returns. Since the methods of ValueMap do not return
+// errors, a failed write is not retried; the first error is kept and
+// reported by FlushWrites and Close.
+func WithWriteThrough[K comparable, V any](store Store[K, V]) Option {
+	return optionFunc(func(c *config) {
+		c.store = store
+		c.flushInterval = 0
+		c.writeBehind = false
+	})
+}
+
+// WithWriteBehind is like WithWriteThrough, but queues the writes and
+// applies them to store in the background every flushInterval, so that the
+// methods of the map do not wait for it. Writes of the same key are
+// coalesced, so only the last one is applied. The queue is flushed as a
+// batch with one WriteBatch call if store is a BatchStore, and with a call
+// per key otherwise. Writes that fail stay queued and are retried on the
+// next flush, unless the key was written again since.
+//
+// FlushWrites applies the queue at once, and Close flushes it a last time
+// and stops the background flushes; writes made after Close are dropped.
+// Missing keys are loaded from the queue while their writes are pending, so
+// the map does not load values that are about to be overwritten. An
+// interval of zero or less disables the background flushes.
+func WithWriteBehind[K comparable, V any](store Store[K, V], flushInterval time.Duration) Option {
+	return optionFunc(func(c *config) {
+		c.store = store
+		c.flushInterval = flushInterval
+		c.writeBehind = true
+	})
+}
+
+// writer holds the state of a map created with WithWriteThrough or
+// WithWriteBehind.
+type writer[K comparable, V any] struct {
+	store  Store[K, V]
+	behind bool
+
+	mu       sync.Mutex // guards the fields below
+	pending  map[K]StoreWrite[K, V]
+	inflight map[K]StoreWrite[K, V] // writes of the flush in progress
+	err      error                  // first write-through error
+	closed   bool
+
+	flushing  sync.Mutex // serializes flushes
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func (m *ValueMap[K, V]) startWriter(c *config) {
+	w := &writer[K, V]{
+		store:   typed[Store[K, V]](c.store, "store"),
+		behind:  c.writeBehind,
+		pending: make(map[K]StoreWrite[K, V]),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	m.writer = w
+	if !w.behind || c.flushInterval <= 0 {
+		close(w.stopped)
+		return
+	}
+	go m.writeBehind(c.flushInterval)
+}
+
+func (m *ValueMap[K, V]) writeBehind(interval time.Duration) {
+	w := m.writer
+	defer close(w.stopped)
+	m.every(interval, w.done, func() { w.flush(context.Background()) })
+}
+
+// write passes a change to the store, or queues it.
+// The caller must hold the write lock.
+func (w *writer[K, V]) write(sw StoreWrite[K, V]) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.behind {
+		if !w.closed {
+			w.pending[sw.Key] = sw
+		}
+		return
+	}
+	var err error
+	if sw.Deleted {
+		err = w.store.Delete(context.Background(), sw.Key)
+	} else {
+		err = w.store.Save(context.Background(), sw.Key, sw.Value)
+	}
+	w.err = cmp.Or(w.err, err)
+}
+
+// load fetches a key from the store, or from the queue if a write of the
+// key is pending.
+func (w *writer[K, V]) load(ctx context.Context, key K) (V, error) {
+	w.mu.Lock()
+	sw, ok := w.pending[key]
+	if !ok {
+		sw, ok = w.inflight[key]
+	}
+	w.mu.Unlock()
+	switch {
+	case !ok:
+		return w.store.Load(ctx, key)
+	case sw.Deleted:
+		var zero V
+		return zero, ErrNotFound
+	default:
+		return sw.Value, nil
+	}
+}
+
+// flush applies the queued writes to the store and returns the first
+// error. Failed writes are queued again unless the key was written since.
+func (w *writer[K, V]) flush(ctx context.Context) error {
+	w.flushing.Lock()
+	defer w.flushing.Unlock()
+
+	w.mu.Lock()
+	batch := w.pending
+	w.pending = make(map[K]StoreWrite[K, V])
+	w.inflight = batch
+	w.mu.Unlock()
+	if len(batch) == 0 {
+		return nil
+	}
+
+	var err error
+	failed := batch
+	if bs, ok := w.store.(BatchStore[K, V]); ok {
+		writes := make([]StoreWrite[K, V], 0, len(batch))
+		for _, sw := range batch {
+			writes = append(writes, sw)
+		}
+		if err = bs.WriteBatch(ctx, writes); err == nil {
+			failed = nil
+		}
+	} else {
+		failed = make(map[K]StoreWrite[K, V])
+		for k, sw := range batch {
+			var e error
+			if sw.Deleted {
+				e = w.store.Delete(ctx, k)
+			} else {
+				e = w.store.Save(ctx, k, sw.Value)
+			}
+			if e != nil {
+				failed[k] = sw
+				err = cmp.Or(err, e)
+			}
+		}
+	}
+
+	w.mu.Lock()
+	for k, sw := range failed {
+		if _, ok := w.pending[k]; !ok {
+			w.pending[k] = sw
+		}
+	}
+	w.inflight = nil
+	w.mu.Unlock()
+	return err
+}
+
+// persist passes the assignment of a key to the store, if any.
+// The caller must hold the write lock.
+func (m *ValueMap[K, V]) persist(key K, value V) {
+	if m.writer != nil {
+		m.writer.write(StoreWrite[K, V]{Key: key, Value: value})
+	}
+}
+
+// unpersist passes the deletion of a key to the store, if any.
+// The caller must hold the write lock.
+func (m *ValueMap[K, V]) unpersist(key K) {
+	if m.writer != nil {
+		m.writer.write(StoreWrite[K, V]{Key: key, Deleted: true})
+	}
+}
+
+// FlushWrites applies the writes queued for the store of a map created
+// with WithWriteBehind, and returns the first error. Writes that fail stay
+// queued. For a map created with WithWriteThrough, it returns the first
+// error met while writing since the last call, and forgets it.
+//
+// FlushWrites does not lock the map: the queue has its own lock, and ctx
+// is passed to the store.
+func (m *ValueMap[K, V]) FlushWrites(ctx context.Context) error {
+	w := m.writer
+	if w == nil {
+		return ErrNoStore
+	}
+	if w.behind {
+		return w.flush(ctx)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	err := w.err
+	w.err = nil
+	return err
+}
+
+// closeWriter stops the background flushes and flushes the queue a last
+// time, or returns the pending write-through error.
+func (m *ValueMap[K, V]) closeWriter() error {
+	w := m.writer
+	w.closeOnce.Do(func() {
+		close(w.done)
+		<-w.stopped
+		w.mu.Lock()
+		w.closed = true
+		w.mu.Unlock()
+		w.closeErr = m.FlushWrites(context.Background())
+	})
+	return w.closeErr
+}
@@ -0,0 +1,200 @@
+package valuemap
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memStore is a Store backed by a map, that fails while fail is set.
+type memStore struct {
+	mu      sync.Mutex
+	data    map[string]int
+	writes  int
+	batches int
+	fail    error
+}
+
+func newMemStore() *memStore {
+	return &memStore{data: make(map[string]int)}
+}
+
+func (s *memStore) Load(ctx context.Context, key string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.data[key]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	return v, nil
+}
+
+func (s *memStore) Save(ctx context.Context, key string, value int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail != nil {
+		return s.fail
+	}
+	s.writes++
+	s.data[key] = value
+	return nil
+}
+
+func (s *memStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail != nil {
+		return s.fail
+	}
+	s.writes++
+	delete(s.data, key)
+	return nil
+}
+
+func (s *memStore) contents() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.data)
+}
+
+func (s *memStore) setFail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fail = err
+}
+
+// batchStore is a memStore that applies writes in batches.
+type batchStore struct {
+	*memStore
+}
+
+func (s batchStore) WriteBatch(ctx context.Context, writes []StoreWrite[string, int]) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail != nil {
+		return s.fail
+	}
+	s.batches++
+	for _, w := range writes {
+		if w.Deleted {
+			delete(s.data, w.Key)
+		} else {
+			s.data[w.Key] = w.Value
+		}
+	}
+	return nil
+}
+
+func TestWithWriteThrough(t *testing.T) {
+	mu := sync.RWMutex{}
+	s := newMemStore()
+	s.data["stored"] = 7
+	m := New[string, int](WithWriteThrough[string, int](s), WithMaxEntries(2))
+
+	m.Set(&mu, "a", 1)
+	m.Set(&mu, "b", 2)
+	m.Set(&mu, "c", 3) // evicts a from the map only
+	m.Delete(&mu, "b")
+	if got, want := s.contents(), map[string]int{"stored": 7, "a": 1, "c": 3}; !maps.Equal(got, want) {
+		t.Errorf("store = %v, want %v", got, want)
+	}
+
+	// Missing keys are loaded from the store without being written back.
+	writes := s.writes
+	if v, ok := m.Get(&mu, "a"); v != 1 || !ok {
+		t.Errorf("Get(a) = %d, %v, want the stored 1", v, ok)
+	}
+	if s.writes != writes {
+		t.Error("a loaded value was written back")
+	}
+	m.Clear(&mu)
+	if len(s.contents()) != 3 {
+		t.Error("Clear was written through")
+	}
+
+	errFail := errors.New("unavailable")
+	s.setFail(errFail)
+	m.Set(&mu, "d", 4)
+	s.setFail(nil)
+	m.Set(&mu, "e", 5)
+	if err := m.FlushWrites(context.Background()); !errors.Is(err, errFail) {
+		t.Errorf("FlushWrites error = %v, want %v", err, errFail)
+	}
+	if err := m.FlushWrites(context.Background()); err != nil {
+		t.Errorf("second FlushWrites error = %v, want nil", err)
+	}
+	if err := New[string, int]().FlushWrites(context.Background()); err != ErrNoStore {
+		t.Errorf("FlushWrites error = %v without a store, want ErrNoStore", err)
+	}
+}
+
+func TestWithWriteBehind(t *testing.T) {
+	mu := sync.RWMutex{}
+	s := newMemStore()
+	s.data["old"] = 1
+	m := New[string, int](WithWriteBehind[string, int](s, 0))
+
+	m.Set(&mu, "a", 1)
+	m.Set(&mu, "a", 2)
+	m.Set(&mu, "b", 3)
+	m.Delete(&mu, "old")
+	if len(s.contents()) != 1 {
+		t.Fatal("writes were applied before flushing")
+	}
+
+	// Pending writes are seen by loads.
+	m.Clear(&mu)
+	if v, _ := m.Get(&mu, "a"); v != 2 {
+		t.Errorf("Get(a) = %d, want the pending 2", v)
+	}
+	if _, err := m.GetContext(context.Background(), &mu, "old"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetContext(old) error = %v, want the pending deletion", err)
+	}
+
+	// Failed writes stay queued, unless written again.
+	errFail := errors.New("unavailable")
+	s.setFail(errFail)
+	if err := m.FlushWrites(context.Background()); !errors.Is(err, errFail) {
+		t.Errorf("FlushWrites error = %v, want %v", err, errFail)
+	}
+	s.setFail(nil)
+	m.Set(&mu, "b", 4)
+	if err := m.FlushWrites(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.contents(), map[string]int{"a": 2, "b": 4}; !maps.Equal(got, want) {
+		t.Errorf("store = %v, want %v", got, want)
+	}
+	if s.writes != 3 {
+		t.Errorf("%d writes, want 3 coalesced ones", s.writes)
+	}
+
+	m.Set(&mu, "c", 5)
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	m.Set(&mu, "d", 6)
+	if got := s.contents(); got["c"] != 5 || len(got) != 3 {
+		t.Errorf("store = %v after Close, want c flushed and d dropped", got)
+	}
+}
+
+func TestWriteBehindBatch(t *testing.T) {
+	mu := sync.RWMutex{}
+	clock := NewManualClock(time.Unix(0, 0))
+	s := batchStore{newMemStore()}
+	m := New[string, int](WithWriteBehind[string, int](s, time.Second), WithClock(clock))
+	defer m.Close()
+
+	m.Set(&mu, "a", 1)
+	m.Set(&mu, "b", 2)
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	clock.BlockUntil(1) // the flush is done when the timer is set again
+	if got := s.contents(); len(got) != 2 || s.batches != 1 || s.writes != 0 {
+		t.Errorf("store = %v after %d batches and %d writes, want one batch", got, s.batches, s.writes)
+	}
+}
@@ -0,0 +1,233 @@
+package valuemap
+
+import (
+	"hash/maphash"
+	"iter"
+	"maps"
+	"sync"
+	"sync/atomic"
+)
+
+// WithShards stripes the map over n shards selected by key hash, each with
+// a lock of its own, so that goroutines working on keys in different shards
+// do not contend, without changing how the map is called.
+//
+// The methods on a single key, such as Get, Set, Delete, GetOrSet, Update
+// and the Compute and CompareAnd methods, hold mu only for reading and lock
+// the shard of their key. All the other methods, including reads of the
+// whole map such as Len and Keys, hold mu for writing, so they still see
+// and change the map atomically, and holding mu for writing still excludes
+// every other method of the map. Striping adds a little work to every call,
+// so it pays off for maps used by many goroutines at once, mostly through
+// methods on a single key.
+//
+// Striping only applies to maps that keep no state besides their entries.
+// Maps that expire or bound entries, keep revisions, history, tombstones or
+// indexes, or use journals, stores, loaders, hot key tracking, auditing or
+// eviction callbacks hold mu for writing in every method, as they do
+// without WithShards. Clones of a striped map are not striped. An n of one
+// or less leaves the map unstriped.
+func WithShards(n int) Option {
+	return optionFunc(func(c *config) {
+		c.shards = max(n, 0)
+	})
+}
+
+// stripes holds the entries of a map created with WithShards.
+type stripes[K comparable, V any] struct {
+	seed  maphash.Seed
+	parts []stripe[K, V]
+	mu    atomic.Pointer[sync.RWMutex] // the external mutex of the map
+}
+
+// stripe is one of the independently locked parts of a striped map.
+type stripe[K comparable, V any] struct {
+	mu       sync.RWMutex
+	data     map[K]V
+	reserved int // number of entries data was allocated for
+	parent   *stripes[K, V]
+}
+
+// stripeWriter and stripeReader unlock a stripe locked by lockKey and
+// rlockKey respectively, then the external mutex of the map.
+type (
+	stripeWriter[K comparable, V any] stripe[K, V]
+	stripeReader[K comparable, V any] stripe[K, V]
+)
+
+func (s *stripeWriter[K, V]) Lock()   { s.parent.mu.Load().RLock(); s.mu.Lock() }
+func (s *stripeWriter[K, V]) Unlock() { s.mu.Unlock(); s.parent.mu.Load().RUnlock() }
+func (s *stripeReader[K, V]) Lock()   { s.parent.mu.Load().RLock(); s.mu.RLock() }
+func (s *stripeReader[K, V]) Unlock() { s.mu.RUnlock(); s.parent.mu.Load().RUnlock() }
+
+// spread spreads the entries of the map over n stripes with room for
+// capacity entries in total. It is called once, before the map is used.
+func (m *ValueMap[K, V]) spread(n, capacity int) {
+	s := &stripes[K, V]{seed: maphash.MakeSeed(), parts: make([]stripe[K, V], n)}
+	for i := range s.parts {
+		s.parts[i].data = make(map[K]V, capacity/n)
+		s.parts[i].reserved = capacity / n
+		s.parts[i].parent = s
+	}
+	for k, v := range m.data {
+		s.of(k).data[k] = v
+	}
+	m.stripes = s
+	m.data = nil
+}
+
+// of returns the stripe holding key.
+func (s *stripes[K, V]) of(key K) *stripe[K, V] {
+	return &s.parts[maphash.Comparable(s.seed, key)%uint64(len(s.parts))]
+}
+
+// grow makes room for n entries in total, spread evenly over the stripes.
+func (s *stripes[K, V]) grow(n int) {
+	n /= len(s.parts)
+	for i := range s.parts {
+		p := &s.parts[i]
+		if n <= len(p.data) || n <= p.reserved {
+			continue
+		}
+		data := make(map[K]V, n)
+		maps.Copy(data, p.data)
+		p.data = data
+		p.reserved = n
+	}
+}
+
+// compact reallocates every stripe at the size of its contents.
+func (s *stripes[K, V]) compact() {
+	for i := range s.parts {
+		p := &s.parts[i]
+		p.data = shrink(p.data)
+		p.reserved = len(p.data)
+	}
+}
+
+// lockKey locks the map for a write to key. A striped map is locked for
+// reading and the stripe of key for writing, unless the map keeps state
+// that the write would update, in which case it is locked for writing.
+func (m *ValueMap[K, V]) lockKey(mu *sync.RWMutex, key K) sync.Locker {
+	s := m.lockStripes(mu)
+	if s == nil {
+		return m.lock(mu)
+	}
+	st := s.of(key)
+	st.mu.Lock()
+	return (*stripeWriter[K, V])(st)
+}
+
+// rlockKey is lockKey for a read of key, locking its stripe for reading.
+func (m *ValueMap[K, V]) rlockKey(mu *sync.RWMutex, key K) sync.Locker {
+	s := m.lockStripes(mu)
+	if s == nil {
+		return m.rlock(mu)
+	}
+	st := s.of(key)
+	st.mu.RLock()
+	return (*stripeReader[K, V])(st)
+}
+
+// lockStripes locks mu for reading and returns the stripes of the map if
+// a single key can be locked by its stripe. Otherwise it returns nil with
+// mu unlocked.
+func (m *ValueMap[K, V]) lockStripes(mu *sync.RWMutex) *stripes[K, V] {
+	s := m.stripes
+	if s == nil {
+		return nil
+	}
+	if p := s.mu.Load(); p != mu && (p != nil || !s.mu.CompareAndSwap(nil, mu)) {
+		// The stripes are unlocked with the mutex they were first used with.
+		return nil
+	}
+	m.lockShared(mu)
+	if !m.plain() {
+		mu.RUnlock()
+		return nil
+	}
+	return s
+}
+
+// plain reports whether the map keeps no state besides its entries that
+// writes to a single key would update, so that they can run in parallel.
+// The caller must hold mu at least for reading.
+func (m *ValueMap[K, V]) plain() bool {
+	return m.ttl == nil && m.bound == nil && m.onEvict == nil && m.journal == nil &&
+		m.writer == nil && m.loader == nil && m.revs == nil && m.hist == nil &&
+		m.tombs == nil && m.reverse == nil && len(m.indexes) == 0 && m.hot == nil &&
+		m.audit == nil && !m.trackReads
+}
+
+// The entries of a map are accessed through the methods below, which use
+// either data or the stripes. The caller must hold the lock of the key or
+// of the whole map, as for the methods that call them.
+
+// stored returns the value of a key, whether it is expired or not.
+func (m *ValueMap[K, V]) stored(key K) (V, bool) {
+	if m.stripes != nil {
+		v, ok := m.stripes.of(key).data[key]
+		return v, ok
+	}
+	v, ok := m.data[key]
+	return v, ok
+}
+
+// assign sets the value of a key.
+func (m *ValueMap[K, V]) assign(key K, value V) {
+	if m.stripes != nil {
+		m.stripes.of(key).data[key] = value
+		return
+	}
+	m.data[key] = value
+}
+
+// unset deletes a key.
+func (m *ValueMap[K, V]) unset(key K) {
+	if m.stripes != nil {
+		delete(m.stripes.of(key).data, key)
+		return
+	}
+	delete(m.data, key)
+}
+
+// size returns the number of entries, expired or not.
+func (m *ValueMap[K, V]) size() int {
+	if m.stripes == nil {
+		return len(m.data)
+	}
+	n := 0
+	for i := range m.stripes.parts {
+		n += len(m.stripes.parts[i].data)
+	}
+	return n
+}
+
+// each returns an iterator over the entries, expired or not. Entries can
+// be deleted during the iteration.
+func (m *ValueMap[K, V]) each() iter.Seq2[K, V] {
+	if m.stripes == nil {
+		return maps.All(m.data)
+	}
+	return func(yield func(K, V) bool) {
+		for i := range m.stripes.parts {
+			for k, v := range m.stripes.parts[i].data {
+				if !yield(k, v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// wipe deletes all the entries.
+func (m *ValueMap[K, V]) wipe() {
+	if m.stripes != nil {
+		for i := range m.stripes.parts {
+			m.stripes.parts[i].data = make(map[K]V)
+			m.stripes.parts[i].reserved = 0
+		}
+		return
+	}
+	m.data = make(map[K]V)
+}
@@ -0,0 +1,153 @@
+package valuemap
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithShards(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := New[int, int](WithShards(8), WithCapacity(100))
+	if m.stripes == nil || len(m.stripes.parts) != 8 {
+		t.Fatal("map is not striped over 8 shards")
+	}
+
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 100 {
+				k := g*100 + i
+				m.Set(&mu, k, i)
+				m.Update(&mu, k, func(v int, ok bool) (int, bool) { return v + 1, ok })
+				if v, ok := m.Get(&mu, k); !ok || v != i+1 {
+					t.Errorf("Get(%d) = %d, %v, want %d, true", k, v, ok, i+1)
+				}
+				if i%2 == 0 {
+					m.Delete(&mu, k)
+				}
+				if i%10 == 0 {
+					m.Len(&mu)
+					m.Keys(&mu)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := m.Len(&mu); n != 400 {
+		t.Errorf("Len() = %d, want 400", n)
+	}
+	if c := m.Clone(&mu); c.Len(&mu) != 400 || c.stripes != nil {
+		t.Error("Clone() is not an unstriped copy")
+	}
+	m.Compact(&mu)
+	m.Clear(&mu)
+	if n := m.Len(&mu); n != 0 {
+		t.Errorf("Len() = %d after Clear, want 0", n)
+	}
+}
+
+func TestWithShardsParallel(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := New[int, int](WithShards(2))
+	a, b := 0, 1
+	for m.stripes.of(a) == m.stripes.of(b) {
+		b++
+	}
+
+	// A write to b proceeds while an update of a holds its shard.
+	started, release := make(chan struct{}), make(chan struct{})
+	go m.Update(&mu, a, func(int, bool) (int, bool) {
+		close(started)
+		<-release
+		return 1, true
+	})
+	<-started
+	done := make(chan struct{})
+	go func() {
+		m.Set(&mu, b, 2)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Set on another shard waited for Update")
+	}
+	close(release)
+
+	// Holding mu for writing excludes the methods on a single key.
+	mu.Lock()
+	done = make(chan struct{})
+	go func() {
+		m.Set(&mu, a, 3)
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("Set ran while mu was held")
+	case <-time.After(10 * time.Millisecond):
+	}
+	mu.Unlock()
+	<-done
+	if v, _ := m.Get(&mu, a); v != 3 {
+		t.Errorf("Get(a) = %d, want 3", v)
+	}
+}
+
+func TestWithShardsState(t *testing.T) {
+	mu := sync.RWMutex{}
+	clock := NewManualClock(time.Unix(0, 0))
+	m := New[string, int](WithShards(4), WithTTL(&mu, time.Second, 0), WithClock(clock))
+	m.Set(&mu, "a", 1)
+	if v, ok := m.Get(&mu, "a"); !ok || v != 1 {
+		t.Errorf("Get(a) = %d, %v, want 1, true", v, ok)
+	}
+	clock.Advance(2 * time.Second)
+	if _, ok := m.Get(&mu, "a"); ok {
+		t.Error("expired entry of a striped map is visible")
+	}
+
+	p := New[string, int](WithShards(4))
+	p.Set(&mu, "a", 1)
+	p.SoftDelete(&mu, "a")
+	p.Set(&mu, "b", 2)
+	if !p.Restore(&mu, "a") || p.Len(&mu) != 2 {
+		t.Error("Restore() did not bring back a in a striped map")
+	}
+}
+
+func TestWithShardsDoesNotAllocate(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := New[int, int](WithShards(4))
+	m.Set(&mu, 1, 1)
+	for name, fn := range map[string]func(){
+		"Set":    func() { m.Set(&mu, 1, 1) },
+		"Get":    func() { m.Get(&mu, 1) },
+		"Delete": func() { m.Delete(&mu, 2) },
+	} {
+		if n := testing.AllocsPerRun(100, fn); n != 0 {
+			t.Errorf("%s allocates %v times per call, want 0", name, n)
+		}
+	}
+}
+
+func BenchmarkWithShards(b *testing.B) {
+	for _, shards := range []int{1, 64} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			mu := sync.RWMutex{}
+			m := New[int, int](WithShards(shards))
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					m.Set(&mu, i%1024, i)
+					m.Get(&mu, (i+512)%1024)
+					i++
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package valuemap
+
+import (
+	"sync"
+	"time"
+)
+
+// tombstone is the value of a soft-deleted key and when it was deleted.
+type tombstone[V any] struct {
+	value V
+	at    time.Time
+}
+
+// tombstones holds the soft-deleted keys of a map.
+type tombstones[K comparable, V any] struct {
+	retention time.Duration
+	byKey     map[K]tombstone[V]
+	purged    time.Time // when the expired tombstones were last purged
+}
+
+// WithTombstoneRetention makes the tombstones left by SoftDelete expire
+// after retention, so that they can no longer be restored. Expired
+// tombstones are purged by SoftDelete, at most once per retention, so that
+// they do not pile up, as well as by PurgeTombstones and the janitor of a
+// map created with WithTTL. Without it, or with a retention of zero or
+// less, tombstones are kept until the key is assigned again or the map is
+// cleared.
+func WithTombstoneRetention(retention time.Duration) Option {
+	return optionFunc(func(c *config) {
+		c.tombstoneRetention = retention
+	})
+}
+
+// graves returns the tombstones of the map, creating them on first use.
+// The caller must hold the write lock.
+func (m *ValueMap[K, V]) graves() *tombstones[K, V] {
+	if m.tombs == nil {
+		m.tombs = &tombstones[K, V]{byKey: make(map[K]tombstone[V])}
+	}
+	return m.tombs
+}
+
+// expired reports whether a tombstone is past its retention.
+func (t *tombstones[K, V]) expired(ts tombstone[V], now time.Time) bool {
+	return t.retention > 0 && !now.Before(ts.at.Add(t.retention))
+}
+
+// SoftDelete removes a key like Delete, but leaves a tombstone holding its
+// value, so that Restore can bring it back until the tombstone is purged.
+// Get and the other methods see the key as missing meanwhile. Assigning
+// the key removes its tombstone. It reports whether the key was present.
+//
+// mu is an external mutex to lock the internal map during key deletion
+func (m *ValueMap[K, V]) SoftDelete(mu *sync.RWMutex, key K) bool {
+	defer m.lock(mu).Unlock()
+	v, ok := m.lookup(key)
+	if !ok {
+		return false
+	}
+	m.remove(key)
+	now := m.now()
+	graves := m.graves()
+	if graves.retention > 0 && !now.Before(graves.purged.Add(graves.retention)) {
+		m.purgeTombstones()
+	}
+	graves.byKey[key] = tombstone[V]{value: v, at: now}
+	return true
+}
+
+// Restore assigns a soft-deleted key the value it had when it was deleted,
+// and removes its tombstone. It reports whether the key had a tombstone
+// within its retention.
+//
+// mu is an external mutex to lock the internal map during value assigning
+func (m *ValueMap[K, V]) Restore(mu *sync.RWMutex, key K) bool {
+	defer m.lock(mu).Unlock()
+	if m.tombs == nil {
+		return false
+	}
+	ts, ok := m.tombs.byKey[key]
+	if !ok {
+		return false
+	}
+	delete(m.tombs.byKey, key)
+	if m.tombs.expired(ts, m.now()) {
+		return false
+	}
+	m.store(key, ts.value)
+	return true
+}
+
+// PurgeTombstones removes the tombstones past their retention and returns
+// how many were removed.
+//
+// mu is an external mutex to lock the internal map during the purge
+func (m *ValueMap[K, V]) PurgeTombstones(mu *sync.RWMutex) int {
+	defer m.lock(mu).Unlock()
+	return m.purgeTombstones()
+}
+
+// purgeTombstones removes the tombstones past their retention.
+// The caller must hold the write lock.
+func (m *ValueMap[K, V]) purgeTombstones() int {
+	if m.tombs == nil || m.tombs.retention <= 0 {
+		return 0
+	}
+	now := m.now()
+	m.tombs.purged = now
+	n := 0
+	for k, ts := range m.tombs.byKey {
+		if m.tombs.expired(ts, now) {
+			delete(m.tombs.byKey, k)
+			n++
+		}
+	}
+	return n
+}
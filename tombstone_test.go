@@ -0,0 +1,64 @@
+package valuemap
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSoftDelete(t *testing.T) {
+	mu := sync.RWMutex{}
+	clock := NewManualClock(time.Unix(0, 0))
+	m := New[string, int](WithClock(clock), WithTombstoneRetention(time.Minute))
+	m.Set(&mu, "a", 1)
+	m.Set(&mu, "b", 2)
+
+	if !m.SoftDelete(&mu, "a") || m.SoftDelete(&mu, "missing") {
+		t.Fatal("SoftDelete reported the wrong keys")
+	}
+	if _, ok := m.Get(&mu, "a"); ok || m.Len(&mu) != 1 {
+		t.Error("a soft-deleted key is still visible")
+	}
+	if !m.Restore(&mu, "a") {
+		t.Fatal("Restore(a) = false")
+	}
+	if v, ok := m.Get(&mu, "a"); v != 1 || !ok {
+		t.Errorf("Get(a) = %d, %v after Restore, want 1", v, ok)
+	}
+	if m.Restore(&mu, "a") {
+		t.Error("Restore(a) = true twice")
+	}
+
+	// Assigning a key drops its tombstone.
+	m.SoftDelete(&mu, "a")
+	m.Set(&mu, "a", 3)
+	m.Delete(&mu, "a")
+	if m.Restore(&mu, "a") {
+		t.Error("Restore(a) = true after an assignment")
+	}
+
+	// Tombstones past their retention are purged.
+	m.SoftDelete(&mu, "b")
+	clock.Advance(time.Minute)
+	if n := m.PurgeTombstones(&mu); n != 1 {
+		t.Errorf("PurgeTombstones = %d, want 1", n)
+	}
+	if m.Restore(&mu, "b") {
+		t.Error("Restore(b) = true after its retention")
+	}
+}
+
+func TestSoftDeletePurges(t *testing.T) {
+	mu := sync.RWMutex{}
+	clock := NewManualClock(time.Unix(0, 0))
+	m := New[int, int](WithClock(clock), WithTombstoneRetention(time.Minute))
+	for i := range 300 {
+		m.Set(&mu, i, i)
+		m.SoftDelete(&mu, i)
+		clock.Advance(time.Second)
+	}
+	// Purging once per retention leaves at most two retentions of tombstones.
+	if n := len(m.tombs.byKey); n > 120 {
+		t.Errorf("%d tombstones left, want at most the 120 of the last two minutes", n)
+	}
+}
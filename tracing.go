@@ -0,0 +1,20 @@
+package valuemap
+
+// Tracer observes the operations on a map, for example to record their
+// latency and hit rate as metrics or trace spans.
+type Tracer interface {
+	// Start is called when Get, Set or Delete begins, with the name of the
+	// method, and returns a function that is called when it ends, after the
+	// external mutex is unlocked. found reports whether the key was present:
+	// for Get, whether it was found, and for Set and Delete, whether it was
+	// present before.
+	Start(op string) (end func(found bool))
+}
+
+// WithTracing makes the map report its Get, Set and Delete operations to t.
+// Maps without a Tracer only check for one, so the calls cost nothing more.
+func WithTracing(t Tracer) Option {
+	return optionFunc(func(c *config) {
+		c.tracer = t
+	})
+}
@@ -0,0 +1,33 @@
+package valuemap
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+type recorder struct{ ops []string }
+
+func (r *recorder) Start(op string) func(found bool) {
+	return func(found bool) {
+		r.ops = append(r.ops, fmt.Sprintf("%s %v", op, found))
+	}
+}
+
+func TestWithTracing(t *testing.T) {
+	mu := sync.RWMutex{}
+	r := &recorder{}
+	m := NewBounded[string, int](0, WithTracing(r))
+
+	m.Set(&mu, "a", 1)
+	m.Set(&mu, "a", 2)
+	m.Get(&mu, "a")
+	m.Get(&mu, "b")
+	m.Delete(&mu, "a")
+	m.Delete(&mu, "a")
+
+	want := []string{"Set false", "Set true", "Get true", "Get false", "Delete true", "Delete false"}
+	if fmt.Sprint(r.ops) != fmt.Sprint(want) {
+		t.Errorf("traced %q, want %q", r.ops, want)
+	}
+}
@@ -0,0 +1,233 @@
+package valuemap
+
+import (
+	"sync"
+	"time"
+)
+
+// expiry holds the expiration state of a ValueMap created with NewWithTTL.
+type expiry[K comparable] struct {
+	ttl       time.Duration
+	deadlines map[K]time.Time
+	lifetimes map[K]time.Duration // keys whose lifetime differs from ttl
+	sliding   bool                // reads restart the lifetime
+	stale     time.Duration       // how long expired entries are kept to be served stale
+	refresh   float64             // fraction of the lifetime after which reads reload
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newExpiry[K comparable](ttl time.Duration) *expiry[K] {
+	return &expiry[K]{
+		ttl:       ttl,
+		deadlines: make(map[K]time.Time),
+		lifetimes: make(map[K]time.Duration),
+		done:      make(chan struct{}),
+	}
+}
+
+// set gives a key a lifetime of ttl starting from now.
+// A ttl of zero or less means the key does not expire.
+func (e *expiry[K]) set(key K, ttl time.Duration, now time.Time) {
+	if ttl == e.ttl {
+		delete(e.lifetimes, key)
+	} else {
+		e.lifetimes[key] = ttl
+	}
+	if ttl <= 0 {
+		delete(e.deadlines, key)
+		return
+	}
+	e.deadlines[key] = now.Add(ttl)
+}
+
+// touch restarts the lifetime of a key from now.
+func (e *expiry[K]) touch(key K, now time.Time) {
+	ttl, ok := e.lifetimes[key]
+	if !ok {
+		ttl = e.ttl
+	}
+	e.set(key, ttl, now)
+}
+
+// forget drops the expiration state of a key.
+func (e *expiry[K]) forget(key K) {
+	delete(e.deadlines, key)
+	delete(e.lifetimes, key)
+}
+
+// expired reports whether the deadline of a key has passed.
+func (e *expiry[K]) expired(key K, now time.Time) bool {
+	d, ok := e.deadlines[key]
+	return ok && !now.Before(d)
+}
+
+// due reports whether a key is old enough to be refreshed ahead of its
+// expiration.
+func (e *expiry[K]) due(key K, now time.Time) bool {
+	d, ok := e.deadlines[key]
+	if !ok {
+		return false
+	}
+	ttl, ok := e.lifetimes[key]
+	if !ok {
+		ttl = e.ttl
+	}
+	return ttl-d.Sub(now) >= time.Duration(float64(ttl)*e.refresh)
+}
+
+// removable reports whether an expired key can no longer be served stale.
+func (e *expiry[K]) removable(key K, now time.Time) bool {
+	d, ok := e.deadlines[key]
+	return ok && !now.Before(d.Add(e.stale))
+}
+
+// NewWithTTL returns a new pointer to a ValueMap whose entries expire
+// defaultTTL after they were last assigned. A defaultTTL of zero or less
+// means entries do not expire.
+//
+// Expired entries are never returned, and a background janitor removes them
+// every cleanupInterval. A cleanupInterval of zero or less disables the janitor,
+// in which case DeleteExpired should be called periodically. Call Close to
+// stop the janitor when the map is no longer needed.
+//
+// mu is an external mutex to lock the internal map during expired entry cleanup.
+// It must be the same mutex passed to the other methods
+func NewWithTTL[K comparable, V any](mu *sync.RWMutex, defaultTTL, cleanupInterval time.Duration, opts ...Option) *ValueMap[K, V] {
+	return New[K, V](append([]Option{WithTTL(mu, defaultTTL, cleanupInterval)}, opts...)...)
+}
+
+// NewWithSlidingTTL is like NewWithTTL, but reading an entry with Get,
+// GetMany, GetOrSet or ComputeIfAbsent also restarts its lifetime, so entries
+// expire after defaultTTL without being accessed. Reads lock mu for writing.
+//
+// mu is an external mutex to lock the internal map during expired entry cleanup.
+// It must be the same mutex passed to the other methods
+func NewWithSlidingTTL[K comparable, V any](mu *sync.RWMutex, defaultTTL, cleanupInterval time.Duration, opts ...Option) *ValueMap[K, V] {
+	return New[K, V](append([]Option{WithSlidingTTL(mu, defaultTTL, cleanupInterval)}, opts...)...)
+}
+
+// WithTTL makes entries expire defaultTTL after they were last assigned,
+// with a janitor removing them every cleanupInterval, as NewWithTTL does.
+//
+// mu is an external mutex to lock the internal map during expired entry cleanup.
+// It must be the same mutex passed to the other methods
+func WithTTL(mu *sync.RWMutex, defaultTTL, cleanupInterval time.Duration) Option {
+	return optionFunc(func(c *config) {
+		c.ttlMu = mu
+		c.ttl = defaultTTL
+		c.cleanupInterval = cleanupInterval
+		c.sliding = false
+	})
+}
+
+// WithSlidingTTL is like WithTTL, but reads restart the lifetime of
+// entries, as NewWithSlidingTTL does.
+//
+// mu is an external mutex to lock the internal map during expired entry cleanup.
+// It must be the same mutex passed to the other methods
+func WithSlidingTTL(mu *sync.RWMutex, defaultTTL, cleanupInterval time.Duration) Option {
+	return optionFunc(func(c *config) {
+		c.ttlMu = mu
+		c.ttl = defaultTTL
+		c.cleanupInterval = cleanupInterval
+		c.sliding = true
+	})
+}
+
+func (m *ValueMap[K, V]) janitor(mu *sync.RWMutex, interval time.Duration) {
+	m.every(interval, m.ttl.done, func() { m.DeleteExpired(mu) })
+}
+
+// DeleteExpired removes all expired entries and returns how many were removed.
+// For a map created with WithStaleWhileRevalidate, entries are kept until
+// they can no longer be served stale, and for a map created with
+// WithNegativeCache, the cached failures whose TTL has passed are dropped.
+// Tombstones past their retention are purged too.
+//
+// mu is an external mutex to lock the internal map during expired entry cleanup
+func (m *ValueMap[K, V]) DeleteExpired(mu *sync.RWMutex) int {
+	defer m.lock(mu).Unlock()
+	m.forgetFailures(false)
+	m.purgeTombstones()
+	if m.ttl == nil {
+		return 0
+	}
+	now := m.now()
+	n := 0
+	for k := range m.ttl.deadlines {
+		if m.ttl.removable(k, now) {
+			m.removeFor(k, ReasonExpired)
+			n++
+		}
+	}
+	return n
+}
+
+// SetWithTTL assigns a value to a key that expires after ttl,
+// regardless of the default TTL of the map. A ttl of zero or less
+// means the entry does not expire.
+//
+// Maps not created with NewWithTTL have no janitor, so their expired
+// entries are only removed by DeleteExpired.
+//
+// mu is an external mutex to lock the internal map during value assigning
+func (m *ValueMap[K, V]) SetWithTTL(mu *sync.RWMutex, key K, value V, ttl time.Duration) {
+	defer m.lock(mu).Unlock()
+	if m.ttl == nil {
+		m.ttl = newExpiry[K](0)
+	}
+	m.store(key, value)
+	m.ttl.set(key, ttl, m.now())
+}
+
+// Touch restarts the lifetime of an entry without changing its value.
+// It reports whether the key was present.
+//
+// mu is an external mutex to lock the internal map during the lifetime reset
+func (m *ValueMap[K, V]) Touch(mu *sync.RWMutex, key K) bool {
+	defer m.lock(mu).Unlock()
+	if _, ok := m.lookup(key); !ok {
+		return false
+	}
+	if m.ttl != nil {
+		m.ttl.touch(key, m.now())
+	}
+	return true
+}
+
+// Expire changes the lifetime of an entry to ttl, starting from now.
+// A ttl of zero or less makes the entry permanent.
+// It reports whether the key was present.
+//
+// mu is an external mutex to lock the internal map during the lifetime change
+func (m *ValueMap[K, V]) Expire(mu *sync.RWMutex, key K, ttl time.Duration) bool {
+	defer m.lock(mu).Unlock()
+	if _, ok := m.lookup(key); !ok {
+		return false
+	}
+	if m.ttl == nil {
+		m.ttl = newExpiry[K](0)
+	}
+	m.ttl.set(key, ttl, m.now())
+	return true
+}
+
+// TTL returns the remaining lifetime of an entry. The ok result is false if
+// the key is not present, and the duration is zero if the entry does not expire.
+//
+// mu is an external mutex to lock the internal map during lifetime retrieval
+func (m *ValueMap[K, V]) TTL(mu *sync.RWMutex, key K) (remaining time.Duration, ok bool) {
+	defer m.readLock(mu).Unlock()
+	if _, ok := m.lookup(key); !ok {
+		return 0, false
+	}
+	if m.ttl == nil {
+		return 0, true
+	}
+	d, ok := m.ttl.deadlines[key]
+	if !ok {
+		return 0, true
+	}
+	return d.Sub(m.now()), true
+}
@@ -0,0 +1,110 @@
+package valuemap
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTTL(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := NewWithTTL[string, int](&mu, 20*time.Millisecond, 0)
+	defer m.Close()
+
+	m.Set(&mu, "a", 1)
+	if _, ok := m.Get(&mu, "a"); !ok {
+		t.Fatal("entry expired too early")
+	}
+	time.Sleep(30 * time.Millisecond)
+	m.Set(&mu, "b", 2)
+
+	if _, ok := m.Get(&mu, "a"); ok {
+		t.Error("Get returned an expired entry")
+	}
+	if n := m.Len(&mu); n != 1 {
+		t.Errorf("Len = %d, want 1", n)
+	}
+	if !m.SetIfAbsent(&mu, "a", 3) {
+		t.Error("SetIfAbsent treated an expired entry as present")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if n := m.DeleteExpired(&mu); n != 2 {
+		t.Errorf("DeleteExpired = %d, want 2", n)
+	}
+}
+
+func TestTTLJanitor(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := NewWithTTL[string, int](&mu, 10*time.Millisecond, 5*time.Millisecond)
+	defer m.Close()
+
+	m.Set(&mu, "a", 1)
+	time.Sleep(50 * time.Millisecond)
+
+	mu.RLock()
+	n := len(m.data)
+	mu.RUnlock()
+	if n != 0 {
+		t.Errorf("janitor left %d entries", n)
+	}
+}
+
+func TestPerKeyTTL(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := New[string, string]()
+
+	m.SetWithTTL(&mu, "token", "t", 20*time.Millisecond)
+	m.SetWithTTL(&mu, "session", "s", time.Hour)
+	m.Set(&mu, "config", "c")
+
+	if d, ok := m.TTL(&mu, "session"); !ok || d <= 0 || d > time.Hour {
+		t.Errorf("TTL(session) = %v, %v", d, ok)
+	}
+	if d, ok := m.TTL(&mu, "config"); !ok || d != 0 {
+		t.Errorf("TTL(config) = %v, %v; want 0, true", d, ok)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if !m.Touch(&mu, "token") {
+		t.Fatal("Touch(token) = false")
+	}
+	time.Sleep(15 * time.Millisecond)
+	if _, ok := m.Get(&mu, "token"); !ok {
+		t.Error("token expired despite Touch")
+	}
+
+	if !m.Expire(&mu, "session", time.Millisecond) {
+		t.Fatal("Expire(session) = false")
+	}
+	time.Sleep(25 * time.Millisecond)
+	if _, ok := m.Get(&mu, "session"); ok {
+		t.Error("session did not expire after Expire")
+	}
+	if _, ok := m.Get(&mu, "token"); ok {
+		t.Error("token did not expire")
+	}
+	if _, ok := m.Get(&mu, "config"); !ok {
+		t.Error("config expired")
+	}
+	if m.Touch(&mu, "token") {
+		t.Error("Touch revived an expired entry")
+	}
+}
+
+func TestSlidingTTL(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := NewWithSlidingTTL[string, int](&mu, 30*time.Millisecond, 0)
+	defer m.Close()
+
+	m.Set(&mu, "read", 1)
+	m.Set(&mu, "idle", 2)
+	for range 4 {
+		time.Sleep(10 * time.Millisecond)
+		if _, ok := m.Get(&mu, "read"); !ok {
+			t.Fatal("entry expired despite being read")
+		}
+	}
+	if _, ok := m.Get(&mu, "idle"); ok {
+		t.Error("idle entry did not expire")
+	}
+}
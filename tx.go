@@ -0,0 +1,122 @@
+package valuemap
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrTxnAborted is returned by Transact when the callback calls Txn.Abort.
+var ErrTxnAborted = errors.New("valuemap: transaction aborted")
+
+// Tx gives unlocked access to a ValueMap inside a WithLock callback.
+// It must not be retained after the callback returns.
+type Tx[K comparable, V any] struct {
+	m *ValueMap[K, V]
+}
+
+// WithLock calls fn with the map locked for writing, so that several
+// operations on the Tx execute atomically.
+//
+// mu is an external mutex to lock the internal map during the callback,
+// so fn must not call methods that lock mu
+func (m *ValueMap[K, V]) WithLock(mu *sync.RWMutex, fn func(tx *Tx[K, V])) {
+	defer m.lock(mu).Unlock()
+	fn(&Tx[K, V]{m: m})
+}
+
+// Get retrieves a value and a boolean indicating if the key exists.
+func (tx *Tx[K, V]) Get(key K) (V, bool) {
+	return tx.m.lookup(key)
+}
+
+// Set assigns a value to a key.
+func (tx *Tx[K, V]) Set(key K, value V) {
+	tx.m.store(key, value)
+}
+
+// Delete removes a key from the map.
+func (tx *Tx[K, V]) Delete(key K) {
+	tx.m.remove(key)
+}
+
+// Len returns the number of key-value pairs.
+func (tx *Tx[K, V]) Len() int {
+	return tx.m.count()
+}
+
+// Range calls fn sequentially for each key and value present in the map.
+// If fn returns false, Range stops the iteration.
+func (tx *Tx[K, V]) Range(fn func(key K, value V) bool) {
+	for k, v := range tx.m.live() {
+		if !fn(k, v) {
+			return
+		}
+	}
+}
+
+// Txn buffers writes to a ValueMap inside a Transact callback.
+// Buffered writes are visible to the Txn's own reads, but not to other
+// readers until the transaction commits. It must not be retained after
+// the callback returns.
+type Txn[K comparable, V any] struct {
+	m       *ValueMap[K, V]
+	pending map[K]txnWrite[V]
+	aborted bool
+}
+
+type txnWrite[V any] struct {
+	value   V
+	deleted bool
+}
+
+// Transact calls fn with a Txn that buffers writes. The writes are applied
+// atomically when fn returns nil. They are discarded when fn returns an
+// error, which Transact returns, or when fn calls Abort, in which case
+// Transact returns ErrTxnAborted.
+//
+// mu is an external mutex to lock the internal map during the transaction,
+// so fn must not call methods that lock mu
+func (m *ValueMap[K, V]) Transact(mu *sync.RWMutex, fn func(txn *Txn[K, V]) error) error {
+	defer m.lock(mu).Unlock()
+	txn := &Txn[K, V]{m: m, pending: make(map[K]txnWrite[V])}
+	if err := fn(txn); err != nil {
+		return err
+	}
+	if txn.aborted {
+		return ErrTxnAborted
+	}
+	for k, w := range txn.pending {
+		if w.deleted {
+			m.remove(k)
+			continue
+		}
+		m.store(k, w.value)
+	}
+	return nil
+}
+
+// Get retrieves a value and a boolean indicating if the key exists,
+// taking the transaction's buffered writes into account.
+func (txn *Txn[K, V]) Get(key K) (V, bool) {
+	if w, ok := txn.pending[key]; ok {
+		return w.value, !w.deleted
+	}
+	return txn.m.lookup(key)
+}
+
+// Set buffers the assignment of a value to a key.
+func (txn *Txn[K, V]) Set(key K, value V) {
+	txn.pending[key] = txnWrite[V]{value: value}
+}
+
+// Delete buffers the removal of a key.
+func (txn *Txn[K, V]) Delete(key K) {
+	txn.pending[key] = txnWrite[V]{deleted: true}
+}
+
+// Abort discards all buffered writes. Further writes are ignored
+// when the callback returns.
+func (txn *Txn[K, V]) Abort() {
+	txn.aborted = true
+	clear(txn.pending)
+}
@@ -0,0 +1,71 @@
+package valuemap
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestWithLock(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := FromMap(map[string]int{"a": 1})
+
+	// Move the value of "a" to "b".
+	m.WithLock(&mu, func(tx *Tx[string, int]) {
+		if v, ok := tx.Get("a"); ok {
+			tx.Delete("a")
+			tx.Set("b", v)
+		}
+	})
+
+	if _, ok := m.Get(&mu, "a"); ok {
+		t.Error("key a still present")
+	}
+	if v, _ := m.Get(&mu, "b"); v != 1 {
+		t.Errorf("Get(b) = %d, want 1", v)
+	}
+}
+
+func TestTransact(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := FromMap(map[string]int{"a": 1, "b": 2})
+
+	err := m.Transact(&mu, func(txn *Txn[string, int]) error {
+		txn.Set("a", 10)
+		txn.Delete("b")
+		if _, ok := txn.Get("b"); ok {
+			t.Error("Txn.Get sees a buffered delete as present")
+		}
+		return errors.New("invalid batch")
+	})
+	if err == nil || err.Error() != "invalid batch" {
+		t.Errorf("Transact error = %v", err)
+	}
+	if v, _ := m.Get(&mu, "a"); v != 1 {
+		t.Errorf("Get(a) = %d after failed transaction, want 1", v)
+	}
+
+	err = m.Transact(&mu, func(txn *Txn[string, int]) error {
+		txn.Set("a", 10)
+		txn.Abort()
+		return nil
+	})
+	if !errors.Is(err, ErrTxnAborted) {
+		t.Errorf("Transact error = %v, want ErrTxnAborted", err)
+	}
+
+	err = m.Transact(&mu, func(txn *Txn[string, int]) error {
+		txn.Set("a", 10)
+		txn.Delete("b")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := m.Get(&mu, "a"); v != 10 {
+		t.Errorf("Get(a) = %d, want 10", v)
+	}
+	if _, ok := m.Get(&mu, "b"); ok {
+		t.Error("key b still present after commit")
+	}
+}
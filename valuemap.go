@@ -1,17 +1,108 @@
 package valuemap
 
 import (
+	"context"
+	"iter"
 	"maps"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type ValueMap[K comparable, V any] struct {
-	data map[K]V
+	data       map[K]V
+	stripes    *stripes[K, V] // entries of WithShards, used instead of data
+	ttl        *expiry[K]
+	bound      *bound[K, V]
+	onEvict    func(key K, value V, reason RemovalReason)
+	shared     bool // data is shared with a clone and must be copied before writing
+	reserved   int  // number of entries data was allocated for
+	trackReads bool // reads update state, so they need the write lock
+	autosave   *autosave[K, V]
+	journal    *journal[K, V]
+	watch      atomic.Pointer[watchers[K, V]]
+	tracer     Tracer
+	clock      Clock
+	hot        *hotKeys[K]
+	revs       *revisions[K]
+	hist       *history[K, V]
+	tombs      *tombstones[K, V]
+	reverse    valueIndex[K, V] // index of WithReverseIndex
+	indexes    map[string]*fieldIndex[K, V]
+	audit      func(r AuditRecord[K, V])
+	auditLog   *auditLog
+	actor      string // actor of the change in progress, set under the write lock
+	loader     *loader[K, V]
+	computes   atomic.Pointer[loader[K, V]] // calls of GetOrCompute
+	writer     *writer[K, V]
+	ops        counters
+	lockWait   atomic.Pointer[func(write bool, wait time.Duration)]
+	closed     atomic.Bool
+	closeOnce  sync.Once
+	closeErr   error
 }
 
-// New returns a new pointer to a thread-safe ValueMap.
-func New[K comparable, V any]() *ValueMap[K, V] {
-	return &ValueMap[K, V]{data: make(map[K]V)}
+// New returns a new pointer to a thread-safe ValueMap configured by opts.
+// Without options it is a plain map. Options pre-size it, bound it, make
+// its entries expire, observe it and persist it, and they combine freely:
+//
+//	m := valuemap.New[string, *Session](
+//		valuemap.WithCapacity(10_000),
+//		valuemap.WithMaxEntries(10_000),
+//		valuemap.WithTTL(&mu, 30*time.Minute, time.Minute),
+//		valuemap.WithOnEvict(logEviction),
+//	)
+//
+// Options given later override earlier ones. Options that require other
+// options, or whose types do not match the map, make New panic.
+func New[K comparable, V any](opts ...Option) *ValueMap[K, V] {
+	c := newConfig(opts)
+	m := &ValueMap[K, V]{data: make(map[K]V, c.capacity), reserved: c.capacity}
+	m.setup(c)
+	return m
+}
+
+// setup applies the options in c to a new map. The map may already hold
+// entries, as when OpenJournaled recovers them, in which case they are
+// adopted as if they had just been set.
+func (m *ValueMap[K, V]) setup(c *config) {
+	if c.shards > 1 {
+		m.spread(c.shards, max(m.reserved, m.size()))
+	}
+	if c.bounded {
+		m.bind(c)
+	}
+	if c.ttlMu != nil {
+		m.ttl = newExpiry[K](c.ttl)
+		m.ttl.sliding = c.sliding
+		m.trackReads = m.trackReads || c.sliding
+	}
+	m.configure(c)
+	m.adopt()
+	if c.ttlMu != nil && c.cleanupInterval > 0 {
+		go m.janitor(c.ttlMu, c.cleanupInterval)
+	}
+}
+
+// adopt gives the entries of a new map the default lifetime and registers
+// them with the eviction policy, in no particular order, evicting those
+// beyond the limits of the map.
+func (m *ValueMap[K, V]) adopt() {
+	if m.size() == 0 || (m.ttl == nil && m.bound == nil) {
+		return
+	}
+	now := m.now()
+	for k, v := range m.each() {
+		if m.ttl != nil {
+			m.ttl.set(k, m.ttl.ttl, now)
+		}
+		if m.bound != nil {
+			m.bound.policy.OnSet(k)
+			m.bound.charge(k, v)
+			m.evict(k)
+		}
+	}
 }
 
 // FromMap returns a new ValueMap initialized with a copy of an existing map.
@@ -25,58 +116,317 @@ func FromMap[K comparable, V any](m map[K]V) *ValueMap[K, V] {
 //
 // mu is an external mutex to lock the internal map during value assigning
 func (m *ValueMap[K, V]) Set(mu *sync.RWMutex, key K, value V) {
-	mu.Lock()
-	defer mu.Unlock()
-	m.data[key] = value
+	var existed bool
+	if m.tracer != nil {
+		end := m.tracer.Start("Set")
+		defer func() { end(existed) }()
+	}
+	defer m.lockKey(mu, key).Unlock()
+	existed = m.store(key, value)
+}
+
+// SetIfAbsent assigns a value to a key only if the key is not present.
+// It reports whether the value was assigned.
+//
+// mu is an external mutex to lock the internal map during value assigning
+func (m *ValueMap[K, V]) SetIfAbsent(mu *sync.RWMutex, key K, value V) bool {
+	defer m.lockKey(mu, key).Unlock()
+	if _, ok := m.lookup(key); ok {
+		return false
+	}
+	m.store(key, value)
+	return true
+}
+
+// SetIfPresent assigns a value to a key only if the key is already present.
+// It reports whether the value was assigned.
+//
+// mu is an external mutex to lock the internal map during value assigning
+func (m *ValueMap[K, V]) SetIfPresent(mu *sync.RWMutex, key K, value V) bool {
+	defer m.lockKey(mu, key).Unlock()
+	if _, ok := m.lookup(key); !ok {
+		return false
+	}
+	m.store(key, value)
+	return true
 }
 
 // Get retrieves a value and a boolean indicating if the key exists.
+// For a map created with WithLoader, a missing key is loaded as by
+// GetContext, and the result is false if the loader fails.
 //
 // mu is an external mutex to lock the internal map during value retrieval
-func (m *ValueMap[K, V]) Get(mu *sync.RWMutex, key K) (V, bool) {
-	mu.RLock()
-	defer mu.RUnlock()
-	v, ok := m.data[key]
-	return v, ok
+func (m *ValueMap[K, V]) Get(mu *sync.RWMutex, key K) (value V, ok bool) {
+	if m.tracer != nil {
+		end := m.tracer.Start("Get")
+		defer func() { end(ok) }()
+	}
+	value, ok, reload := m.get(mu, key)
+	if reload {
+		m.revalidate(mu, key)
+	}
+	if !ok && m.loader != nil {
+		var err error
+		value, err = m.load(context.Background(), mu, key)
+		ok = err == nil
+	}
+	return value, ok
+}
+
+// get retrieves the value of a present key, or the expired value of a key
+// that can be served stale. reload is true if the key should be loaded
+// again in the background, because it is stale or due for a refresh.
+func (m *ValueMap[K, V]) get(mu *sync.RWMutex, key K) (v V, ok, reload bool) {
+	defer m.rlockKey(mu, key).Unlock()
+	v, ok = m.lookup(key)
+	stale := false
+	if !ok && m.ttl != nil && m.ttl.stale > 0 {
+		v, ok = m.stored(key)
+		stale = ok
+	}
+	m.read(key, ok)
+	if ok && !stale {
+		m.access(key)
+		reload = m.ttl != nil && m.ttl.refresh > 0 && m.ttl.due(key, m.now())
+	}
+	return v, ok, stale || reload
+}
+
+// GetOrSet returns the existing value for the key if present.
+// Otherwise, it stores and returns the given value.
+// The loaded result is true if the value was loaded, false if stored.
+//
+// mu is an external mutex to lock the internal map during the check and assignment
+func (m *ValueMap[K, V]) GetOrSet(mu *sync.RWMutex, key K, value V) (actual V, loaded bool) {
+	defer m.lockKey(mu, key).Unlock()
+	v, ok := m.lookup(key)
+	m.read(key, ok)
+	if ok {
+		m.access(key)
+		return v, true
+	}
+	m.store(key, value)
+	return value, false
+}
+
+// Swap assigns a value to a key and returns the previous value, if any.
+// The existed result reports whether the key was present.
+//
+// mu is an external mutex to lock the internal map during value swapping
+func (m *ValueMap[K, V]) Swap(mu *sync.RWMutex, key K, value V) (previous V, existed bool) {
+	defer m.lockKey(mu, key).Unlock()
+	previous, existed = m.lookup(key)
+	m.store(key, value)
+	return previous, existed
+}
+
+// Update performs a read-modify-write on the value of a key.
+// fn receives the current value and whether the key exists, and returns
+// the new value and whether it should be kept. If keep is false, the key is deleted.
+// Update returns the stored value and whether the key is present afterwards.
+//
+// mu is an external mutex to lock the internal map during the update,
+// so fn must not call methods that lock mu
+func (m *ValueMap[K, V]) Update(mu *sync.RWMutex, key K, fn func(current V, exists bool) (V, bool)) (V, bool) {
+	defer m.lockKey(mu, key).Unlock()
+	cur, ok := m.lookup(key)
+	v, keep := fn(cur, ok)
+	if !keep {
+		m.remove(key)
+		var zero V
+		return zero, false
+	}
+	m.store(key, v)
+	return v, true
+}
+
+// ComputeIfAbsent returns the value of the key if present. Otherwise, it
+// stores and returns the value produced by factory.
+//
+// mu is an external mutex to lock the internal map during the computation,
+// so factory must not call methods that lock mu
+func (m *ValueMap[K, V]) ComputeIfAbsent(mu *sync.RWMutex, key K, factory func(key K) V) V {
+	defer m.lockKey(mu, key).Unlock()
+	v, ok := m.lookup(key)
+	m.read(key, ok)
+	if ok {
+		m.access(key)
+		return v
+	}
+	v = factory(key)
+	m.store(key, v)
+	return v
+}
+
+// ComputeIfPresent replaces the value of an existing key with the value
+// returned by fn, or deletes the key if fn returns false for keep.
+// It is a no-op when the key is absent. ComputeIfPresent returns the stored
+// value and whether the key is present afterwards.
+//
+// mu is an external mutex to lock the internal map during the computation,
+// so fn must not call methods that lock mu
+func (m *ValueMap[K, V]) ComputeIfPresent(mu *sync.RWMutex, key K, fn func(key K, value V) (V, bool)) (V, bool) {
+	defer m.lockKey(mu, key).Unlock()
+	cur, ok := m.lookup(key)
+	if !ok {
+		return cur, false
+	}
+	v, keep := fn(key, cur)
+	if !keep {
+		m.remove(key)
+		var zero V
+		return zero, false
+	}
+	m.store(key, v)
+	return v, true
+}
+
+// CompareAndSwapFunc swaps the old and new values for the key
+// if the value stored in the map is equal to old according to eq.
+// It reports whether the swap was performed.
+//
+// mu is an external mutex to lock the internal map during the comparison and swap
+func (m *ValueMap[K, V]) CompareAndSwapFunc(mu *sync.RWMutex, key K, old, new V, eq func(a, b V) bool) bool {
+	defer m.lockKey(mu, key).Unlock()
+	v, ok := m.lookup(key)
+	if !ok || !eq(v, old) {
+		return false
+	}
+	m.store(key, new)
+	return true
+}
+
+// CompareAndDeleteFunc deletes the entry for the key
+// if its value is equal to old according to eq.
+// It reports whether the entry was deleted.
+//
+// mu is an external mutex to lock the internal map during the comparison and deletion
+func (m *ValueMap[K, V]) CompareAndDeleteFunc(mu *sync.RWMutex, key K, old V, eq func(a, b V) bool) bool {
+	defer m.lockKey(mu, key).Unlock()
+	v, ok := m.lookup(key)
+	if !ok || !eq(v, old) {
+		return false
+	}
+	m.remove(key)
+	return true
 }
 
 // Delete removes a key from the map.
 //
 // mu is an external mutex to lock the internal map during key deletion
 func (m *ValueMap[K, V]) Delete(mu *sync.RWMutex, key K) {
-	mu.Lock()
-	defer mu.Unlock()
-	delete(m.data, key)
+	var found bool
+	if m.tracer != nil {
+		end := m.tracer.Start("Delete")
+		defer func() { end(found) }()
+	}
+	defer m.lockKey(mu, key).Unlock()
+	found = m.remove(key)
+}
+
+// SetMany assigns all the key-value pairs of entries.
+//
+// mu is an external mutex to lock the internal map once for the whole batch
+func (m *ValueMap[K, V]) SetMany(mu *sync.RWMutex, entries map[K]V) {
+	defer m.lock(mu).Unlock()
+	for k, v := range entries {
+		m.store(k, v)
+	}
+}
+
+// GetMany retrieves the values of the given keys.
+// Keys that do not exist are omitted from the result. For a map created
+// with WithLoader or WithBulkLoader, missing keys are loaded as by
+// GetManyContext, and keys that fail to load are omitted.
+//
+// mu is an external mutex to lock the internal map once for the whole batch
+func (m *ValueMap[K, V]) GetMany(mu *sync.RWMutex, keys []K) map[K]V {
+	res, missing := m.getMany(mu, keys)
+	if len(missing) > 0 && m.loader != nil {
+		m.loadMany(context.Background(), mu, res, missing)
+	}
+	return res
+}
+
+// getMany retrieves the values of the present keys, and returns the
+// others as missing.
+func (m *ValueMap[K, V]) getMany(mu *sync.RWMutex, keys []K) (res map[K]V, missing []K) {
+	defer m.rlock(mu).Unlock()
+	res = make(map[K]V, len(keys))
+	for _, k := range keys {
+		v, ok := m.lookup(k)
+		m.read(k, ok)
+		if ok {
+			m.access(k)
+			res[k] = v
+		} else {
+			missing = append(missing, k)
+		}
+	}
+	return res, missing
+}
+
+// DeleteMany removes the given keys and returns how many were present.
+//
+// mu is an external mutex to lock the internal map once for the whole batch
+func (m *ValueMap[K, V]) DeleteMany(mu *sync.RWMutex, keys []K) int {
+	defer m.lock(mu).Unlock()
+	n := 0
+	for _, k := range keys {
+		if m.remove(k) {
+			n++
+		}
+	}
+	return n
+}
+
+// Pop removes a key from the map and returns its value, if any.
+// The ok result reports whether the key was present.
+//
+// mu is an external mutex to lock the internal map during retrieval and deletion
+func (m *ValueMap[K, V]) Pop(mu *sync.RWMutex, key K) (V, bool) {
+	defer m.lockKey(mu, key).Unlock()
+	v, ok := m.lookup(key)
+	m.remove(key)
+	return v, ok
 }
 
-// Clone returns a deep copy of the ValueMap.
+// Clone returns a copy of the ValueMap that is independent of the original.
+//
+// The copy shares its storage with the original until either of them is
+// first written to, which then copies the storage. Cloning is therefore
+// cheap for maps that are cloned often but rarely written to afterwards.
+// Maps with expiring entries, and maps created with WithShards, are copied
+// right away.
 //
 // mu is an external mutex to lock the internal map during cloning
 func (m *ValueMap[K, V]) Clone(mu *sync.RWMutex) *ValueMap[K, V] {
-	mu.Lock()
-	defer mu.Unlock()
-	cp := make(map[K]V, len(m.data))
-	maps.Copy(cp, m.data)
-	return &ValueMap[K, V]{data: cp}
+	defer m.lock(mu).Unlock()
+	if m.ttl != nil || m.stripes != nil {
+		return &ValueMap[K, V]{data: maps.Collect(m.live())}
+	}
+	m.shared = true
+	return &ValueMap[K, V]{data: m.data, shared: true}
 }
 
 // Merge adds or overwrites keys from another ValueMap into this one.
+// Use MergeWith to handle the keys present in both otherwise.
 //
 // mu is an external mutex to lock the internal map during value merging
 func (m *ValueMap[K, V]) Merge(mu *sync.RWMutex, other *ValueMap[K, V]) {
-	mu.Lock()
-	defer mu.Unlock()
-	maps.Copy(m.data, other.data)
+	defer m.lock(mu).Unlock()
+	for k, v := range other.live() {
+		m.store(k, v)
+	}
 }
 
 // Keys returns a slice of all keys.
 //
 // mu is an external mutex to lock the internal map during key retrieval
 func (m *ValueMap[K, V]) Keys(mu *sync.RWMutex) []K {
-	mu.RLock()
-	defer mu.RUnlock()
-	keys := make([]K, 0, len(m.data))
-	for k := range m.data {
+	defer m.readLock(mu).Unlock()
+	keys := make([]K, 0, m.size())
+	for k := range m.live() {
 		keys = append(keys, k)
 	}
 	return keys
@@ -86,10 +436,9 @@ func (m *ValueMap[K, V]) Keys(mu *sync.RWMutex) []K {
 //
 // mu is an external mutex to lock the internal map during value retrieval
 func (m *ValueMap[K, V]) Values(mu *sync.RWMutex) []V {
-	mu.RLock()
-	defer mu.RUnlock()
-	values := make([]V, 0, len(m.data))
-	for _, v := range m.data {
+	defer m.readLock(mu).Unlock()
+	values := make([]V, 0, m.size())
+	for _, v := range m.live() {
 		values = append(values, v)
 	}
 	return values
@@ -99,29 +448,388 @@ func (m *ValueMap[K, V]) Values(mu *sync.RWMutex) []V {
 //
 // mu is an external mutex to lock the internal map during map content counting
 func (m *ValueMap[K, V]) Len(mu *sync.RWMutex) int {
-	mu.RLock()
-	defer mu.RUnlock()
-	return len(m.data)
+	defer m.readLock(mu).Unlock()
+	return m.count()
 }
 
 // Clear removes all entries from the map.
 //
 // mu is an external mutex to lock the internal map during map clearing
 func (m *ValueMap[K, V]) Clear(mu *sync.RWMutex) {
-	mu.Lock()
-	defer mu.Unlock()
-	m.data = make(map[K]V)
+	defer m.lock(mu).Unlock()
+	m.reset()
+}
+
+// Replace swaps the contents of the map for a copy of data in one step,
+// so readers see either the old or the new contents.
+//
+// mu is an external mutex to lock the internal map during content replacement
+func (m *ValueMap[K, V]) Replace(mu *sync.RWMutex, data map[K]V) {
+	defer m.lock(mu).Unlock()
+	m.replace(data)
 }
 
 // Raw returns a read-only copy of the internal map.
 //
 // mu is an external mutex to lock the internal map during raw value retrieval
 func (m *ValueMap[K, V]) Raw(mu *sync.RWMutex) map[K]V {
-	mu.RLock()
-	defer mu.RUnlock()
-	cp := make(map[K]V, len(m.data))
-	for k, v := range m.data {
-		cp[k] = v
+	defer m.readLock(mu).Unlock()
+	return maps.Collect(m.live())
+}
+
+// Range calls fn sequentially for each key and value present in the map.
+// If fn returns false, Range stops the iteration.
+//
+// mu is an external mutex to lock the internal map during iteration,
+// so fn must not call methods that lock mu
+func (m *ValueMap[K, V]) Range(mu *sync.RWMutex, fn func(key K, value V) bool) {
+	defer m.readLock(mu).Unlock()
+	for k, v := range m.live() {
+		if !fn(k, v) {
+			return
+		}
+	}
+}
+
+// All returns an iterator over the key-value pairs of a snapshot of the map.
+//
+// mu is an external mutex to lock the internal map while the snapshot is taken.
+// It is not held while the loop body runs
+func (m *ValueMap[K, V]) All(mu *sync.RWMutex) iter.Seq2[K, V] {
+	snap := m.Raw(mu)
+	return func(yield func(K, V) bool) {
+		for k, v := range snap {
+			if !yield(k, v) {
+				return
+			}
+		}
+	}
+}
+
+// KeysSeq returns an iterator over the keys of a snapshot of the map.
+//
+// mu is an external mutex to lock the internal map while the snapshot is taken.
+// It is not held while the loop body runs
+func (m *ValueMap[K, V]) KeysSeq(mu *sync.RWMutex) iter.Seq[K] {
+	return slices.Values(m.Keys(mu))
+}
+
+// ValuesSeq returns an iterator over the values of a snapshot of the map.
+//
+// mu is an external mutex to lock the internal map while the snapshot is taken.
+// It is not held while the loop body runs
+func (m *ValueMap[K, V]) ValuesSeq(mu *sync.RWMutex) iter.Seq[V] {
+	return slices.Values(m.Values(mu))
+}
+
+// CompareAndSwap swaps the old and new values for the key
+// if the value stored in the map is equal to old.
+// It reports whether the swap was performed.
+//
+// mu is an external mutex to lock the internal map during the comparison and swap
+func CompareAndSwap[K, V comparable](m *ValueMap[K, V], mu *sync.RWMutex, key K, old, new V) bool {
+	return m.CompareAndSwapFunc(mu, key, old, new, equal[V])
+}
+
+// CompareAndDelete deletes the entry for the key if its value is equal to old.
+// It reports whether the entry was deleted.
+//
+// mu is an external mutex to lock the internal map during the comparison and deletion
+func CompareAndDelete[K, V comparable](m *ValueMap[K, V], mu *sync.RWMutex, key K, old V) bool {
+	return m.CompareAndDeleteFunc(mu, key, old, equal[V])
+}
+
+func equal[V comparable](a, b V) bool {
+	return a == b
+}
+
+// lock locks mu for writing and returns it to be unlocked.
+// The wait is reported to the function registered by ObserveLockWait.
+func (m *ValueMap[K, V]) lock(mu *sync.RWMutex) sync.Locker {
+	if fn := m.lockWait.Load(); fn == nil {
+		mu.Lock()
+	} else if mu.TryLock() {
+		(*fn)(true, 0)
+	} else {
+		start := time.Now()
+		mu.Lock()
+		(*fn)(true, time.Since(start))
+	}
+	return mu
+}
+
+// rlock locks mu for reading and returns the matching locker to unlock it.
+// Maps whose reads update state are locked for writing instead.
+func (m *ValueMap[K, V]) rlock(mu *sync.RWMutex) sync.Locker {
+	if m.trackReads {
+		return m.lock(mu)
+	}
+	return m.readLock(mu)
+}
+
+// readLock locks mu for reading and returns the matching locker to unlock
+// it, for reads that do not update state. Striped maps are locked for
+// writing instead, since writes to a single key hold mu only for reading.
+func (m *ValueMap[K, V]) readLock(mu *sync.RWMutex) sync.Locker {
+	if m.stripes != nil {
+		return m.lock(mu)
+	}
+	return m.lockShared(mu)
+}
+
+// lockShared locks mu for reading and returns the matching locker to
+// unlock it. The wait is reported to the function registered by
+// ObserveLockWait.
+func (m *ValueMap[K, V]) lockShared(mu *sync.RWMutex) sync.Locker {
+	if fn := m.lockWait.Load(); fn == nil {
+		mu.RLock()
+	} else if mu.TryRLock() {
+		(*fn)(false, 0)
+	} else {
+		start := time.Now()
+		mu.RLock()
+		(*fn)(false, time.Since(start))
+	}
+	return mu.RLocker()
+}
+
+// access records a read of a present key.
+// The caller must hold the lock taken by rlock.
+func (m *ValueMap[K, V]) access(key K) {
+	if m.ttl != nil && m.ttl.sliding {
+		m.ttl.touch(key, m.now())
+	}
+	if m.bound != nil {
+		m.bound.policy.OnGet(key)
+	}
+}
+
+// read records a lookup of key in the statistics of the map.
+// The caller must hold the lock taken by rlock.
+func (m *ValueMap[K, V]) read(key K, hit bool) {
+	m.ops.read(hit)
+	if m.hot != nil {
+		m.hot.add(key)
+	}
+}
+
+// lookup returns the value of a key that is present and not expired.
+// The caller must hold at least the read lock.
+func (m *ValueMap[K, V]) lookup(key K) (V, bool) {
+	v, ok := m.stored(key)
+	if !ok || (m.ttl != nil && m.ttl.expired(key, m.now())) {
+		var zero V
+		return zero, false
+	}
+	return v, true
+}
+
+// store assigns a value to a key and reports whether the key was present
+// and not expired. The caller must hold the write lock.
+func (m *ValueMap[K, V]) store(key K, value V) bool {
+	m.persist(key, value)
+	return m.put(key, value)
+}
+
+// put is store without writing to the backing store, for values that come
+// from it or from a snapshot. The caller must hold the write lock.
+func (m *ValueMap[K, V]) put(key K, value V) bool {
+	m.logSet(key, value)
+	if m.loader != nil {
+		m.loader.supersede(key)
+	}
+	if l := m.computes.Load(); l != nil {
+		l.supersede(key)
+	}
+	old, existed := m.lookup(key)
+	if m.ttl != nil {
+		now := m.now()
+		if v, ok := m.stored(key); ok && m.ttl.expired(key, now) {
+			if m.hist != nil {
+				delete(m.hist.byKey, key)
+			}
+			m.removed(key, v, ReasonExpired)
+			m.emit(Event[K, V]{Kind: EventDelete, Key: key, Old: v, Existed: true, Reason: ReasonExpired})
+		}
+		m.ttl.set(key, m.ttl.ttl, now)
+	}
+	m.own()
+	m.reindex(key, value)
+	m.assign(key, value)
+	if m.revs != nil {
+		m.revs.revise(key)
+	}
+	if m.hist != nil && existed {
+		m.hist.record(key, old)
+	}
+	if m.tombs != nil {
+		delete(m.tombs.byKey, key)
+	}
+	if m.bound != nil {
+		m.bound.policy.OnSet(key)
+		m.bound.charge(key, value)
+	}
+	m.ops.sets.Add(1)
+	m.changed()
+	m.emit(Event[K, V]{Kind: EventSet, Key: key, Old: old, New: value, Existed: existed})
+	// Evictions follow the set that caused them, including the eviction of
+	// key itself when the policy rejects it, so that the events describe
+	// the contents of the map in order.
+	if m.bound != nil {
+		m.evict(key)
+	}
+	return existed
+}
+
+// remove deletes a key and reports whether it was present and not expired.
+// The caller must hold the write lock.
+func (m *ValueMap[K, V]) remove(key K) bool {
+	m.unpersist(key)
+	return m.removeFor(key, ReasonDeleted)
+}
+
+// removeFor deletes a key for the given reason and reports whether it was
+// present and not expired. An expired entry is always removed as expired.
+// The caller must hold the write lock.
+func (m *ValueMap[K, V]) removeFor(key K, reason RemovalReason) bool {
+	v, present := m.stored(key)
+	_, ok := m.lookup(key)
+	if present && !ok {
+		reason = ReasonExpired
+	}
+	if present {
+		m.logDelete(key)
+		m.own()
+		m.unset(key)
+		m.unindex(key, v)
+	}
+	if m.ttl != nil {
+		m.ttl.forget(key)
+	}
+	if m.revs != nil {
+		delete(m.revs.byKey, key)
+	}
+	if m.hist != nil {
+		if ok && reason == ReasonDeleted {
+			m.hist.record(key, v)
+		} else if present {
+			delete(m.hist.byKey, key)
+		}
+	}
+	if m.bound != nil {
+		m.bound.policy.OnDelete(key)
+		m.bound.refund(key)
+	}
+	if present {
+		m.removed(key, v, reason)
+		m.changed()
+		m.emit(Event[K, V]{Kind: EventDelete, Key: key, Old: v, Existed: true, Reason: reason})
+	}
+	return ok
+}
+
+// reset removes all entries. The caller must hold the write lock.
+func (m *ValueMap[K, V]) reset() {
+	m.logClear()
+	m.forgetFailures(true)
+	cleared := m.size() > 0
+	m.emitCleared()
+	if m.onEvict != nil {
+		now := m.now()
+		for k, v := range m.each() {
+			reason := ReasonCleared
+			if m.ttl != nil && m.ttl.expired(k, now) {
+				reason = ReasonExpired
+			}
+			m.removed(k, v, reason)
+		}
+	} else {
+		m.ops.removal(ReasonCleared, uint64(m.size()))
+	}
+	if m.ttl != nil {
+		clear(m.ttl.deadlines)
+		clear(m.ttl.lifetimes)
+	}
+	if m.revs != nil {
+		clear(m.revs.byKey)
+	}
+	if m.hist != nil {
+		clear(m.hist.byKey)
+	}
+	if m.tombs != nil {
+		clear(m.tombs.byKey)
+	}
+	if m.reverse != nil {
+		m.reverse.clear()
+	}
+	for _, ix := range m.indexes {
+		clear(ix.byValue)
+	}
+	if m.bound != nil {
+		if r, ok := m.bound.policy.(resetter); ok {
+			r.Reset()
+		} else {
+			for k := range m.each() {
+				m.bound.policy.OnDelete(k)
+			}
+		}
+		clear(m.bound.costs)
+		m.bound.used = 0
+	}
+	m.wipe()
+	m.shared = false
+	m.reserved = 0
+	m.changed()
+	if cleared {
+		m.emit(Event[K, V]{Kind: EventClear})
+	}
+}
+
+// changed records that the contents of the map were modified.
+// The caller must hold the write lock.
+func (m *ValueMap[K, V]) changed() {
+	if m.autosave != nil {
+		m.autosave.dirty.Store(true)
+	}
+}
+
+// own copies the storage of the map if it is shared with a clone,
+// so that it can be written to. The caller must hold the write lock.
+func (m *ValueMap[K, V]) own() {
+	if m.shared {
+		m.data = maps.Clone(m.data)
+		m.shared = false
+	}
+}
+
+// live returns an iterator over the entries that are not expired.
+// The caller must hold at least the read lock.
+func (m *ValueMap[K, V]) live() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		var now time.Time
+		if m.ttl != nil {
+			now = m.now()
+		}
+		for k, v := range m.each() {
+			if m.ttl != nil && m.ttl.expired(k, now) {
+				continue
+			}
+			if !yield(k, v) {
+				return
+			}
+		}
+	}
+}
+
+// count returns the number of entries that are not expired.
+// The caller must hold at least the read lock.
+func (m *ValueMap[K, V]) count() int {
+	if m.ttl == nil {
+		return m.size()
+	}
+	n := 0
+	for range m.live() {
+		n++
 	}
-	return cp
+	return n
 }
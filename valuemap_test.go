@@ -2,6 +2,8 @@ package valuemap
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 	"sync"
 	"testing"
 )
@@ -23,3 +25,255 @@ func TestMap(t *testing.T) {
 	m1.Merge(&mu, m2)
 	fmt.Println(m1.Get(&mu, "a")) // 99
 }
+
+func TestRange(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := FromMap(map[string]int{"a": 1, "b": 2, "c": 3})
+
+	sum := 0
+	m.Range(&mu, func(k string, v int) bool {
+		sum += v
+		return true
+	})
+	if sum != 6 {
+		t.Errorf("Range sum = %d, want 6", sum)
+	}
+
+	visited := 0
+	m.Range(&mu, func(k string, v int) bool {
+		visited++
+		return false
+	})
+	if visited != 1 {
+		t.Errorf("Range visited %d entries after stop, want 1", visited)
+	}
+}
+
+func TestIterators(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := FromMap(map[string]int{"a": 1, "b": 2})
+
+	got := maps.Collect(m.All(&mu))
+	if len(got) != 2 || got["a"] != 1 || got["b"] != 2 {
+		t.Errorf("All = %v", got)
+	}
+
+	// The snapshot must not hold the lock while the loop body runs.
+	for k := range m.KeysSeq(&mu) {
+		m.Set(&mu, k+k, 0)
+	}
+	if n := m.Len(&mu); n != 4 {
+		t.Errorf("Len = %d, want 4", n)
+	}
+
+	keys := slices.Sorted(m.KeysSeq(&mu))
+	if !slices.Equal(keys, []string{"a", "aa", "b", "bb"}) {
+		t.Errorf("KeysSeq = %v", keys)
+	}
+
+	sum := 0
+	for v := range m.ValuesSeq(&mu) {
+		sum += v
+	}
+	if sum != 3 {
+		t.Errorf("ValuesSeq sum = %d, want 3", sum)
+	}
+}
+
+func TestGetOrSet(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := New[string, int]()
+
+	if v, loaded := m.GetOrSet(&mu, "a", 1); v != 1 || loaded {
+		t.Errorf("GetOrSet = %d, %v; want 1, false", v, loaded)
+	}
+	if v, loaded := m.GetOrSet(&mu, "a", 2); v != 1 || !loaded {
+		t.Errorf("GetOrSet = %d, %v; want 1, true", v, loaded)
+	}
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := FromMap(map[string]int{"a": 1})
+
+	if CompareAndSwap(m, &mu, "a", 2, 3) {
+		t.Error("CompareAndSwap succeeded with stale old value")
+	}
+	if !CompareAndSwap(m, &mu, "a", 1, 3) {
+		t.Error("CompareAndSwap failed with current old value")
+	}
+	if CompareAndSwap(m, &mu, "missing", 0, 1) {
+		t.Error("CompareAndSwap succeeded on missing key")
+	}
+	if CompareAndDelete(m, &mu, "a", 1) {
+		t.Error("CompareAndDelete succeeded with stale old value")
+	}
+	if !CompareAndDelete(m, &mu, "a", 3) {
+		t.Error("CompareAndDelete failed with current old value")
+	}
+	if _, ok := m.Get(&mu, "a"); ok {
+		t.Error("key still present after CompareAndDelete")
+	}
+}
+
+func TestSwap(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := New[string, int]()
+
+	if prev, existed := m.Swap(&mu, "a", 1); prev != 0 || existed {
+		t.Errorf("Swap = %d, %v; want 0, false", prev, existed)
+	}
+	if prev, existed := m.Swap(&mu, "a", 2); prev != 1 || !existed {
+		t.Errorf("Swap = %d, %v; want 1, true", prev, existed)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := New[string, []string]()
+
+	add := func(cur []string, _ bool) ([]string, bool) {
+		return append(cur, "x"), true
+	}
+	m.Update(&mu, "a", add)
+	if v, _ := m.Update(&mu, "a", add); len(v) != 2 {
+		t.Errorf("Update = %v, want 2 elements", v)
+	}
+	if _, ok := m.Update(&mu, "a", func(cur []string, _ bool) ([]string, bool) {
+		return nil, false
+	}); ok {
+		t.Error("Update kept the key after returning keep=false")
+	}
+	if n := m.Len(&mu); n != 0 {
+		t.Errorf("Len = %d, want 0", n)
+	}
+}
+
+func TestComputeIfAbsent(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := New[string, int]()
+
+	calls := 0
+	factory := func(k string) int {
+		calls++
+		return len(k)
+	}
+	if v := m.ComputeIfAbsent(&mu, "abc", factory); v != 3 {
+		t.Errorf("ComputeIfAbsent = %d, want 3", v)
+	}
+	if v := m.ComputeIfAbsent(&mu, "abc", factory); v != 3 {
+		t.Errorf("ComputeIfAbsent = %d, want 3", v)
+	}
+	if calls != 1 {
+		t.Errorf("factory called %d times, want 1", calls)
+	}
+}
+
+func TestComputeIfPresent(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := FromMap(map[string]int{"a": 2})
+
+	decr := func(_ string, v int) (int, bool) {
+		return v - 1, v > 1
+	}
+	if _, ok := m.ComputeIfPresent(&mu, "missing", decr); ok {
+		t.Error("ComputeIfPresent created a missing key")
+	}
+	if v, ok := m.ComputeIfPresent(&mu, "a", decr); v != 1 || !ok {
+		t.Errorf("ComputeIfPresent = %d, %v; want 1, true", v, ok)
+	}
+	if _, ok := m.ComputeIfPresent(&mu, "a", decr); ok {
+		t.Error("ComputeIfPresent kept the key after keep=false")
+	}
+	if n := m.Len(&mu); n != 0 {
+		t.Errorf("Len = %d, want 0", n)
+	}
+}
+
+func TestPop(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := FromMap(map[string]int{"a": 1})
+
+	if v, ok := m.Pop(&mu, "a"); v != 1 || !ok {
+		t.Errorf("Pop = %d, %v; want 1, true", v, ok)
+	}
+	if _, ok := m.Pop(&mu, "a"); ok {
+		t.Error("second Pop found the key")
+	}
+}
+
+func TestSetIf(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := New[string, int]()
+
+	if m.SetIfPresent(&mu, "a", 1) {
+		t.Error("SetIfPresent assigned a missing key")
+	}
+	if !m.SetIfAbsent(&mu, "a", 1) {
+		t.Error("SetIfAbsent did not assign a missing key")
+	}
+	if m.SetIfAbsent(&mu, "a", 2) {
+		t.Error("SetIfAbsent overwrote an existing key")
+	}
+	if !m.SetIfPresent(&mu, "a", 3) {
+		t.Error("SetIfPresent did not assign an existing key")
+	}
+	if v, _ := m.Get(&mu, "a"); v != 3 {
+		t.Errorf("Get = %d, want 3", v)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := New[string, int]()
+
+	m.SetMany(&mu, map[string]int{"a": 1, "b": 2, "c": 3})
+	got := m.GetMany(&mu, []string{"a", "c", "missing"})
+	if len(got) != 2 || got["a"] != 1 || got["c"] != 3 {
+		t.Errorf("GetMany = %v", got)
+	}
+	if n := m.DeleteMany(&mu, []string{"a", "b", "missing"}); n != 2 {
+		t.Errorf("DeleteMany = %d, want 2", n)
+	}
+	if n := m.Len(&mu); n != 1 {
+		t.Errorf("Len = %d, want 1", n)
+	}
+}
+
+func TestCloneCopyOnWrite(t *testing.T) {
+	mu1, mu2 := sync.RWMutex{}, sync.RWMutex{}
+	m1 := FromMap(map[string]int{"a": 1, "b": 2})
+	m2 := m1.Clone(&mu1)
+
+	m2.Set(&mu2, "a", 10)
+	m1.Delete(&mu1, "b")
+
+	if v, _ := m1.Get(&mu1, "a"); v != 1 {
+		t.Errorf("original Get(a) = %d, want 1", v)
+	}
+	if v, ok := m2.Get(&mu2, "b"); !ok || v != 2 {
+		t.Errorf("clone Get(b) = %d, %v; want 2, true", v, ok)
+	}
+
+	m3 := m2.Clone(&mu2)
+	m3.Clear(&mu2)
+	if n := m2.Len(&mu2); n != 2 {
+		t.Errorf("Len after clearing clone = %d, want 2", n)
+	}
+}
+
+func TestLockingDoesNotAllocate(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := New[int, int]()
+	m.Set(&mu, 1, 1)
+	for name, fn := range map[string]func(){
+		"Set":    func() { m.Set(&mu, 1, 1) },
+		"Get":    func() { m.Get(&mu, 1) },
+		"Delete": func() { m.Delete(&mu, 2) },
+		"Len":    func() { m.Len(&mu) },
+	} {
+		if n := testing.AllocsPerRun(100, fn); n != 0 {
+			t.Errorf("%s allocates %v times per call, want 0", name, n)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+module github.com/eaglebush/valuemap/valuemapbolt
+
+go 1.24.2
+
+require (
+	github.com/eaglebush/valuemap v0.1.0
+	go.etcd.io/bbolt v1.4.3
+)
+
+require golang.org/x/sys v0.32.0 // indirect
+
+replace github.com/eaglebush/valuemap => ../
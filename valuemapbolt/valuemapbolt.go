@@ -0,0 +1,110 @@
+// Package valuemapbolt provides a valuemap.Backend that stores the entries
+// of a valuemap.DiskValueMap in a bucket of a bbolt database
+// (go.etcd.io/bbolt). Every change is a committed bbolt transaction,
+// so entries survive crashes once the method that wrote them returns.
+package valuemapbolt
+
+import (
+	"bytes"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Backend is a valuemap.Backend storing entries in one bucket of a bbolt
+// database. Entries are ranged over in byte order of their keys.
+type Backend struct {
+	db     *bolt.DB
+	bucket []byte
+	owned  bool
+}
+
+// Open opens or creates the bbolt database at path and returns a Backend
+// storing entries in the named bucket, which is created if needed.
+// Closing the Backend closes the database.
+func Open(path, bucket string) (*Backend, error) {
+	db, err := bolt.Open(path, 0o600, nil)
+	if err != nil {
+		return nil, err
+	}
+	b, err := New(db, bucket)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	b.owned = true
+	return b, nil
+}
+
+// New returns a Backend storing entries in the named bucket of an open
+// database, which is created if needed. Several maps can share a database
+// by using different buckets. Closing the Backend leaves the database open.
+func New(db *bolt.DB, bucket string) (*Backend, error) {
+	b := &Backend{db: db, bucket: []byte(bucket)}
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(b.bucket)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Get implements valuemap.Backend.
+func (b *Backend) Get(key []byte) (value []byte, ok bool, err error) {
+	err = b.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(b.bucket).Get(key); v != nil {
+			value, ok = bytes.Clone(v), true
+		}
+		return nil
+	})
+	return value, ok, err
+}
+
+// Put implements valuemap.Backend.
+func (b *Backend) Put(key, value []byte) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(b.bucket).Put(key, value)
+	})
+}
+
+// Delete implements valuemap.Backend.
+func (b *Backend) Delete(key []byte) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(b.bucket).Delete(key)
+	})
+}
+
+// Range implements valuemap.Backend. fn runs inside a read transaction,
+// so it must not write to the database.
+func (b *Backend) Range(fn func(key, value []byte) bool) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(b.bucket).Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if !fn(k, v) {
+				break
+			}
+		}
+		return nil
+	})
+}
+
+// Clear implements valuemap.Backend by recreating the bucket.
+func (b *Backend) Clear() error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(b.bucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucket(b.bucket)
+		return err
+	})
+}
+
+// Close implements valuemap.Backend. It closes the database if the
+// Backend was created by Open.
+func (b *Backend) Close() error {
+	if !b.owned {
+		return nil
+	}
+	return b.db.Close()
+}
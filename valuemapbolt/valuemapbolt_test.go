@@ -0,0 +1,66 @@
+package valuemapbolt
+
+import (
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/eaglebush/valuemap"
+)
+
+var _ valuemap.Backend = (*Backend)(nil)
+
+func TestDiskValueMap(t *testing.T) {
+	mu := sync.RWMutex{}
+	path := filepath.Join(t.TempDir(), "data.db")
+
+	b, err := Open(path, "counts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := valuemap.NewDisk(b, valuemap.JSONCodec[int, string]{})
+	m.Set(&mu, 10, "ten")
+	m.Set(&mu, -1, "minus one")
+	m.Set(&mu, 2, "two")
+	m.Delete(&mu, 2)
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err = Open(path, "counts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vm valuemap.Map[int, string] = valuemap.NewDisk(b, valuemap.JSONCodec[int, string]{})
+	if v, ok := vm.Get(&mu, 10); !ok || v != "ten" {
+		t.Errorf("Get(10) = %q, %v, want ten, true", v, ok)
+	}
+	if keys := vm.Keys(&mu); !slices.Equal(keys, []int{-1, 10}) {
+		t.Errorf("Keys() = %v, want [-1 10] in numeric order", keys)
+	}
+	vm.Clear(&mu)
+	if vm.Len(&mu) != 0 {
+		t.Errorf("Len() = %d after Clear, want 0", vm.Len(&mu))
+	}
+	if err := vm.(*valuemap.DiskValueMap[int, string]).Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEmptyKey(t *testing.T) {
+	mu := sync.RWMutex{}
+	b, err := Open(filepath.Join(t.TempDir(), "data.db"), "names")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := valuemap.NewDisk(b, valuemap.JSONCodec[string, int]{})
+	defer m.Close()
+	m.Set(&mu, "", 1)
+	if v, ok := m.Get(&mu, ""); !ok || v != 1 {
+		t.Errorf("Get(\"\") = %d, %v, want 1, true", v, ok)
+	}
+	if err := m.Err(); err != nil {
+		t.Errorf("Err() = %v", err)
+	}
+}
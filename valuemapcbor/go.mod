@@ -0,0 +1,12 @@
+module github.com/eaglebush/valuemap/valuemapcbor
+
+go 1.24.2
+
+require (
+	github.com/eaglebush/valuemap v0.1.0
+	github.com/fxamacker/cbor/v2 v2.9.0
+)
+
+require github.com/x448/float16 v0.8.4 // indirect
+
+replace github.com/eaglebush/valuemap => ../
@@ -0,0 +1,101 @@
+// Package valuemapcbor reads and writes ValueMaps as CBOR (RFC 8949)
+// using github.com/fxamacker/cbor/v2.
+//
+// CBOR map keys need not be text. Integer keys are written as CBOR integers,
+// and maps keyed by cbor.ByteString are written with byte string keys,
+// which is how binary keys such as hashes or COSE key IDs should be stored:
+// Go byte slices are not comparable and cannot be ValueMap keys.
+package valuemapcbor
+
+import (
+	"io"
+	"sync"
+
+	"github.com/eaglebush/valuemap"
+	"github.com/fxamacker/cbor/v2"
+)
+
+var canonical = mustEncMode(cbor.CoreDetEncOptions())
+
+func mustEncMode(opts cbor.EncOptions) cbor.EncMode {
+	em, err := opts.EncMode()
+	if err != nil {
+		panic(err)
+	}
+	return em
+}
+
+// Encode writes the contents of m to w as a CBOR map using the default
+// encoding options. Map keys are written in no particular order.
+//
+// mu is an external mutex to lock the internal map during encoding
+func Encode[K comparable, V any](w io.Writer, m *valuemap.ValueMap[K, V], mu *sync.RWMutex) error {
+	return m.Encode(mu, w, Codec[K, V]{})
+}
+
+// EncodeCanonical writes the contents of m to w as a CBOR map using the
+// Core Deterministic Encoding of RFC 8949 section 4.2: shortest integer and
+// length forms, no indefinite lengths, and map keys sorted bytewise.
+// Equal maps always produce identical bytes, so the output can be hashed
+// or signed.
+//
+// mu is an external mutex to lock the internal map during encoding
+func EncodeCanonical[K comparable, V any](w io.Writer, m *valuemap.ValueMap[K, V], mu *sync.RWMutex) error {
+	return m.Encode(mu, w, Codec[K, V]{Canonical: true})
+}
+
+// Decode reads a CBOR map from r and replaces the contents of m with it.
+// The map is decoded completely before m is changed, so on error m is left
+// as it was.
+//
+// mu is an external mutex to lock the internal map during content replacement
+func Decode[K comparable, V any](r io.Reader, m *valuemap.ValueMap[K, V], mu *sync.RWMutex) error {
+	return m.Decode(mu, r, Codec[K, V]{})
+}
+
+// Codec is a valuemap.Codec that writes the map as a CBOR map.
+// If Canonical is set, it uses the deterministic encoding of EncodeCanonical.
+type Codec[K comparable, V any] struct {
+	Canonical bool
+}
+
+// Encode implements valuemap.Codec.
+func (c Codec[K, V]) Encode(w io.Writer, data map[K]V) error {
+	if c.Canonical {
+		return canonical.NewEncoder(w).Encode(data)
+	}
+	return cbor.NewEncoder(w).Encode(data)
+}
+
+// Decode implements valuemap.Codec.
+func (Codec[K, V]) Decode(r io.Reader) (map[K]V, error) {
+	var data map[K]V
+	if err := cbor.NewDecoder(r).Decode(&data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Map binds a ValueMap to its mutex so that it can be embedded in a struct
+// encoded with cbor. It implements cbor.Marshaler and cbor.Unmarshaler,
+// locking Mu while the map is encoded or replaced. Marshaling uses the
+// default encoding options of the cbor package.
+type Map[K comparable, V any] struct {
+	M  *valuemap.ValueMap[K, V]
+	Mu *sync.RWMutex
+}
+
+// MarshalCBOR implements cbor.Marshaler.
+func (t Map[K, V]) MarshalCBOR() ([]byte, error) {
+	return cbor.Marshal(t.M.Raw(t.Mu))
+}
+
+// UnmarshalCBOR implements cbor.Unmarshaler.
+func (t Map[K, V]) UnmarshalCBOR(b []byte) error {
+	var data map[K]V
+	if err := cbor.Unmarshal(b, &data); err != nil {
+		return err
+	}
+	t.M.Replace(t.Mu, data)
+	return nil
+}
@@ -0,0 +1,102 @@
+package valuemapcbor
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/eaglebush/valuemap"
+	"github.com/fxamacker/cbor/v2"
+)
+
+var _ valuemap.Codec[int, string] = Codec[int, string]{}
+
+func TestEncodeDecode(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := valuemap.FromMap(map[int]string{1: "one", 2: "two"})
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, m, &mu); err != nil {
+		t.Fatal(err)
+	}
+	got := valuemap.New[int, string]()
+	if err := Decode(&buf, got, &mu); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := got.Get(&mu, 2); v != "two" {
+		t.Errorf("Get(2) = %q, want two", v)
+	}
+}
+
+func TestBinaryKeys(t *testing.T) {
+	mu := sync.RWMutex{}
+	key := cbor.ByteString([]byte{0x00, 0xff, 0x10})
+	m := valuemap.FromMap(map[cbor.ByteString]int{key: 7})
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, m, &mu); err != nil {
+		t.Fatal(err)
+	}
+	// 0xa1: map of one pair, 0x43: byte string of length 3.
+	if b := buf.Bytes(); len(b) < 2 || b[0] != 0xa1 || b[1] != 0x43 {
+		t.Errorf("encoding = % x, want a byte string key", b)
+	}
+	got := valuemap.New[cbor.ByteString, int]()
+	if err := Decode(&buf, got, &mu); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := got.Get(&mu, key); v != 7 {
+		t.Errorf("Get(key) = %d, want 7", v)
+	}
+}
+
+func TestEncodeCanonical(t *testing.T) {
+	mu := sync.RWMutex{}
+	data := map[string]int{}
+	for _, k := range []string{"b", "a", "aa", "c", "d", "e", "f", "g"} {
+		data[k] = len(k)
+	}
+
+	var first []byte
+	for range 10 {
+		var buf bytes.Buffer
+		if err := EncodeCanonical(&buf, valuemap.FromMap(data), &mu); err != nil {
+			t.Fatal(err)
+		}
+		if first == nil {
+			first = buf.Bytes()
+			continue
+		}
+		if !bytes.Equal(buf.Bytes(), first) {
+			t.Fatalf("encoding changed: % x, want % x", buf.Bytes(), first)
+		}
+	}
+	// Keys sort bytewise on their encoding, so shorter keys come first.
+	a := []byte{0x61, 'a', 0x01}
+	aa := []byte{0x62, 'a', 'a', 0x02}
+	if i, j := bytes.Index(first, a), bytes.Index(first, aa); i < 0 || j < 0 || i > j {
+		t.Errorf("key a encoded after aa: % x", first)
+	}
+}
+
+func TestMap(t *testing.T) {
+	type message struct {
+		ID   int
+		Tags Map[string, int]
+	}
+
+	mu := sync.RWMutex{}
+	in := message{ID: 1, Tags: Map[string, int]{M: valuemap.FromMap(map[string]int{"a": 1}), Mu: &mu}}
+	b, err := cbor.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := message{Tags: Map[string, int]{M: valuemap.New[string, int](), Mu: &mu}}
+	if err := cbor.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := out.Tags.M.Get(&mu, "a"); v != 1 {
+		t.Errorf("Get(a) = %d, want 1", v)
+	}
+}
@@ -0,0 +1,201 @@
+// Package valuemapetcd keeps a ValueMap in sync with a key prefix in etcd
+// using go.etcd.io/etcd/client/v3, which suits distributing feature flags
+// and configuration to a fleet.
+//
+// A Mirror loads every key under the prefix into the map, with the prefix
+// removed, and then applies the changes reported by an etcd watch, so the
+// map follows etcd in revision order. Values are stored in etcd as JSON.
+// Local changes can be pushed back with Set and Delete.
+//
+// The map remembers the revision of the last change applied to each key,
+// including deletions, so that a change is never replaced by an older one
+// arriving later, whether from the watch or from Set and Delete. The
+// revisions of deleted keys are kept until the prefix is next reloaded.
+package valuemapetcd
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eaglebush/valuemap"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// retryDelay is how long a Mirror waits before watching again after
+// the watch failed.
+const retryDelay = time.Second
+
+// Mirror keeps a ValueMap in sync with a key prefix in etcd.
+type Mirror[V any] struct {
+	kv     clientv3.KV
+	w      clientv3.Watcher
+	prefix string
+	m      *valuemap.ValueMap[string, V]
+	mu     *sync.RWMutex
+	rev    int64 // revision the map reflects, owned by the watch loop after Open
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	revMu sync.Mutex       // serializes changes to the map with revs
+	base  int64            // revision of the last load
+	revs  map[string]int64 // revision of the last change applied to each key
+
+	errMu sync.Mutex
+	err   error
+}
+
+// Open loads the keys under prefix into m, replacing its contents, and
+// keeps m updated until the Mirror is closed. A *clientv3.Client can be
+// passed as both kv and w.
+//
+// If the watch fails, for example because the revision it resumes from
+// was compacted, the Mirror reloads the prefix and watches again.
+//
+// mu is an external mutex to lock the internal map during updates.
+// It must be the same mutex passed to the other methods of m
+func Open[V any](ctx context.Context, kv clientv3.KV, w clientv3.Watcher, prefix string, m *valuemap.ValueMap[string, V], mu *sync.RWMutex) (*Mirror[V], error) {
+	s := &Mirror[V]{kv: kv, w: w, prefix: prefix, m: m, mu: mu, done: make(chan struct{})}
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+	ctx, s.cancel = context.WithCancel(context.Background())
+	go s.run(ctx)
+	return s, nil
+}
+
+// load replaces the contents of the map with the keys under the prefix.
+func (s *Mirror[V]) load(ctx context.Context) error {
+	resp, err := s.kv.Get(ctx, s.prefix, clientv3.WithPrefix())
+	if err != nil {
+		return err
+	}
+	data := make(map[string]V, len(resp.Kvs))
+	revs := make(map[string]int64, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		var v V
+		if err := json.Unmarshal(kv.Value, &v); err != nil {
+			return err
+		}
+		key := strings.TrimPrefix(string(kv.Key), s.prefix)
+		data[key] = v
+		revs[key] = kv.ModRevision
+	}
+	s.revMu.Lock()
+	defer s.revMu.Unlock()
+	s.m.Replace(s.mu, data)
+	s.base = resp.Header.Revision
+	s.revs = revs
+	s.rev = resp.Header.Revision
+	return nil
+}
+
+// apply makes a change of key at revision rev to the map, unless a change
+// of the same or a later revision was already applied.
+func (s *Mirror[V]) apply(key string, rev int64, change func()) {
+	s.revMu.Lock()
+	defer s.revMu.Unlock()
+	if rev <= s.base || rev <= s.revs[key] {
+		return
+	}
+	s.revs[key] = rev
+	change()
+}
+
+func (s *Mirror[V]) run(ctx context.Context) {
+	defer close(s.done)
+	for {
+		err := s.watch(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			s.fail(err)
+		}
+		select {
+		case <-time.After(retryDelay):
+		case <-ctx.Done():
+			return
+		}
+		if err := s.load(ctx); err != nil {
+			s.fail(err)
+		}
+	}
+}
+
+// watch applies changes until the watch ends, and returns why it ended.
+func (s *Mirror[V]) watch(ctx context.Context) error {
+	wch := s.w.Watch(ctx, s.prefix, clientv3.WithPrefix(), clientv3.WithRev(s.rev+1))
+	for resp := range wch {
+		if err := resp.Err(); err != nil {
+			return err
+		}
+		for _, ev := range resp.Events {
+			key := strings.TrimPrefix(string(ev.Kv.Key), s.prefix)
+			switch ev.Type {
+			case clientv3.EventTypePut:
+				var v V
+				if err := json.Unmarshal(ev.Kv.Value, &v); err != nil {
+					return err
+				}
+				s.apply(key, ev.Kv.ModRevision, func() { s.m.Set(s.mu, key, v) })
+			case clientv3.EventTypeDelete:
+				s.apply(key, ev.Kv.ModRevision, func() { s.m.Delete(s.mu, key) })
+			}
+			s.rev = ev.Kv.ModRevision
+		}
+		if resp.Header.Revision > s.rev {
+			s.rev = resp.Header.Revision
+		}
+	}
+	return nil
+}
+
+// fail records the first error met by the background updates.
+func (s *Mirror[V]) fail(err error) {
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// Set puts a value under the prefix in etcd, then assigns it to the key
+// in the local map, unless the watch already applied a later change of the
+// key. If etcd fails, the local map is not changed.
+func (s *Mirror[V]) Set(ctx context.Context, key string, value V) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	resp, err := s.kv.Put(ctx, s.prefix+key, string(b))
+	if err != nil {
+		return err
+	}
+	s.apply(key, resp.Header.Revision, func() { s.m.Set(s.mu, key, value) })
+	return nil
+}
+
+// Delete deletes a key under the prefix in etcd, then from the local map,
+// unless the watch already applied a later change of the key. If etcd
+// fails, the local map is not changed.
+func (s *Mirror[V]) Delete(ctx context.Context, key string) error {
+	resp, err := s.kv.Delete(ctx, s.prefix+key)
+	if err != nil {
+		return err
+	}
+	s.apply(key, resp.Header.Revision, func() { s.m.Delete(s.mu, key) })
+	return nil
+}
+
+// Close stops following etcd and returns the first error met while
+// updating the map in the background. The local map keeps its contents.
+func (s *Mirror[V]) Close() error {
+	s.cancel()
+	<-s.done
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+	return s.err
+}
@@ -0,0 +1,159 @@
+package valuemapetcd
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eaglebush/valuemap"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// fakeKV stores keys in memory and reports writes to its watchers.
+type fakeKV struct {
+	clientv3.KV
+	clientv3.Watcher
+
+	mu       sync.Mutex
+	rev      int64
+	data     map[string]string
+	revs     map[string]int64
+	events   chan clientv3.WatchResponse
+	afterPut func() // called once a put is reported, before it returns
+}
+
+func newFakeKV() *fakeKV {
+	return &fakeKV{
+		data:   make(map[string]string),
+		revs:   make(map[string]int64),
+		events: make(chan clientv3.WatchResponse, 16),
+	}
+}
+
+func (f *fakeKV) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	resp := &clientv3.GetResponse{Header: &pb.ResponseHeader{Revision: f.rev}}
+	for k, v := range f.data {
+		if strings.HasPrefix(k, key) {
+			resp.Kvs = append(resp.Kvs, &mvccpb.KeyValue{Key: []byte(k), Value: []byte(v), ModRevision: f.revs[k]})
+		}
+	}
+	return resp, nil
+}
+
+func (f *fakeKV) Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	f.mu.Lock()
+	f.rev++
+	f.data[key] = val
+	f.revs[key] = f.rev
+	f.events <- clientv3.WatchResponse{
+		Header: pb.ResponseHeader{Revision: f.rev},
+		Events: []*clientv3.Event{{Type: clientv3.EventTypePut, Kv: &mvccpb.KeyValue{Key: []byte(key), Value: []byte(val), ModRevision: f.rev}}},
+	}
+	resp := &clientv3.PutResponse{Header: &pb.ResponseHeader{Revision: f.rev}}
+	hook := f.afterPut
+	f.afterPut = nil
+	f.mu.Unlock()
+	if hook != nil {
+		hook()
+	}
+	return resp, nil
+}
+
+func (f *fakeKV) Delete(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.DeleteResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rev++
+	delete(f.data, key)
+	f.revs[key] = f.rev
+	f.events <- clientv3.WatchResponse{
+		Header: pb.ResponseHeader{Revision: f.rev},
+		Events: []*clientv3.Event{{Type: clientv3.EventTypeDelete, Kv: &mvccpb.KeyValue{Key: []byte(key), ModRevision: f.rev}}},
+	}
+	return &clientv3.DeleteResponse{Header: &pb.ResponseHeader{Revision: f.rev}}, nil
+}
+
+func (f *fakeKV) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	ch := make(chan clientv3.WatchResponse)
+	go func() {
+		defer close(ch)
+		for {
+			select {
+			case resp := <-f.events:
+				select {
+				case ch <- resp:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestMirror(t *testing.T) {
+	mu := sync.RWMutex{}
+	ctx := context.Background()
+	kv := newFakeKV()
+	kv.data["/flags/dark-mode"] = "true"
+	kv.data["/other/key"] = "false"
+	m := valuemap.New[string, bool]()
+
+	s, err := Open(ctx, kv, kv, "/flags/", m, &mu)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := m.Get(&mu, "dark-mode"); !v {
+		t.Error("Get(dark-mode) = false after Open, want true")
+	}
+	if m.Len(&mu) != 1 {
+		t.Errorf("Len() = %d, want only keys under the prefix", m.Len(&mu))
+	}
+
+	// Another instance changes the prefix.
+	kv.Put(ctx, "/flags/beta", "true")
+	waitFor(t, func() bool { v, _ := m.Get(&mu, "beta"); return v })
+	kv.Delete(ctx, "/flags/dark-mode")
+	waitFor(t, func() bool { _, ok := m.Get(&mu, "dark-mode"); return !ok })
+
+	if err := s.Set(ctx, "new-ui", true); err != nil {
+		t.Fatal(err)
+	}
+	if kv.data["/flags/new-ui"] != "true" {
+		t.Errorf("etcd value = %q, want true", kv.data["/flags/new-ui"])
+	}
+
+	// Another instance overwrites the key after our put, and the watch
+	// applies both before Set returns: the newer value must stay.
+	kv.afterPut = func() {
+		kv.Put(ctx, "/flags/new-ui", "false")
+		waitFor(t, func() bool { v, _ := m.Get(&mu, "new-ui"); return !v })
+	}
+	if err := s.Set(ctx, "new-ui", true); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := m.Get(&mu, "new-ui"); v {
+		t.Error("Set overwrote a newer value applied by the watch")
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
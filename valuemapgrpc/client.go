@@ -0,0 +1,263 @@
+package valuemapgrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/eaglebush/valuemap"
+	"google.golang.org/grpc"
+)
+
+// callTimeout bounds each call made by the methods of Map.
+const callTimeout = 10 * time.Second
+
+// Client is a map held by a remote server registered with Register.
+// It implements valuemap.Map; the remote map is locked by the server, so
+// the mutexes passed to the methods are ignored and may be nil.
+//
+// Since the methods of valuemap.Map do not return errors, the first failed
+// call is kept and reported by Err, and the failing operation acts as if
+// the key were absent.
+type Client[K comparable, V any] struct {
+	conn grpc.ClientConnInterface
+
+	errMu sync.Mutex
+	err   error
+}
+
+var _ valuemap.Map[string, int] = (*Client[string, int])(nil)
+
+// NewClient returns a new pointer to a Client calling the server at the
+// other end of conn.
+func NewClient[K comparable, V any](conn grpc.ClientConnInterface) *Client[K, V] {
+	return &Client[K, V]{conn: conn}
+}
+
+// fail records the first error met by the client.
+func (c *Client[K, V]) fail(err error) {
+	c.errMu.Lock()
+	defer c.errMu.Unlock()
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+// Err returns the first error met by the methods of the client.
+func (c *Client[K, V]) Err() error {
+	c.errMu.Lock()
+	defer c.errMu.Unlock()
+	return c.err
+}
+
+func (c *Client[K, V]) invoke(method string, req, resp message) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	err := c.conn.Invoke(ctx, "/"+serviceName+"/"+method, req, resp, grpc.ForceCodec(codec{}))
+	if err != nil {
+		c.fail(err)
+		return false
+	}
+	return true
+}
+
+// stream starts a server stream and sends it req.
+func (c *Client[K, V]) stream(ctx context.Context, method string, req message) (grpc.ClientStream, error) {
+	desc := &grpc.StreamDesc{StreamName: method, ServerStreams: true}
+	stream, err := c.conn.NewStream(ctx, desc, "/"+serviceName+"/"+method, grpc.ForceCodec(codec{}))
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.SendMsg(req); err != nil {
+		return nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+func (c *Client[K, V]) encode(v any) ([]byte, bool) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		c.fail(err)
+		return nil, false
+	}
+	return b, true
+}
+
+// Set assigns a value to a key.
+func (c *Client[K, V]) Set(_ *sync.RWMutex, key K, value V) {
+	kb, ok := c.encode(key)
+	if !ok {
+		return
+	}
+	vb, ok := c.encode(value)
+	if !ok {
+		return
+	}
+	c.invoke("Set", &entry{key: kb, value: vb}, &empty{})
+}
+
+// Get retrieves a value by key.
+func (c *Client[K, V]) Get(_ *sync.RWMutex, key K) (V, bool) {
+	var zero V
+	kb, ok := c.encode(key)
+	if !ok {
+		return zero, false
+	}
+	resp := &getResponse{}
+	if !c.invoke("Get", &keyRequest{key: kb}, resp) || !resp.found {
+		return zero, false
+	}
+	var v V
+	if err := json.Unmarshal(resp.value, &v); err != nil {
+		c.fail(err)
+		return zero, false
+	}
+	return v, true
+}
+
+// Delete removes a key.
+func (c *Client[K, V]) Delete(_ *sync.RWMutex, key K) {
+	if kb, ok := c.encode(key); ok {
+		c.invoke("Delete", &keyRequest{key: kb}, &deleteResponse{})
+	}
+}
+
+// Len returns the number of entries.
+func (c *Client[K, V]) Len(_ *sync.RWMutex) int {
+	resp := &lenResponse{}
+	c.invoke("Len", &empty{}, resp)
+	return int(resp.n)
+}
+
+// Clear removes all entries.
+func (c *Client[K, V]) Clear(_ *sync.RWMutex) {
+	c.invoke("Clear", &empty{}, &empty{})
+}
+
+// Keys returns a slice of all keys.
+func (c *Client[K, V]) Keys(_ *sync.RWMutex) []K {
+	var keys []K
+	c.Range(nil, func(k K, _ V) bool {
+		keys = append(keys, k)
+		return true
+	})
+	return keys
+}
+
+// Values returns a slice of all values.
+func (c *Client[K, V]) Values(_ *sync.RWMutex) []V {
+	var values []V
+	c.Range(nil, func(_ K, v V) bool {
+		values = append(values, v)
+		return true
+	})
+	return values
+}
+
+// Range calls fn sequentially for each entry of the map as of the call,
+// as it receives them. If fn returns false, Range stops the iteration.
+func (c *Client[K, V]) Range(_ *sync.RWMutex, fn func(key K, value V) bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	stream, err := c.stream(ctx, "List", &empty{})
+	if err != nil {
+		c.fail(err)
+		return
+	}
+	for {
+		var e entry
+		if err := stream.RecvMsg(&e); err != nil {
+			if !errors.Is(err, io.EOF) {
+				c.fail(err)
+			}
+			return
+		}
+		var (
+			k K
+			v V
+		)
+		if err := errors.Join(json.Unmarshal(e.key, &k), json.Unmarshal(e.value, &v)); err != nil {
+			c.fail(err)
+			return
+		}
+		if !fn(k, v) {
+			return
+		}
+	}
+}
+
+// Watch is like ValueMap.Watch for the remote map. It returns once the
+// server has subscribed, so the changes made afterwards are all received.
+// The channel is closed when ctx is done or the stream fails, in which
+// case the error is reported by Err.
+func (c *Client[K, V]) Watch(ctx context.Context) (<-chan valuemap.Event[K, V], error) {
+	return c.watch(ctx, &watchRequest{})
+}
+
+// WatchKey is like ValueMap.WatchKey for the remote map. See Watch.
+func (c *Client[K, V]) WatchKey(ctx context.Context, key K) (<-chan valuemap.Event[K, V], error) {
+	kb, err := json.Marshal(key)
+	if err != nil {
+		return nil, err
+	}
+	return c.watch(ctx, &watchRequest{key: kb, hasKey: true})
+}
+
+func (c *Client[K, V]) watch(ctx context.Context, req *watchRequest) (<-chan valuemap.Event[K, V], error) {
+	stream, err := c.stream(ctx, "Watch", req)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stream.Header(); err != nil {
+		return nil, err
+	}
+	out := make(chan valuemap.Event[K, V])
+	go func() {
+		defer close(out)
+		for {
+			var msg event
+			if err := stream.RecvMsg(&msg); err != nil {
+				if ctx.Err() == nil && !errors.Is(err, io.EOF) {
+					c.fail(err)
+				}
+				return
+			}
+			e, err := decodeEvent[K, V](&msg)
+			if err != nil {
+				c.fail(err)
+				return
+			}
+			select {
+			case out <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func decodeEvent[K comparable, V any](msg *event) (valuemap.Event[K, V], error) {
+	e := valuemap.Event[K, V]{
+		Kind:    valuemap.EventKind(msg.kind),
+		Existed: msg.existed,
+		Reason:  valuemap.RemovalReason(msg.reason),
+	}
+	var errs []error
+	if msg.key != nil {
+		errs = append(errs, json.Unmarshal(msg.key, &e.Key))
+	}
+	if msg.old != nil {
+		errs = append(errs, json.Unmarshal(msg.old, &e.Old))
+	}
+	if msg.new != nil {
+		errs = append(errs, json.Unmarshal(msg.new, &e.New))
+	}
+	return e, errors.Join(errs...)
+}
@@ -0,0 +1,19 @@
+module github.com/eaglebush/valuemap/valuemapgrpc
+
+go 1.24.2
+
+require (
+	github.com/eaglebush/valuemap v0.1.0
+	google.golang.org/grpc v1.59.0
+	google.golang.org/protobuf v1.31.0
+)
+
+require (
+	github.com/golang/protobuf v1.5.3 // indirect
+	golang.org/x/net v0.14.0 // indirect
+	golang.org/x/sys v0.32.0 // indirect
+	golang.org/x/text v0.12.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20230822172742-b8732ec3820d // indirect
+)
+
+replace github.com/eaglebush/valuemap => ../
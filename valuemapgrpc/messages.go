@@ -0,0 +1,305 @@
+package valuemapgrpc
+
+import (
+	"bytes"
+	"fmt"
+
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The messages of valuemap.proto are encoded by hand with protowire, so
+// that the package needs no generated code. They are marshaled by codec,
+// which is used in place of the default protobuf codec.
+
+// message is a message of valuemap.proto.
+type message interface {
+	marshal() []byte
+	unmarshal(b []byte) error
+}
+
+// codec marshals the messages of valuemap.proto, and any other message
+// with the default protobuf codec, so that it can be forced on a server
+// that also serves generated services. It keeps the name of the default
+// codec, since the wire format is the same.
+type codec struct{}
+
+func (codec) Marshal(v any) ([]byte, error) {
+	if m, ok := v.(message); ok {
+		return m.marshal(), nil
+	}
+	return encoding.GetCodec("proto").Marshal(v)
+}
+
+func (codec) Unmarshal(b []byte, v any) error {
+	if m, ok := v.(message); ok {
+		return m.unmarshal(b)
+	}
+	return encoding.GetCodec("proto").Unmarshal(b, v)
+}
+
+func (codec) Name() string {
+	return "proto"
+}
+
+func appendBytes(b []byte, num protowire.Number, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendBool(b []byte, num protowire.Number, v bool) []byte {
+	return appendVarint(b, num, protowire.EncodeBool(v))
+}
+
+// field is a field of an encoded message.
+type field struct {
+	num protowire.Number
+	typ protowire.Type
+	raw []byte
+}
+
+// fields splits an encoded message into its fields.
+func fields(b []byte) ([]field, error) {
+	var fs []field
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		m := protowire.ConsumeFieldValue(num, typ, b)
+		if m < 0 {
+			return nil, protowire.ParseError(m)
+		}
+		fs = append(fs, field{num: num, typ: typ, raw: b[:m]})
+		b = b[m:]
+	}
+	return fs, nil
+}
+
+func (f field) bytes() ([]byte, error) {
+	if f.typ != protowire.BytesType {
+		return nil, fmt.Errorf("valuemapgrpc: field %d is not bytes", f.num)
+	}
+	v, n := protowire.ConsumeBytes(f.raw)
+	if n < 0 {
+		return nil, protowire.ParseError(n)
+	}
+	return bytes.Clone(v), nil
+}
+
+func (f field) varint() (uint64, error) {
+	if f.typ != protowire.VarintType {
+		return 0, fmt.Errorf("valuemapgrpc: field %d is not a varint", f.num)
+	}
+	v, n := protowire.ConsumeVarint(f.raw)
+	if n < 0 {
+		return 0, protowire.ParseError(n)
+	}
+	return v, nil
+}
+
+func (f field) bool() (bool, error) {
+	v, err := f.varint()
+	return protowire.DecodeBool(v), err
+}
+
+// empty is a message without fields, such as SetResponse.
+type empty struct{}
+
+func (*empty) marshal() []byte { return nil }
+
+func (*empty) unmarshal(b []byte) error {
+	_, err := fields(b)
+	return err
+}
+
+// keyRequest is GetRequest or DeleteRequest.
+type keyRequest struct {
+	key []byte
+}
+
+func (m *keyRequest) marshal() []byte {
+	return appendBytes(nil, 1, m.key)
+}
+
+func (m *keyRequest) unmarshal(b []byte) error {
+	fs, err := fields(b)
+	for _, f := range fs {
+		if f.num == 1 && err == nil {
+			m.key, err = f.bytes()
+		}
+	}
+	return err
+}
+
+// getResponse is GetResponse.
+type getResponse struct {
+	value []byte
+	found bool
+}
+
+func (m *getResponse) marshal() []byte {
+	return appendBool(appendBytes(nil, 1, m.value), 2, m.found)
+}
+
+func (m *getResponse) unmarshal(b []byte) error {
+	fs, err := fields(b)
+	for _, f := range fs {
+		if err != nil {
+			break
+		}
+		switch f.num {
+		case 1:
+			m.value, err = f.bytes()
+		case 2:
+			m.found, err = f.bool()
+		}
+	}
+	return err
+}
+
+// entry is SetRequest or Entry.
+type entry struct {
+	key, value []byte
+}
+
+func (m *entry) marshal() []byte {
+	return appendBytes(appendBytes(nil, 1, m.key), 2, m.value)
+}
+
+func (m *entry) unmarshal(b []byte) error {
+	fs, err := fields(b)
+	for _, f := range fs {
+		if err != nil {
+			break
+		}
+		switch f.num {
+		case 1:
+			m.key, err = f.bytes()
+		case 2:
+			m.value, err = f.bytes()
+		}
+	}
+	return err
+}
+
+// deleteResponse is DeleteResponse.
+type deleteResponse struct {
+	found bool
+}
+
+func (m *deleteResponse) marshal() []byte {
+	return appendBool(nil, 1, m.found)
+}
+
+func (m *deleteResponse) unmarshal(b []byte) error {
+	fs, err := fields(b)
+	for _, f := range fs {
+		if f.num == 1 && err == nil {
+			m.found, err = f.bool()
+		}
+	}
+	return err
+}
+
+// lenResponse is LenResponse.
+type lenResponse struct {
+	n int64
+}
+
+func (m *lenResponse) marshal() []byte {
+	return appendVarint(nil, 1, uint64(m.n))
+}
+
+func (m *lenResponse) unmarshal(b []byte) error {
+	fs, err := fields(b)
+	for _, f := range fs {
+		if f.num == 1 && err == nil {
+			var v uint64
+			v, err = f.varint()
+			m.n = int64(v)
+		}
+	}
+	return err
+}
+
+// watchRequest is WatchRequest.
+type watchRequest struct {
+	key    []byte
+	hasKey bool
+}
+
+func (m *watchRequest) marshal() []byte {
+	return appendBool(appendBytes(nil, 1, m.key), 2, m.hasKey)
+}
+
+func (m *watchRequest) unmarshal(b []byte) error {
+	fs, err := fields(b)
+	for _, f := range fs {
+		if err != nil {
+			break
+		}
+		switch f.num {
+		case 1:
+			m.key, err = f.bytes()
+		case 2:
+			m.hasKey, err = f.bool()
+		}
+	}
+	return err
+}
+
+// event is Event. Its kind and reason take the values of
+// valuemap.EventKind and valuemap.RemovalReason.
+type event struct {
+	kind     uint64
+	key      []byte
+	old, new []byte
+	existed  bool
+	reason   uint64
+}
+
+func (m *event) marshal() []byte {
+	b := appendVarint(nil, 1, m.kind)
+	b = appendBytes(b, 2, m.key)
+	b = appendBytes(b, 3, m.old)
+	b = appendBytes(b, 4, m.new)
+	b = appendBool(b, 5, m.existed)
+	return appendVarint(b, 6, m.reason)
+}
+
+func (m *event) unmarshal(b []byte) error {
+	fs, err := fields(b)
+	for _, f := range fs {
+		if err != nil {
+			break
+		}
+		switch f.num {
+		case 1:
+			m.kind, err = f.varint()
+		case 2:
+			m.key, err = f.bytes()
+		case 3:
+			m.old, err = f.bytes()
+		case 4:
+			m.new, err = f.bytes()
+		case 5:
+			m.existed, err = f.bool()
+		case 6:
+			m.reason, err = f.varint()
+		}
+	}
+	return err
+}
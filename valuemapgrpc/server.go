@@ -0,0 +1,243 @@
+// Package valuemapgrpc serves a ValueMap over gRPC, so that one process can
+// hold a map and others can read, write and watch it.
+//
+// The service is described by valuemap.proto in this directory; clients in
+// other languages can be generated from it. Keys and values travel as their
+// JSON encoding. The messages are encoded by this package without generated
+// code, so servers must be created with ServerOption, which keeps serving
+// generated services on the same server as usual.
+package valuemapgrpc
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/eaglebush/valuemap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const serviceName = "valuemap.v1.ValueMap"
+
+// ServerOption returns the option a gRPC server must be created with to
+// serve a map registered with Register.
+func ServerOption() grpc.ServerOption {
+	return grpc.ForceServerCodec(codec{})
+}
+
+// service is the set of methods a server registered with Register has,
+// used by grpc to check the implementation against the service description.
+type service interface {
+	get(ctx context.Context, req *keyRequest) (*getResponse, error)
+	set(ctx context.Context, req *entry) (*empty, error)
+	delete(ctx context.Context, req *keyRequest) (*deleteResponse, error)
+	len(ctx context.Context, req *empty) (*lenResponse, error)
+	clear(ctx context.Context, req *empty) (*empty, error)
+	list(req *empty, stream grpc.ServerStream) error
+	watch(req *watchRequest, stream grpc.ServerStream) error
+}
+
+type server[K comparable, V any] struct {
+	m  *valuemap.ValueMap[K, V]
+	mu *sync.RWMutex
+}
+
+// Register registers the ValueMap service backed by m on s, which must
+// have been created with ServerOption.
+//
+// mu is an external mutex to lock the internal map during each call.
+// It must be the same mutex passed to the other methods of m
+func Register[K comparable, V any](s grpc.ServiceRegistrar, m *valuemap.ValueMap[K, V], mu *sync.RWMutex) {
+	s.RegisterService(&serviceDesc, &server[K, V]{m: m, mu: mu})
+}
+
+func decodeKey[K comparable](b []byte) (K, error) {
+	var key K
+	if err := json.Unmarshal(b, &key); err != nil {
+		return key, status.Errorf(codes.InvalidArgument, "invalid key: %v", err)
+	}
+	return key, nil
+}
+
+func (s *server[K, V]) get(ctx context.Context, req *keyRequest) (*getResponse, error) {
+	key, err := decodeKey[K](req.key)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := s.m.Get(s.mu, key)
+	if !ok {
+		return &getResponse{}, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "encoding value: %v", err)
+	}
+	return &getResponse{value: b, found: true}, nil
+}
+
+func (s *server[K, V]) set(ctx context.Context, req *entry) (*empty, error) {
+	key, err := decodeKey[K](req.key)
+	if err != nil {
+		return nil, err
+	}
+	var v V
+	if err := json.Unmarshal(req.value, &v); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid value: %v", err)
+	}
+	s.m.Set(s.mu, key, v)
+	return &empty{}, nil
+}
+
+func (s *server[K, V]) delete(ctx context.Context, req *keyRequest) (*deleteResponse, error) {
+	key, err := decodeKey[K](req.key)
+	if err != nil {
+		return nil, err
+	}
+	_, found := s.m.Pop(s.mu, key)
+	return &deleteResponse{found: found}, nil
+}
+
+func (s *server[K, V]) len(ctx context.Context, req *empty) (*lenResponse, error) {
+	return &lenResponse{n: int64(s.m.Len(s.mu))}, nil
+}
+
+func (s *server[K, V]) clear(ctx context.Context, req *empty) (*empty, error) {
+	s.m.Clear(s.mu)
+	return &empty{}, nil
+}
+
+func (s *server[K, V]) list(req *empty, stream grpc.ServerStream) error {
+	for k, v := range s.m.Raw(s.mu) {
+		e, err := encodeEntry(k, v)
+		if err != nil {
+			return err
+		}
+		if err := stream.SendMsg(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeEntry[K comparable, V any](k K, v V) (*entry, error) {
+	kb, err := json.Marshal(k)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "encoding key: %v", err)
+	}
+	vb, err := json.Marshal(v)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "encoding value: %v", err)
+	}
+	return &entry{key: kb, value: vb}, nil
+}
+
+func (s *server[K, V]) watch(req *watchRequest, stream grpc.ServerStream) error {
+	ctx := stream.Context()
+	var events <-chan valuemap.Event[K, V]
+	if req.hasKey {
+		key, err := decodeKey[K](req.key)
+		if err != nil {
+			return err
+		}
+		events = s.m.WatchKey(ctx, s.mu, key)
+	} else {
+		events = s.m.Watch(ctx, s.mu)
+	}
+	// Send the headers, so the client knows the subscription is in place.
+	if err := stream.SendHeader(nil); err != nil {
+		return err
+	}
+	for e := range events {
+		msg, err := encodeEvent(e)
+		if err != nil {
+			return err
+		}
+		if err := stream.SendMsg(msg); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+func encodeEvent[K comparable, V any](e valuemap.Event[K, V]) (*event, error) {
+	msg := &event{kind: uint64(e.Kind), existed: e.Existed, reason: uint64(e.Reason)}
+	if e.Kind == valuemap.EventClear {
+		return msg, nil
+	}
+	var err error
+	if msg.key, err = json.Marshal(e.Key); err != nil {
+		return nil, status.Errorf(codes.Internal, "encoding key: %v", err)
+	}
+	if e.Existed {
+		if msg.old, err = json.Marshal(e.Old); err != nil {
+			return nil, status.Errorf(codes.Internal, "encoding value: %v", err)
+		}
+	}
+	if e.Kind == valuemap.EventSet {
+		if msg.new, err = json.Marshal(e.New); err != nil {
+			return nil, status.Errorf(codes.Internal, "encoding value: %v", err)
+		}
+	}
+	return msg, nil
+}
+
+func unaryHandler[Req any, PReq interface {
+	*Req
+	message
+}](name string, call func(s service, ctx context.Context, req PReq) (any, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			req := PReq(new(Req))
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			if interceptor == nil {
+				return call(srv.(service), ctx, req)
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + serviceName + "/" + name}
+			return interceptor(ctx, req, info, func(ctx context.Context, req any) (any, error) {
+				return call(srv.(service), ctx, req.(PReq))
+			})
+		},
+	}
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*service)(nil),
+	Methods: []grpc.MethodDesc{
+		unaryHandler("Get", func(s service, ctx context.Context, req *keyRequest) (any, error) { return s.get(ctx, req) }),
+		unaryHandler("Set", func(s service, ctx context.Context, req *entry) (any, error) { return s.set(ctx, req) }),
+		unaryHandler("Delete", func(s service, ctx context.Context, req *keyRequest) (any, error) { return s.delete(ctx, req) }),
+		unaryHandler("Len", func(s service, ctx context.Context, req *empty) (any, error) { return s.len(ctx, req) }),
+		unaryHandler("Clear", func(s service, ctx context.Context, req *empty) (any, error) { return s.clear(ctx, req) }),
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "List",
+			ServerStreams: true,
+			Handler: func(srv any, stream grpc.ServerStream) error {
+				req := new(empty)
+				if err := stream.RecvMsg(req); err != nil {
+					return err
+				}
+				return srv.(service).list(req, stream)
+			},
+		},
+		{
+			StreamName:    "Watch",
+			ServerStreams: true,
+			Handler: func(srv any, stream grpc.ServerStream) error {
+				req := new(watchRequest)
+				if err := stream.RecvMsg(req); err != nil {
+					return err
+				}
+				return srv.(service).watch(req, stream)
+			},
+		},
+	},
+	Metadata: "valuemap.proto",
+}
@@ -0,0 +1,123 @@
+package valuemapgrpc
+
+import (
+	"context"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eaglebush/valuemap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func serve(t *testing.T, m *valuemap.ValueMap[string, int], mu *sync.RWMutex) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer(ServerOption())
+	Register(s, m, mu)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestClient(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := valuemap.FromMap(map[string]int{"a": 1})
+	c := NewClient[string, int](serve(t, m, &mu))
+
+	if v, ok := c.Get(nil, "a"); !ok || v != 1 {
+		t.Errorf("Get(a) = %d, %v, want 1, true", v, ok)
+	}
+	if _, ok := c.Get(nil, "missing"); ok {
+		t.Error("Get(missing) found a value")
+	}
+	c.Set(nil, "b", 2)
+	if v, _ := m.Get(&mu, "b"); v != 2 {
+		t.Errorf("remote Get(b) = %d, want 2", v)
+	}
+	if n := c.Len(nil); n != 2 {
+		t.Errorf("Len() = %d, want 2", n)
+	}
+	keys := c.Keys(nil)
+	slices.Sort(keys)
+	if !slices.Equal(keys, []string{"a", "b"}) {
+		t.Errorf("Keys() = %v, want [a b]", keys)
+	}
+	c.Delete(nil, "a")
+	if _, ok := m.Get(&mu, "a"); ok {
+		t.Error("deleted key a is present remotely")
+	}
+	c.Clear(nil)
+	if m.Len(&mu) != 0 {
+		t.Errorf("remote Len() = %d after Clear, want 0", m.Len(&mu))
+	}
+	if err := c.Err(); err != nil {
+		t.Errorf("Err() = %v", err)
+	}
+}
+
+func TestClientWatch(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := valuemap.New[string, int]()
+	c := NewClient[string, int](serve(t, m, &mu))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	all, err := c.Watch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	one, err := c.WatchKey(ctx, "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Set(&mu, "a", 1)
+	m.Set(&mu, "b", 2)
+	m.Clear(&mu)
+
+	want := []valuemap.Event[string, int]{
+		{Kind: valuemap.EventSet, Key: "a", New: 1},
+		{Kind: valuemap.EventSet, Key: "b", New: 2},
+		{Kind: valuemap.EventClear},
+	}
+	for i, w := range want {
+		select {
+		case e := <-all:
+			if e != w {
+				t.Errorf("event %d = %+v, want %+v", i, e, w)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("event %d not received", i)
+		}
+	}
+	if e := <-one; e.Key != "b" || e.Kind != valuemap.EventSet {
+		t.Errorf("key event = %+v, want the set of b", e)
+	}
+	if e := <-one; e.Kind != valuemap.EventDelete || e.Reason != valuemap.ReasonCleared {
+		t.Errorf("key event = %+v, want b deleted by the clear", e)
+	}
+}
+
+func TestInvalidKey(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := valuemap.New[string, int]()
+	conn := serve(t, m, &mu)
+	bad := NewClient[int, int](conn) // the server expects string keys
+	bad.Set(nil, 1, 1)
+	if bad.Err() == nil {
+		t.Error("Err() = nil after sending a key of the wrong type")
+	}
+}
@@ -0,0 +1,106 @@
+package valuemaphttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/eaglebush/valuemap"
+)
+
+// keepAlive is how often an idle event stream sends a comment,
+// so that proxies do not close it.
+const keepAlive = 30 * time.Second
+
+// event is the data of a Server-Sent Event.
+type event[K comparable, V any] struct {
+	Key    *K     `json:"key,omitempty"`
+	Old    *V     `json:"old,omitempty"`
+	New    *V     `json:"new,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Events returns a handler that streams the changes of m as Server-Sent
+// Events. Each event is named after its kind (set, delete or clear) and
+// its data is a JSON object with the key, the old value if there was one,
+// the new value for sets and the removal reason for deletions. Events are
+// numbered from 1 in their id field for each stream. If the request has a
+// key query parameter, only the events of that key are streamed, as with
+// ValueMap.WatchKey. A change whose key or values cannot be encoded is
+// sent as an error event, whose data is a JSON object with an error field.
+//
+// The stream starts with the changes made after the request is received,
+// so clients that need the current contents should fetch them once the
+// stream is open. It ends when the client disconnects.
+//
+// mu is an external mutex to lock the internal map during subscription.
+// It must be the same mutex passed to the other methods of m
+func Events[K comparable, V any](m *valuemap.ValueMap[K, V], mu *sync.RWMutex) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+		var events <-chan valuemap.Event[K, V]
+		if r.URL.Query().Has("key") {
+			key, err := parseKey[K](r.URL.Query().Get("key"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			events = m.WatchKey(r.Context(), mu, key)
+		} else {
+			events = m.Watch(r.Context(), mu)
+		}
+
+		h := w.Header()
+		h.Set("Content-Type", "text/event-stream")
+		h.Set("Cache-Control", "no-cache")
+		h.Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		t := time.NewTicker(keepAlive)
+		defer t.Stop()
+		for id := 1; ; {
+			select {
+			case e, ok := <-events:
+				if !ok {
+					return
+				}
+				data, err := json.Marshal(encodeEvent(e))
+				if err != nil {
+					data, _ = json.Marshal(map[string]string{"error": err.Error()})
+					fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+				} else {
+					fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", id, e.Kind, data)
+					id++
+				}
+			case <-t.C:
+				fmt.Fprint(w, ": keep-alive\n\n")
+			}
+			flusher.Flush()
+		}
+	})
+}
+
+func encodeEvent[K comparable, V any](e valuemap.Event[K, V]) event[K, V] {
+	var out event[K, V]
+	if e.Kind == valuemap.EventClear {
+		return out
+	}
+	out.Key = &e.Key
+	if e.Existed {
+		out.Old = &e.Old
+	}
+	switch e.Kind {
+	case valuemap.EventSet:
+		out.New = &e.New
+	case valuemap.EventDelete:
+		out.Reason = e.Reason.String()
+	}
+	return out
+}
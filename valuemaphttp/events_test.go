@@ -0,0 +1,98 @@
+package valuemaphttp
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eaglebush/valuemap"
+)
+
+// readEvent reads the next event of a stream, skipping comments.
+func readEvent(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && len(lines) > 0:
+			return strings.Join(lines, "\n")
+		case line == "" || strings.HasPrefix(line, ":"):
+		default:
+			lines = append(lines, line)
+		}
+	}
+}
+
+// open starts a stream. The headers are sent once the handler has
+// subscribed, so the changes made after open returns are streamed.
+func open(t *testing.T, url string) *bufio.Reader {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	return bufio.NewReader(resp.Body)
+}
+
+func TestEvents(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := valuemap.New[int, string]()
+	srv := httptest.NewServer(Events(m, &mu))
+	t.Cleanup(srv.Close) // runs after the cleanups of open close the streams
+
+	all := open(t, srv.URL)
+	one := open(t, srv.URL+"?key=2")
+	m.Set(&mu, 1, "one")
+	m.Set(&mu, 2, "two")
+	m.Delete(&mu, 1)
+	m.Clear(&mu)
+
+	want := []string{
+		"id: 1\nevent: set\ndata: {\"key\":1,\"new\":\"one\"}",
+		"id: 2\nevent: set\ndata: {\"key\":2,\"new\":\"two\"}",
+		"id: 3\nevent: delete\ndata: {\"key\":1,\"old\":\"one\",\"reason\":\"deleted\"}",
+		"id: 4\nevent: clear\ndata: {}",
+	}
+	for i, w := range want {
+		if got := readEvent(t, all); got != w {
+			t.Errorf("event %d = %q, want %q", i, got, w)
+		}
+	}
+	want = []string{
+		"id: 1\nevent: set\ndata: {\"key\":2,\"new\":\"two\"}",
+		"id: 2\nevent: delete\ndata: {\"key\":2,\"old\":\"two\",\"reason\":\"cleared\"}",
+	}
+	for i, w := range want {
+		if got := readEvent(t, one); got != w {
+			t.Errorf("key event %d = %q, want %q", i, got, w)
+		}
+	}
+}
+
+func TestEventsBadKey(t *testing.T) {
+	mu := sync.RWMutex{}
+	srv := httptest.NewServer(Events(valuemap.New[int, string](), &mu))
+	defer srv.Close()
+	client := http.Client{Timeout: time.Second}
+	resp, err := client.Get(srv.URL + "?key=x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", resp.StatusCode)
+	}
+}
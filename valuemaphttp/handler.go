@@ -0,0 +1,178 @@
+package valuemaphttp
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/eaglebush/valuemap"
+)
+
+const (
+	// defaultLimit and maxLimit bound the number of entries in a page.
+	defaultLimit = 100
+	maxLimit     = 1000
+
+	// maxBodySize is the largest request body accepted.
+	maxBodySize = 10 << 20
+)
+
+type handler[K comparable, V any] struct {
+	m           *valuemap.ValueMap[K, V]
+	mu          *sync.RWMutex
+	codec       valuemap.Codec[K, V]
+	contentType string
+}
+
+// Handler returns a handler that exposes the entries of m for reading and
+// editing. Bodies are maps encoded with c, and responses carry contentType
+// as their Content-Type:
+//
+//   - GET /keys returns a page of entries. Pages hold the entries in the
+//     order of the text form of their keys, starting after the key given
+//     in the after query parameter, and up to limit entries (100 by
+//     default, 1000 at most). If more entries follow, the Link header
+//     holds the query of the next page with rel="next".
+//   - GET /keys/{key} returns a map holding the entry of key,
+//     or 404 Not Found if key is not present.
+//   - PUT /keys/{key} assigns the value of the body, which must be a map
+//     holding an entry of key only. It returns 201 Created if key was not
+//     present and 204 No Content otherwise.
+//   - DELETE /keys/{key} removes key, returning 204 No Content,
+//     or 404 Not Found if key was not present.
+//
+// Listing sorts the keys of the whole map for each page, so it is meant for
+// inspection rather than for bulk reads of large maps. To serve the map
+// under a prefix, wrap the handler with http.StripPrefix.
+//
+// mu is an external mutex to lock the internal map during each request.
+// It must be the same mutex passed to the other methods of m
+func Handler[K comparable, V any](m *valuemap.ValueMap[K, V], mu *sync.RWMutex, c valuemap.Codec[K, V], contentType string) http.Handler {
+	h := &handler[K, V]{m: m, mu: mu, codec: c, contentType: contentType}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /keys", h.list)
+	mux.HandleFunc("GET /keys/{key}", h.get)
+	mux.HandleFunc("PUT /keys/{key}", h.put)
+	mux.HandleFunc("DELETE /keys/{key}", h.delete)
+	return mux
+}
+
+// write encodes data into the response. It is encoded completely first,
+// so that an encoding error is reported with a status instead of a
+// truncated body.
+func (h *handler[K, V]) write(w http.ResponseWriter, status int, data map[K]V) {
+	var buf bytes.Buffer
+	if err := h.codec.Encode(&buf, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", h.contentType)
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+// key returns the key of the request path, reporting a bad request if
+// it cannot be parsed.
+func (h *handler[K, V]) key(w http.ResponseWriter, r *http.Request) (K, bool) {
+	key, err := parseKey[K](r.PathValue("key"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return key, false
+	}
+	return key, true
+}
+
+func (h *handler[K, V]) list(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit := defaultLimit
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid limit "+strconv.Quote(s), http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxLimit)
+	}
+	after := q.Get("after")
+
+	type item struct {
+		text string
+		key  K
+	}
+	data := h.m.Raw(h.mu)
+	items := make([]item, 0, len(data))
+	for k := range data {
+		text, err := formatKey(k)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !q.Has("after") || text > after {
+			items = append(items, item{text, k})
+		}
+	}
+	slices.SortFunc(items, func(a, b item) int {
+		return strings.Compare(a.text, b.text)
+	})
+
+	page := make(map[K]V, min(limit, len(items)))
+	for _, it := range items[:min(limit, len(items))] {
+		page[it.key] = data[it.key]
+	}
+	if len(items) > limit {
+		next := url.Values{"after": {items[limit-1].text}, "limit": {strconv.Itoa(limit)}}
+		w.Header().Set("Link", "<?"+next.Encode()+`>; rel="next"`)
+	}
+	h.write(w, http.StatusOK, page)
+}
+
+func (h *handler[K, V]) get(w http.ResponseWriter, r *http.Request) {
+	key, ok := h.key(w, r)
+	if !ok {
+		return
+	}
+	v, ok := h.m.Get(h.mu, key)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	h.write(w, http.StatusOK, map[K]V{key: v})
+}
+
+func (h *handler[K, V]) put(w http.ResponseWriter, r *http.Request) {
+	key, ok := h.key(w, r)
+	if !ok {
+		return
+	}
+	data, err := h.codec.Decode(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	v, ok := data[key]
+	if !ok || len(data) != 1 {
+		http.Error(w, "body must hold the entry of "+strconv.Quote(r.PathValue("key"))+" only", http.StatusBadRequest)
+		return
+	}
+	if _, existed := h.m.Swap(h.mu, key, v); existed {
+		w.WriteHeader(http.StatusNoContent)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+func (h *handler[K, V]) delete(w http.ResponseWriter, r *http.Request) {
+	key, ok := h.key(w, r)
+	if !ok {
+		return
+	}
+	if _, ok := h.m.Pop(h.mu, key); !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,88 @@
+package valuemaphttp
+
+import (
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/eaglebush/valuemap"
+)
+
+func do(t *testing.T, h http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return rec
+}
+
+func TestHandler(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := valuemap.FromMap(map[string]int{"a": 1})
+	h := Handler(m, &mu, valuemap.JSONCodec[string, int]{}, "application/json")
+
+	rec := do(t, h, "GET", "/keys/a", "")
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != `{"a":1}` {
+		t.Errorf("GET /keys/a = %d %q", rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if rec := do(t, h, "GET", "/keys/missing", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("GET /keys/missing = %d, want 404", rec.Code)
+	}
+
+	if rec := do(t, h, "PUT", "/keys/a%2Fb", `{"a/b":2}`); rec.Code != http.StatusCreated {
+		t.Errorf("PUT new key = %d %q, want 201", rec.Code, rec.Body)
+	}
+	if rec := do(t, h, "PUT", "/keys/a", `{"a":3}`); rec.Code != http.StatusNoContent {
+		t.Errorf("PUT existing key = %d, want 204", rec.Code)
+	}
+	if rec := do(t, h, "PUT", "/keys/a", `{"b":3}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("PUT of another key = %d, want 400", rec.Code)
+	}
+	if got := m.Raw(&mu); !maps.Equal(got, map[string]int{"a": 3, "a/b": 2}) {
+		t.Errorf("map = %v after PUT", got)
+	}
+
+	if rec := do(t, h, "DELETE", "/keys/a", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("DELETE = %d, want 204", rec.Code)
+	}
+	if rec := do(t, h, "DELETE", "/keys/a", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("second DELETE = %d, want 404", rec.Code)
+	}
+}
+
+func TestHandlerList(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := valuemap.New[int, string]()
+	for i := range 5 {
+		m.Set(&mu, i*10, "v")
+	}
+	h := Handler(m, &mu, valuemap.JSONCodec[int, string]{}, "application/json")
+
+	var pages []string
+	query := "?limit=2"
+	for query != "" {
+		rec := do(t, h, "GET", "/keys"+query, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET /keys%s = %d %q", query, rec.Code, rec.Body)
+		}
+		pages = append(pages, strings.TrimSpace(rec.Body.String()))
+		query = ""
+		if link := rec.Header().Get("Link"); link != "" {
+			query = strings.TrimSuffix(strings.TrimPrefix(link, "<"), `>; rel="next"`)
+		}
+	}
+	// Keys are ordered by their text form.
+	want := []string{`{"0":"v","10":"v"}`, `{"20":"v","30":"v"}`, `{"40":"v"}`}
+	if strings.Join(pages, " ") != strings.Join(want, " ") {
+		t.Errorf("pages = %v, want %v", pages, want)
+	}
+
+	if rec := do(t, h, "GET", "/keys/x", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("GET of a non-int key = %d, want 400", rec.Code)
+	}
+}
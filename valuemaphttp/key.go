@@ -0,0 +1,41 @@
+// Package valuemaphttp serves ValueMaps over HTTP.
+//
+// Handler exposes the entries of a map for reading and editing, and
+// Events streams its changes as Server-Sent Events, so that browser
+// dashboards and sidecars can follow it without polling. Keys appear in
+// URLs as plain text: string keys as they are, other keys in their JSON
+// form, such as 42 for an int key.
+package valuemaphttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// parseKey converts the text form of a key used in URLs to a key.
+func parseKey[K comparable](s string) (K, error) {
+	var key K
+	v := reflect.ValueOf(&key).Elem()
+	if v.Kind() == reflect.String {
+		v.SetString(s)
+		return key, nil
+	}
+	if err := json.Unmarshal([]byte(s), &key); err != nil {
+		return key, fmt.Errorf("valuemaphttp: invalid key %q: %w", s, err)
+	}
+	return key, nil
+}
+
+// formatKey returns the text form of a key used in URLs.
+func formatKey[K comparable](key K) (string, error) {
+	v := reflect.ValueOf(&key).Elem()
+	if v.Kind() == reflect.String {
+		return v.String(), nil
+	}
+	b, err := json.Marshal(key)
+	if err != nil {
+		return "", fmt.Errorf("valuemaphttp: invalid key %v: %w", key, err)
+	}
+	return string(b), nil
+}
@@ -0,0 +1,12 @@
+module github.com/eaglebush/valuemap/valuemapmsgpack
+
+go 1.24.2
+
+require (
+	github.com/eaglebush/valuemap v0.1.0
+	github.com/vmihailenco/msgpack/v5 v5.4.1
+)
+
+require github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+
+replace github.com/eaglebush/valuemap => ../
@@ -0,0 +1,67 @@
+// Package valuemapmsgpack reads and writes ValueMaps as MessagePack
+// using github.com/vmihailenco/msgpack/v5.
+package valuemapmsgpack
+
+import (
+	"io"
+	"sync"
+
+	"github.com/eaglebush/valuemap"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Encode writes the contents of m to w as a MessagePack map.
+//
+// mu is an external mutex to lock the internal map during encoding
+func Encode[K comparable, V any](w io.Writer, m *valuemap.ValueMap[K, V], mu *sync.RWMutex) error {
+	return m.Encode(mu, w, Codec[K, V]{})
+}
+
+// Decode reads a MessagePack map from r and replaces the contents of m
+// with it. The map is decoded completely before m is changed, so on error
+// m is left as it was.
+//
+// mu is an external mutex to lock the internal map during content replacement
+func Decode[K comparable, V any](r io.Reader, m *valuemap.ValueMap[K, V], mu *sync.RWMutex) error {
+	return m.Decode(mu, r, Codec[K, V]{})
+}
+
+// Codec is a valuemap.Codec that writes the map as a MessagePack map.
+type Codec[K comparable, V any] struct{}
+
+// Encode implements valuemap.Codec.
+func (Codec[K, V]) Encode(w io.Writer, data map[K]V) error {
+	return msgpack.NewEncoder(w).Encode(data)
+}
+
+// Decode implements valuemap.Codec.
+func (Codec[K, V]) Decode(r io.Reader) (map[K]V, error) {
+	var data map[K]V
+	if err := msgpack.NewDecoder(r).Decode(&data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Map binds a ValueMap to its mutex so that it can be embedded in a struct
+// encoded with msgpack. It implements msgpack.CustomEncoder and
+// msgpack.CustomDecoder, locking Mu while the map is encoded or replaced.
+type Map[K comparable, V any] struct {
+	M  *valuemap.ValueMap[K, V]
+	Mu *sync.RWMutex
+}
+
+// EncodeMsgpack implements msgpack.CustomEncoder.
+func (t Map[K, V]) EncodeMsgpack(enc *msgpack.Encoder) error {
+	return enc.Encode(t.M.Raw(t.Mu))
+}
+
+// DecodeMsgpack implements msgpack.CustomDecoder.
+func (t Map[K, V]) DecodeMsgpack(dec *msgpack.Decoder) error {
+	var data map[K]V
+	if err := dec.Decode(&data); err != nil {
+		return err
+	}
+	t.M.Replace(t.Mu, data)
+	return nil
+}
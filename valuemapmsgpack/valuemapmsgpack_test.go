@@ -0,0 +1,51 @@
+package valuemapmsgpack
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/eaglebush/valuemap"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+var _ valuemap.Codec[int, string] = Codec[int, string]{}
+
+func TestEncodeDecode(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := valuemap.FromMap(map[int]string{1: "one", 2: "two"})
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, m, &mu); err != nil {
+		t.Fatal(err)
+	}
+	got := valuemap.New[int, string]()
+	if err := Decode(&buf, got, &mu); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := got.Get(&mu, 2); v != "two" {
+		t.Errorf("Get(2) = %q, want two", v)
+	}
+}
+
+func TestMap(t *testing.T) {
+	type message struct {
+		ID   int
+		Tags Map[string, int]
+	}
+
+	mu := sync.RWMutex{}
+	in := message{ID: 1, Tags: Map[string, int]{M: valuemap.FromMap(map[string]int{"a": 1}), Mu: &mu}}
+	b, err := msgpack.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := message{Tags: Map[string, int]{M: valuemap.New[string, int](), Mu: &mu}}
+	if err := msgpack.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := out.Tags.M.Get(&mu, "a"); v != 1 {
+		t.Errorf("Get(a) = %d, want 1", v)
+	}
+}
@@ -0,0 +1,156 @@
+// Package valuemapnats keeps a ValueMap in sync with a NATS JetStream
+// Key-Value bucket using github.com/nats-io/nats.go/jetstream, so that
+// several instances sharing the bucket converge on the same contents.
+//
+// A Mirror loads every key of the bucket into the map and then applies the
+// updates the bucket reports, including those of other instances. Local
+// changes are published with Set and Delete. Values are stored as JSON.
+//
+// The map remembers the revision of the last update applied to each key,
+// including deletions, so that the value Set stores locally never replaces
+// a later update the watcher applied first.
+package valuemapnats
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/eaglebush/valuemap"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Mirror keeps a ValueMap in sync with a JetStream Key-Value bucket.
+type Mirror[V any] struct {
+	kv      jetstream.KeyValue
+	m       *valuemap.ValueMap[string, V]
+	mu      *sync.RWMutex
+	watcher jetstream.KeyWatcher
+	done    chan struct{}
+
+	revMu sync.Mutex        // serializes changes to the map with revs
+	revs  map[string]uint64 // revision of the last update applied to each key
+
+	errMu sync.Mutex
+	err   error
+}
+
+// Open loads the keys of the bucket into m, replacing its contents, and
+// keeps m updated until the Mirror is closed.
+//
+// mu is an external mutex to lock the internal map during updates.
+// It must be the same mutex passed to the other methods of m
+func Open[V any](ctx context.Context, kv jetstream.KeyValue, m *valuemap.ValueMap[string, V], mu *sync.RWMutex) (*Mirror[V], error) {
+	w, err := kv.WatchAll(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	s := &Mirror[V]{kv: kv, m: m, mu: mu, watcher: w, done: make(chan struct{}), revs: make(map[string]uint64)}
+
+	// The watcher sends the current entries, then nil, then the updates.
+	data := make(map[string]V)
+	for {
+		var e jetstream.KeyValueEntry
+		select {
+		case e = <-w.Updates():
+		case <-ctx.Done():
+			w.Stop()
+			return nil, ctx.Err()
+		}
+		if e == nil {
+			break
+		}
+		s.revs[e.Key()] = e.Revision()
+		if e.Operation() != jetstream.KeyValuePut {
+			delete(data, e.Key())
+			continue
+		}
+		var v V
+		if err := json.Unmarshal(e.Value(), &v); err != nil {
+			w.Stop()
+			return nil, err
+		}
+		data[e.Key()] = v
+	}
+	m.Replace(mu, data)
+
+	go s.run()
+	return s, nil
+}
+
+func (s *Mirror[V]) run() {
+	defer close(s.done)
+	for e := range s.watcher.Updates() {
+		if e == nil {
+			continue
+		}
+		key := e.Key()
+		if e.Operation() != jetstream.KeyValuePut {
+			s.apply(key, e.Revision(), func() { s.m.Delete(s.mu, key) })
+			continue
+		}
+		var v V
+		if err := json.Unmarshal(e.Value(), &v); err != nil {
+			s.fail(err)
+			continue
+		}
+		s.apply(key, e.Revision(), func() { s.m.Set(s.mu, key, v) })
+	}
+}
+
+// apply makes an update of key at revision rev to the map, unless an
+// update of the same or a later revision was already applied.
+func (s *Mirror[V]) apply(key string, rev uint64, change func()) {
+	s.revMu.Lock()
+	defer s.revMu.Unlock()
+	if rev <= s.revs[key] {
+		return
+	}
+	s.revs[key] = rev
+	change()
+}
+
+// fail records the first error met by the background updates.
+func (s *Mirror[V]) fail(err error) {
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// Set puts a value into the bucket, then assigns it to the key in the
+// local map, unless the watcher already applied a later update of the key.
+// If the bucket fails, the local map is not changed.
+func (s *Mirror[V]) Set(ctx context.Context, key string, value V) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	rev, err := s.kv.Put(ctx, key, b)
+	if err != nil {
+		return err
+	}
+	s.apply(key, rev, func() { s.m.Set(s.mu, key, value) })
+	return nil
+}
+
+// Delete deletes a key from the bucket. The bucket does not tell the
+// revision of a deletion, so the key is removed from the local map when
+// the watcher reports it, shortly after Delete returns.
+func (s *Mirror[V]) Delete(ctx context.Context, key string) error {
+	return s.kv.Delete(ctx, key)
+}
+
+// Close stops following the bucket and returns the first error met while
+// updating the map in the background. The local map keeps its contents.
+func (s *Mirror[V]) Close() error {
+	err := s.watcher.Stop()
+	<-s.done
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	return err
+}
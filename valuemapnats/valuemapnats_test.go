@@ -0,0 +1,141 @@
+package valuemapnats
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eaglebush/valuemap"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+type entry struct {
+	jetstream.KeyValueEntry
+	key   string
+	value []byte
+	op    jetstream.KeyValueOp
+	rev   uint64
+}
+
+func (e entry) Key() string                     { return e.key }
+func (e entry) Value() []byte                   { return e.value }
+func (e entry) Operation() jetstream.KeyValueOp { return e.op }
+func (e entry) Revision() uint64                { return e.rev }
+
+type watcher struct {
+	updates chan jetstream.KeyValueEntry
+	once    sync.Once
+}
+
+func (w *watcher) Updates() <-chan jetstream.KeyValueEntry { return w.updates }
+
+func (w *watcher) Stop() error {
+	w.once.Do(func() { close(w.updates) })
+	return nil
+}
+
+// bucket is an in-memory Key-Value bucket with a single watcher.
+type bucket struct {
+	jetstream.KeyValue
+	mu       sync.Mutex
+	rev      uint64
+	data     map[string][]byte
+	w        *watcher
+	afterPut func() // called once a put is reported, before it returns
+}
+
+func (b *bucket) WatchAll(ctx context.Context, opts ...jetstream.WatchOpt) (jetstream.KeyWatcher, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.w = &watcher{updates: make(chan jetstream.KeyValueEntry, 16)}
+	for k, v := range b.data {
+		b.rev++
+		b.w.updates <- entry{key: k, value: v, op: jetstream.KeyValuePut, rev: b.rev}
+	}
+	b.w.updates <- nil
+	return b.w, nil
+}
+
+func (b *bucket) Put(ctx context.Context, key string, value []byte) (uint64, error) {
+	b.mu.Lock()
+	b.rev++
+	rev := b.rev
+	b.data[key] = value
+	b.w.updates <- entry{key: key, value: value, op: jetstream.KeyValuePut, rev: rev}
+	hook := b.afterPut
+	b.afterPut = nil
+	b.mu.Unlock()
+	if hook != nil {
+		hook()
+	}
+	return rev, nil
+}
+
+func (b *bucket) Delete(ctx context.Context, key string, opts ...jetstream.KVDeleteOpt) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rev++
+	delete(b.data, key)
+	b.w.updates <- entry{key: key, op: jetstream.KeyValueDelete, rev: b.rev}
+	return nil
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestMirror(t *testing.T) {
+	mu := sync.RWMutex{}
+	ctx := context.Background()
+	b := &bucket{data: map[string][]byte{"replicas": []byte("3")}}
+	m := valuemap.New[string, int]()
+
+	s, err := Open(ctx, b, m, &mu)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := m.Get(&mu, "replicas"); v != 3 {
+		t.Errorf("Get(replicas) = %d after Open, want 3", v)
+	}
+
+	// Another instance publishes a change.
+	b.Put(ctx, "timeout", []byte("30"))
+	waitFor(t, func() bool { v, _ := m.Get(&mu, "timeout"); return v == 30 })
+
+	// Another instance overwrites the key after our put, and the watcher
+	// applies both before Set returns: the newer value must stay.
+	b.afterPut = func() {
+		b.Put(ctx, "replicas", []byte("7"))
+		waitFor(t, func() bool { v, _ := m.Get(&mu, "replicas"); return v == 7 })
+	}
+	if err := s.Set(ctx, "replicas", 6); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := m.Get(&mu, "replicas"); v != 7 {
+		t.Errorf("Get(replicas) = %d, want the newer 7", v)
+	}
+
+	if err := s.Set(ctx, "replicas", 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(ctx, "timeout"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := m.Get(&mu, "replicas"); v != 5 {
+		t.Errorf("Get(replicas) = %d, want 5", v)
+	}
+	if _, ok := m.Get(&mu, "timeout"); ok {
+		t.Error("deleted key timeout is present")
+	}
+}
@@ -0,0 +1,82 @@
+// Package valuemapotel reports the operations of ValueMaps to OpenTelemetry
+// as metrics and trace spans.
+package valuemapotel
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const scope = "github.com/eaglebush/valuemap/valuemapotel"
+
+// Tracer is a valuemap.Tracer for one map, passed to it with
+// valuemap.WithTracing. It records:
+//
+//	valuemap.operation.duration   histogram of the duration of Get, Set and
+//	                              Delete in seconds, including the lock wait,
+//	                              by valuemap.operation
+//	valuemap.lookups              counter of Get calls by valuemap.hit
+//
+// and a span named after the method for each operation. Both carry the
+// name of the map as valuemap.name. The methods of a map take no context,
+// so the spans have no parent.
+type Tracer struct {
+	name     attribute.KeyValue
+	tracer   trace.Tracer
+	duration metric.Float64Histogram
+	lookups  metric.Int64Counter
+}
+
+// NewTracer returns a Tracer for the map called name, which records
+// metrics with mp and spans with tp. Either provider can be nil to skip
+// its kind of telemetry.
+func NewTracer(name string, tp trace.TracerProvider, mp metric.MeterProvider) (*Tracer, error) {
+	t := &Tracer{name: attribute.String("valuemap.name", name)}
+	if tp != nil {
+		t.tracer = tp.Tracer(scope)
+	}
+	if mp != nil {
+		meter := mp.Meter(scope)
+		var err error
+		t.duration, err = meter.Float64Histogram("valuemap.operation.duration",
+			metric.WithDescription("Duration of the operations on the map."),
+			metric.WithUnit("s"))
+		if err != nil {
+			return nil, err
+		}
+		t.lookups, err = meter.Int64Counter("valuemap.lookups",
+			metric.WithDescription("Lookups of keys in the map."),
+			metric.WithUnit("{lookup}"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// Start implements valuemap.Tracer.
+func (t *Tracer) Start(op string) func(found bool) {
+	ctx := context.Background()
+	start := time.Now()
+	var span trace.Span
+	if t.tracer != nil {
+		_, span = t.tracer.Start(ctx, "valuemap."+op, trace.WithAttributes(t.name))
+	}
+	return func(found bool) {
+		if t.duration != nil {
+			t.duration.Record(ctx, time.Since(start).Seconds(),
+				metric.WithAttributes(t.name, attribute.String("valuemap.operation", op)))
+			if op == "Get" {
+				t.lookups.Add(ctx, 1, metric.WithAttributes(t.name, attribute.Bool("valuemap.hit", found)))
+			}
+		}
+		if span != nil {
+			span.SetAttributes(attribute.Bool("valuemap.found", found))
+			span.End()
+		}
+	}
+}
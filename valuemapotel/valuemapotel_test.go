@@ -0,0 +1,78 @@
+package valuemapotel
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/eaglebush/valuemap"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+var _ valuemap.Tracer = (*Tracer)(nil)
+
+func TestTracer(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	spans := tracetest.NewSpanRecorder()
+	tr, err := NewTracer("sessions",
+		sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)),
+		sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mu := sync.RWMutex{}
+	m := valuemap.NewBounded[string, int](0, valuemap.WithTracing(tr))
+	m.Set(&mu, "a", 1)
+	m.Get(&mu, "a")
+	m.Get(&mu, "b")
+	m.Delete(&mu, "a")
+
+	var names []string
+	for _, s := range spans.Ended() {
+		names = append(names, s.Name())
+	}
+	if len(names) != 4 || names[0] != "valuemap.Set" || names[1] != "valuemap.Get" || names[3] != "valuemap.Delete" {
+		t.Errorf("spans = %v, want Set, Get, Get and Delete", names)
+	}
+	for _, kv := range spans.Ended()[2].Attributes() {
+		if kv.Key == "valuemap.found" && kv.Value.AsBool() {
+			t.Error("span of the missed Get has valuemap.found true")
+		}
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, md := range sm.Metrics {
+			switch data := md.Data.(type) {
+			case metricdata.Histogram[float64]:
+				for _, p := range data.DataPoints {
+					op, _ := p.Attributes.Value("valuemap.operation")
+					counts[op.AsString()] += int64(p.Count)
+				}
+			case metricdata.Sum[int64]:
+				for _, p := range data.DataPoints {
+					if hit, _ := p.Attributes.Value("valuemap.hit"); hit == attribute.BoolValue(true) {
+						counts["hits"] += p.Value
+					} else {
+						counts["misses"] += p.Value
+					}
+				}
+			}
+		}
+	}
+	want := map[string]int64{"Get": 2, "Set": 1, "Delete": 1, "hits": 1, "misses": 1}
+	for k, v := range want {
+		if counts[k] != v {
+			t.Errorf("%s = %d, want %d (all: %v)", k, counts[k], v, counts)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+// Package valuemapprom exports the size, operation counters and lock waits
+// of ValueMaps as Prometheus metrics using
+// github.com/prometheus/client_golang.
+package valuemapprom
+
+import (
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/eaglebush/valuemap"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector is a prometheus.Collector exporting the metrics of the maps
+// added to it, labeled with the name each map was added under:
+//
+//	valuemap_entries                number of entries
+//	valuemap_hits_total             lookups that found their key
+//	valuemap_misses_total           lookups that did not
+//	valuemap_evictions_total        entries evicted by a bounded map
+//	valuemap_expirations_total      entries removed when their lifetime ran out
+//	valuemap_lock_wait_seconds      time spent waiting for the external mutex,
+//	                                with a mode label of read or write
+//
+// The hit ratio of a map is the rate of its hits divided by the rate of
+// its hits and misses. Metric names are prefixed with the namespace passed
+// to NewCollector, if any.
+type Collector struct {
+	mu   sync.Mutex
+	maps map[string]source
+
+	entries     *prometheus.Desc
+	hits        *prometheus.Desc
+	misses      *prometheus.Desc
+	evictions   *prometheus.Desc
+	expirations *prometheus.Desc
+	lockWait    *prometheus.HistogramVec
+}
+
+// source reads the metrics of one map.
+type source struct {
+	len   func() int
+	stats func() valuemap.Stats
+	stop  func()
+}
+
+// NewCollector returns a Collector without maps, whose metric names are
+// prefixed with namespace unless it is empty.
+func NewCollector(namespace string) *Collector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "valuemap", name), help, []string{"map"}, nil)
+	}
+	return &Collector{
+		maps:        make(map[string]source),
+		entries:     desc("entries", "Number of entries in the map."),
+		hits:        desc("hits_total", "Lookups that found their key."),
+		misses:      desc("misses_total", "Lookups that did not find their key."),
+		evictions:   desc("evictions_total", "Entries evicted to keep the map within its limits."),
+		expirations: desc("expirations_total", "Entries removed because their lifetime ran out."),
+		lockWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "valuemap",
+			Name:      "lock_wait_seconds",
+			Help:      "Time spent waiting to lock the external mutex of the map.",
+			Buckets:   prometheus.ExponentialBuckets(1e-6, 4, 10),
+		}, []string{"map", "mode"}),
+	}
+}
+
+// Add adds m to the collector under name, and registers a lock wait
+// observer on it with ValueMap.ObserveLockWait, replacing any other.
+// It returns an error if a map was already added under name.
+//
+// mu is an external mutex to lock the internal map whenever the metrics
+// are collected
+func Add[K comparable, V any](c *Collector, name string, m *valuemap.ValueMap[K, V], mu *sync.RWMutex) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.maps[name]; ok {
+		return fmt.Errorf("valuemapprom: map %q is already added", name)
+	}
+	read := c.lockWait.WithLabelValues(name, "read")
+	write := c.lockWait.WithLabelValues(name, "write")
+	m.ObserveLockWait(func(w bool, wait time.Duration) {
+		if w {
+			write.Observe(wait.Seconds())
+		} else {
+			read.Observe(wait.Seconds())
+		}
+	})
+	c.maps[name] = source{
+		len:   func() int { return m.Len(mu) },
+		stats: func() valuemap.Stats { return m.Stats(false) },
+		stop:  func() { m.ObserveLockWait(nil) },
+	}
+	return nil
+}
+
+// Remove removes the map added under name from the collector, along with
+// its lock wait observer. It reports whether such a map was added.
+func (c *Collector) Remove(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.maps[name]
+	if !ok {
+		return false
+	}
+	s.stop()
+	delete(c.maps, name)
+	c.lockWait.DeletePartialMatch(prometheus.Labels{"map": name})
+	return true
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.entries
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.evictions
+	ch <- c.expirations
+	c.lockWait.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	sources := maps.Clone(c.maps)
+	c.mu.Unlock()
+
+	for name, s := range sources {
+		st := s.stats()
+		ch <- prometheus.MustNewConstMetric(c.entries, prometheus.GaugeValue, float64(s.len()), name)
+		ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(st.Hits), name)
+		ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(st.Misses), name)
+		ch <- prometheus.MustNewConstMetric(c.evictions, prometheus.CounterValue, float64(st.Evictions), name)
+		ch <- prometheus.MustNewConstMetric(c.expirations, prometheus.CounterValue, float64(st.Expirations), name)
+	}
+	c.lockWait.Collect(ch)
+}
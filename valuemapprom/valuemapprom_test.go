@@ -0,0 +1,61 @@
+package valuemapprom
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/eaglebush/valuemap"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := valuemap.NewBounded[string, int](2)
+	c := NewCollector("app")
+	if err := Add(c, "sessions", m, &mu); err != nil {
+		t.Fatal(err)
+	}
+	if err := Add(c, "sessions", m, &mu); err == nil {
+		t.Error("Add error = nil for a name already added")
+	}
+
+	m.Set(&mu, "a", 1)
+	m.Set(&mu, "b", 2)
+	m.Set(&mu, "c", 3) // evicts a
+	m.Get(&mu, "b")
+	m.Get(&mu, "a")
+
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(c)
+	want := `
+# HELP app_valuemap_entries Number of entries in the map.
+# TYPE app_valuemap_entries gauge
+app_valuemap_entries{map="sessions"} 2
+# HELP app_valuemap_evictions_total Entries evicted to keep the map within its limits.
+# TYPE app_valuemap_evictions_total counter
+app_valuemap_evictions_total{map="sessions"} 1
+# HELP app_valuemap_hits_total Lookups that found their key.
+# TYPE app_valuemap_hits_total counter
+app_valuemap_hits_total{map="sessions"} 1
+# HELP app_valuemap_misses_total Lookups that did not find their key.
+# TYPE app_valuemap_misses_total counter
+app_valuemap_misses_total{map="sessions"} 1
+`
+	err := testutil.GatherAndCompare(reg, strings.NewReader(want),
+		"app_valuemap_entries", "app_valuemap_evictions_total", "app_valuemap_hits_total", "app_valuemap_misses_total")
+	if err != nil {
+		t.Error(err)
+	}
+	if n := testutil.CollectAndCount(c, "app_valuemap_lock_wait_seconds"); n != 2 {
+		t.Errorf("%d lock wait histograms, want read and write", n)
+	}
+
+	if !c.Remove("sessions") {
+		t.Error("Remove(sessions) = false")
+	}
+	if n := testutil.CollectAndCount(c); n != 0 {
+		t.Errorf("%d metrics after Remove, want 0", n)
+	}
+}
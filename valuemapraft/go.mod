@@ -0,0 +1,23 @@
+module github.com/eaglebush/valuemap/valuemapraft
+
+go 1.24.2
+
+require (
+	github.com/eaglebush/valuemap v0.1.0
+	github.com/hashicorp/raft v1.7.3
+)
+
+require (
+	github.com/armon/go-metrics v0.4.1 // indirect
+	github.com/fatih/color v1.13.0 // indirect
+	github.com/hashicorp/go-hclog v1.6.2 // indirect
+	github.com/hashicorp/go-immutable-radix v1.0.0 // indirect
+	github.com/hashicorp/go-metrics v0.5.4 // indirect
+	github.com/hashicorp/go-msgpack/v2 v2.1.2 // indirect
+	github.com/hashicorp/golang-lru v0.5.0 // indirect
+	github.com/mattn/go-colorable v0.1.12 // indirect
+	github.com/mattn/go-isatty v0.0.14 // indirect
+	golang.org/x/sys v0.32.0 // indirect
+)
+
+replace github.com/eaglebush/valuemap => ../
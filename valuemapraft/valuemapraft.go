@@ -0,0 +1,242 @@
+// Package valuemapraft replicates a ValueMap across a cluster with
+// github.com/hashicorp/raft, giving a strongly consistent map: writes are
+// committed to a majority of the nodes before they return, and consistent
+// reads are served by a confirmed leader.
+//
+// FSM applies the replicated commands to the local ValueMap and snapshots
+// it; pass it to raft.NewRaft. Node wraps the resulting *raft.Raft with
+// Get, Set and Delete. Writes and consistent reads made on a follower are
+// forwarded to the leader through a Forwarder, which the application
+// implements over its own RPC mechanism by calling Serve on the leader.
+package valuemapraft
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/eaglebush/valuemap"
+	"github.com/hashicorp/raft"
+)
+
+// ErrNoLeader is returned when a request must be forwarded but the
+// cluster has no known leader, such as during an election.
+var ErrNoLeader = errors.New("valuemapraft: no leader")
+
+// Requests are an operation byte followed by the codec encoding of a map
+// holding the affected entry. Only set and delete requests enter the log.
+const (
+	opSet byte = iota + 1
+	opDelete
+	opGet
+)
+
+func encodeRequest[K comparable, V any](op byte, key K, value V, c valuemap.Codec[K, V]) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte(op)
+	if err := c.Encode(&buf, map[K]V{key: value}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeRequest[K comparable, V any](req []byte, c valuemap.Codec[K, V]) (op byte, key K, value V, err error) {
+	if len(req) == 0 {
+		return 0, key, value, errors.New("valuemapraft: empty request")
+	}
+	entry, err := c.Decode(bytes.NewReader(req[1:]))
+	if err != nil {
+		return 0, key, value, err
+	}
+	if len(entry) != 1 {
+		return 0, key, value, errors.New("valuemapraft: request does not hold one entry")
+	}
+	for k, v := range entry {
+		key, value = k, v
+	}
+	return req[0], key, value, nil
+}
+
+// FSM is a raft.FSM applying replicated commands to a ValueMap.
+type FSM[K comparable, V any] struct {
+	m     *valuemap.ValueMap[K, V]
+	mu    *sync.RWMutex
+	codec valuemap.Codec[K, V]
+}
+
+// NewFSM returns an FSM applying commands to m. Commands and snapshots
+// are encoded with c, which must be the same on every node.
+//
+// mu is an external mutex to lock the internal map while commands are
+// applied. It must be the same mutex passed to the other methods of m
+func NewFSM[K comparable, V any](m *valuemap.ValueMap[K, V], mu *sync.RWMutex, c valuemap.Codec[K, V]) *FSM[K, V] {
+	return &FSM[K, V]{m: m, mu: mu, codec: c}
+}
+
+// Apply implements raft.FSM. It returns nil or the error that kept the
+// command from being applied.
+func (f *FSM[K, V]) Apply(l *raft.Log) any {
+	op, key, value, err := decodeRequest(l.Data, f.codec)
+	if err != nil {
+		return err
+	}
+	switch op {
+	case opSet:
+		f.m.Set(f.mu, key, value)
+	case opDelete:
+		f.m.Delete(f.mu, key)
+	default:
+		return errors.New("valuemapraft: unknown command")
+	}
+	return nil
+}
+
+// Snapshot implements raft.FSM.
+func (f *FSM[K, V]) Snapshot() (raft.FSMSnapshot, error) {
+	return &snapshot[K, V]{data: f.m.Raw(f.mu), codec: f.codec}, nil
+}
+
+// Restore implements raft.FSM.
+func (f *FSM[K, V]) Restore(rc io.ReadCloser) error {
+	defer rc.Close()
+	return f.m.Decode(f.mu, rc, f.codec)
+}
+
+type snapshot[K comparable, V any] struct {
+	data  map[K]V
+	codec valuemap.Codec[K, V]
+}
+
+func (s *snapshot[K, V]) Persist(sink raft.SnapshotSink) error {
+	if err := s.codec.Encode(sink, s.data); err != nil {
+		sink.Cancel()
+		return err
+	}
+	return sink.Close()
+}
+
+func (s *snapshot[K, V]) Release() {}
+
+// Forwarder sends a request to the leader of the cluster, where it must be
+// passed to Node.Serve, and returns the response of Serve.
+type Forwarder interface {
+	Forward(ctx context.Context, leader raft.ServerAddress, req []byte) ([]byte, error)
+}
+
+// Node serves a replicated map on one node of a cluster.
+type Node[K comparable, V any] struct {
+	raft    *raft.Raft
+	fsm     *FSM[K, V]
+	forward Forwarder
+	timeout time.Duration
+}
+
+// NewNode returns a Node serving the map of fsm, which r was created with.
+// Requests made on a follower are sent to the leader with forward; if
+// forward is nil, they fail with raft.ErrNotLeader. timeout bounds how
+// long a request waits to enter the log.
+func NewNode[K comparable, V any](r *raft.Raft, fsm *FSM[K, V], forward Forwarder, timeout time.Duration) *Node[K, V] {
+	return &Node[K, V]{raft: r, fsm: fsm, forward: forward, timeout: timeout}
+}
+
+// Set assigns a value to a key on every node. It returns once the change
+// is committed and applied on the leader.
+func (n *Node[K, V]) Set(ctx context.Context, key K, value V) error {
+	req, err := encodeRequest(opSet, key, value, n.fsm.codec)
+	if err != nil {
+		return err
+	}
+	_, err = n.submit(ctx, req)
+	return err
+}
+
+// Delete removes a key on every node. It returns once the change is
+// committed and applied on the leader.
+func (n *Node[K, V]) Delete(ctx context.Context, key K) error {
+	var zero V
+	req, err := encodeRequest(opDelete, key, zero, n.fsm.codec)
+	if err != nil {
+		return err
+	}
+	_, err = n.submit(ctx, req)
+	return err
+}
+
+// Get retrieves a value by key from the leader, after every change
+// committed before the call has been applied, so it observes all
+// completed writes.
+func (n *Node[K, V]) Get(ctx context.Context, key K) (V, bool, error) {
+	var zero V
+	req, err := encodeRequest(opGet, key, zero, n.fsm.codec)
+	if err != nil {
+		return zero, false, err
+	}
+	resp, err := n.submit(ctx, req)
+	if err != nil || len(resp) == 0 {
+		return zero, false, err
+	}
+	_, _, v, err := decodeRequest(resp, n.fsm.codec)
+	if err != nil {
+		return zero, false, err
+	}
+	return v, true, nil
+}
+
+// LocalGet retrieves a value by key from the local copy of the map,
+// without contacting the leader. On a follower it may miss recent writes.
+func (n *Node[K, V]) LocalGet(key K) (V, bool) {
+	return n.fsm.m.Get(n.fsm.mu, key)
+}
+
+func (n *Node[K, V]) submit(ctx context.Context, req []byte) ([]byte, error) {
+	if n.raft.State() == raft.Leader {
+		return n.Serve(ctx, req)
+	}
+	if n.forward == nil {
+		return nil, raft.ErrNotLeader
+	}
+	leader, _ := n.raft.LeaderWithID()
+	if leader == "" {
+		return nil, ErrNoLeader
+	}
+	return n.forward.Forward(ctx, leader, req)
+}
+
+// Serve handles a request forwarded by another node. It fails with
+// raft.ErrNotLeader unless this node is the leader.
+func (n *Node[K, V]) Serve(ctx context.Context, req []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	op, key, _, err := decodeRequest(req, n.fsm.codec)
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case opSet, opDelete:
+		f := n.raft.Apply(req, n.timeout)
+		if err := f.Error(); err != nil {
+			return nil, err
+		}
+		if err, ok := f.Response().(error); ok {
+			return nil, err
+		}
+		return nil, nil
+	case opGet:
+		if err := n.raft.Barrier(n.timeout).Error(); err != nil {
+			return nil, err
+		}
+		if err := n.raft.VerifyLeader().Error(); err != nil {
+			return nil, err
+		}
+		v, ok := n.fsm.m.Get(n.fsm.mu, key)
+		if !ok {
+			return nil, nil
+		}
+		return encodeRequest(opGet, key, v, n.fsm.codec)
+	}
+	return nil, errors.New("valuemapraft: unknown request")
+}
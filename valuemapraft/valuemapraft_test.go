@@ -0,0 +1,160 @@
+package valuemapraft
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eaglebush/valuemap"
+	"github.com/hashicorp/raft"
+)
+
+type cluster struct {
+	nodes map[raft.ServerAddress]*Node[string, int]
+	rafts []*raft.Raft
+}
+
+func (c *cluster) Forward(ctx context.Context, leader raft.ServerAddress, req []byte) ([]byte, error) {
+	return c.nodes[leader].Serve(ctx, req)
+}
+
+func newCluster(t *testing.T, size int) *cluster {
+	t.Helper()
+	c := &cluster{nodes: make(map[raft.ServerAddress]*Node[string, int])}
+	var (
+		servers    []raft.Server
+		transports []*raft.InmemTransport
+	)
+	for i := range size {
+		addr, tr := raft.NewInmemTransport(raft.ServerAddress(fmt.Sprintf("node%d", i)))
+		transports = append(transports, tr)
+		servers = append(servers, raft.Server{ID: raft.ServerID(addr), Address: addr})
+	}
+	for _, a := range transports {
+		for _, b := range transports {
+			a.Connect(b.LocalAddr(), b)
+		}
+	}
+	for i, tr := range transports {
+		conf := raft.DefaultConfig()
+		conf.LocalID = servers[i].ID
+		conf.HeartbeatTimeout = 50 * time.Millisecond
+		conf.ElectionTimeout = 50 * time.Millisecond
+		conf.LeaderLeaseTimeout = 50 * time.Millisecond
+		conf.CommitTimeout = 5 * time.Millisecond
+		conf.LogOutput = io.Discard
+
+		logs, snaps := raft.NewInmemStore(), raft.NewInmemSnapshotStore()
+		if i == 0 {
+			if err := raft.BootstrapCluster(conf, logs, logs, snaps, tr, raft.Configuration{Servers: servers}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		fsm := NewFSM(valuemap.New[string, int](), &sync.RWMutex{}, valuemap.JSONCodec[string, int]{})
+		r, err := raft.NewRaft(conf, fsm, logs, logs, snaps, tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.rafts = append(c.rafts, r)
+		c.nodes[tr.LocalAddr()] = NewNode(r, fsm, c, time.Second)
+	}
+	t.Cleanup(func() {
+		for _, r := range c.rafts {
+			r.Shutdown().Error()
+		}
+	})
+
+	// Wait until every node knows the leader, so that followers can forward.
+	deadline := time.Now().Add(5 * time.Second)
+	for _, r := range c.rafts {
+		for {
+			if addr, _ := r.LeaderWithID(); addr != "" {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("no leader elected")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	return c
+}
+
+func (c *cluster) follower(t *testing.T) *Node[string, int] {
+	t.Helper()
+	for _, n := range c.nodes {
+		if n.raft.State() == raft.Follower {
+			return n
+		}
+	}
+	t.Fatal("no follower")
+	return nil
+}
+
+func TestCluster(t *testing.T) {
+	ctx := context.Background()
+	c := newCluster(t, 3)
+	f := c.follower(t)
+
+	if err := f.Set(ctx, "a", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Set(ctx, "b", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Delete(ctx, "b"); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok, err := f.Get(ctx, "a"); err != nil || !ok || v != 1 {
+		t.Errorf("Get(a) = %d, %v, %v, want 1, true, nil", v, ok, err)
+	}
+	if _, ok, err := f.Get(ctx, "b"); err != nil || ok {
+		t.Errorf("Get(b) = _, %v, %v, want false, nil", ok, err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for _, n := range c.nodes {
+		for {
+			if v, _ := n.LocalGet("a"); v == 1 {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("write was not replicated to every node")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+func TestFSMSnapshot(t *testing.T) {
+	mu := sync.RWMutex{}
+	c := valuemap.JSONCodec[string, int]{}
+	src := NewFSM(valuemap.FromMap(map[string]int{"a": 1}), &mu, c)
+	snap, err := src.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := raft.NewInmemSnapshotStore()
+	sink, err := store.Create(raft.SnapshotVersionMax, 1, 1, raft.Configuration{}, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := snap.Persist(sink); err != nil {
+		t.Fatal(err)
+	}
+
+	_, rc, err := store.Open(sink.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := valuemap.New[string, int]()
+	if err := NewFSM(m, &mu, c).Restore(rc); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := m.Get(&mu, "a"); v != 1 {
+		t.Errorf("Get(a) = %d after Restore, want 1", v)
+	}
+}
@@ -0,0 +1,209 @@
+// Package valuemapredis mirrors a Redis hash into a ValueMap using
+// github.com/redis/go-redis/v9, so that several services can share the
+// same hot map while reading it from local memory.
+//
+// Writes made through a Mirror go to Redis first and then to the local map
+// (write-through). Changes made by others are picked up from Redis keyspace
+// notifications, which must be enabled on the server for hash and generic
+// commands, for example with
+//
+//	CONFIG SET notify-keyspace-events Khg
+//
+// Notifications do not tell which field changed, so each one reloads the
+// whole hash and applies the fields that differ from the local map.
+// Mirrors are therefore meant for small, read-mostly hashes.
+// Values are stored in the hash as JSON.
+//
+// Reloads and the writes of Set and Delete are serialized, so they reach
+// the local map in the order Redis applied them: a reload never brings
+// back a value a later local write replaced, and a local write never
+// replaces a newer value a reload brought in.
+package valuemapredis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/eaglebush/valuemap"
+	"github.com/redis/go-redis/v9"
+)
+
+// hashClient is the part of a Redis client a Mirror writes with.
+type hashClient interface {
+	HGetAll(ctx context.Context, key string) *redis.MapStringStringCmd
+	HSet(ctx context.Context, key string, values ...any) *redis.IntCmd
+	HDel(ctx context.Context, key string, fields ...string) *redis.IntCmd
+}
+
+// Mirror keeps a ValueMap in sync with a Redis hash.
+type Mirror[V any] struct {
+	client hashClient
+	key    string
+	m      *valuemap.ValueMap[string, V]
+	mu     *sync.RWMutex
+	pubsub *redis.PubSub
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	writeMu sync.Mutex // serializes reloads and local writes
+
+	errMu sync.Mutex
+	err   error
+}
+
+// Open loads the hash stored at key into m, replacing its contents, and
+// keeps m updated until the Mirror is closed. The client may be a single
+// node, sentinel or cluster client.
+//
+// mu is an external mutex to lock the internal map during updates.
+// It must be the same mutex passed to the other methods of m
+func Open[V any](ctx context.Context, client redis.UniversalClient, key string, m *valuemap.ValueMap[string, V], mu *sync.RWMutex) (*Mirror[V], error) {
+	// Subscribe before loading, so no change is missed in between.
+	pubsub := client.PSubscribe(ctx, "__keyspace@*__:"+escapeGlob(key))
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+	s, err := newMirror(ctx, client, key, m, mu, pubsub.Channel())
+	if err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+	s.pubsub = pubsub
+	return s, nil
+}
+
+func newMirror[V any](ctx context.Context, client hashClient, key string, m *valuemap.ValueMap[string, V], mu *sync.RWMutex, events <-chan *redis.Message) (*Mirror[V], error) {
+	s := &Mirror[V]{client: client, key: key, m: m, mu: mu, done: make(chan struct{})}
+	if err := s.Reload(ctx); err != nil {
+		return nil, err
+	}
+	ctx, s.cancel = context.WithCancel(context.Background())
+	go s.run(ctx, events)
+	return s, nil
+}
+
+func (s *Mirror[V]) run(ctx context.Context, events <-chan *redis.Message) {
+	defer close(s.done)
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := s.Reload(ctx); err != nil {
+				s.fail(err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// escapeGlob escapes the characters of s that are special in Redis
+// glob-style patterns, so that the pattern only matches s.
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`*?[]\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// fail records the first error met by the background updates.
+func (s *Mirror[V]) fail(err error) {
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// Reload updates the local map to hold the contents of the hash. Only the
+// fields added, removed or changed since the map last matched the hash are
+// applied, in one step, so watchers, revisions and history of the map see
+// those changes alone. Values are compared by their JSON encoding. Reload
+// is called for every keyspace notification.
+func (s *Mirror[V]) Reload(ctx context.Context) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	fields, err := s.client.HGetAll(ctx, s.key).Result()
+	if err != nil {
+		return err
+	}
+	data := make(map[string]V, len(fields))
+	for f, raw := range fields {
+		var v V
+		if err := json.Unmarshal([]byte(raw), &v); err != nil {
+			return err
+		}
+		data[f] = v
+	}
+	var dataMu sync.RWMutex
+	c := s.m.DiffFunc(s.mu, valuemap.FromMap(data), &dataMu, sameJSON[V])
+	if !c.Empty() {
+		s.m.ApplyPatch(s.mu, c)
+	}
+	return nil
+}
+
+// sameJSON reports whether a and b have the same JSON encoding.
+func sameJSON[V any](a, b V) bool {
+	ja, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	jb, err := json.Marshal(b)
+	return err == nil && bytes.Equal(ja, jb)
+}
+
+// Set assigns a value to a field of the hash, then to the key of the same
+// name in the local map. If Redis fails, the local map is not changed.
+func (s *Mirror[V]) Set(ctx context.Context, field string, value V) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if err := s.client.HSet(ctx, s.key, field, string(b)).Err(); err != nil {
+		return err
+	}
+	s.m.Set(s.mu, field, value)
+	return nil
+}
+
+// Delete removes a field from the hash, then the key of the same name from
+// the local map. If Redis fails, the local map is not changed.
+func (s *Mirror[V]) Delete(ctx context.Context, field string) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if err := s.client.HDel(ctx, s.key, field).Err(); err != nil {
+		return err
+	}
+	s.m.Delete(s.mu, field)
+	return nil
+}
+
+// Close stops following the hash and returns the first error met while
+// reloading it in the background. The local map keeps its contents.
+func (s *Mirror[V]) Close() error {
+	s.cancel()
+	<-s.done
+	var err error
+	if s.pubsub != nil {
+		err = s.pubsub.Close()
+	}
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	return err
+}
@@ -0,0 +1,169 @@
+package valuemapredis
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eaglebush/valuemap"
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeHash is an in-memory hash standing in for Redis.
+type fakeHash struct {
+	mu       sync.Mutex
+	fields   map[string]string
+	fail     bool
+	afterSet func() // called once a field is set, before HSet returns
+}
+
+func (h *fakeHash) HGetAll(ctx context.Context, key string) *redis.MapStringStringCmd {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return redis.NewMapStringStringResult(maps.Clone(h.fields), nil)
+}
+
+func (h *fakeHash) HSet(ctx context.Context, key string, values ...any) *redis.IntCmd {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.fail {
+		return redis.NewIntResult(0, errors.New("connection refused"))
+	}
+	for i := 0; i < len(values); i += 2 {
+		h.fields[values[i].(string)] = values[i+1].(string)
+	}
+	if h.afterSet != nil {
+		h.afterSet()
+		h.afterSet = nil
+	}
+	return redis.NewIntResult(int64(len(values)/2), nil)
+}
+
+func (h *fakeHash) HDel(ctx context.Context, key string, fields ...string) *redis.IntCmd {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, f := range fields {
+		delete(h.fields, f)
+	}
+	return redis.NewIntResult(int64(len(fields)), nil)
+}
+
+func TestMirror(t *testing.T) {
+	mu := sync.RWMutex{}
+	ctx := context.Background()
+	h := &fakeHash{fields: map[string]string{"limit": "10"}}
+	events := make(chan *redis.Message)
+	m := valuemap.New[string, int]()
+
+	s, err := newMirror(ctx, h, "flags", m, &mu, events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if v, _ := m.Get(&mu, "limit"); v != 10 {
+		t.Errorf("Get(limit) = %d after Open, want 10", v)
+	}
+
+	if err := s.Set(ctx, "burst", 5); err != nil {
+		t.Fatal(err)
+	}
+	if h.fields["burst"] != "5" {
+		t.Errorf("hash field burst = %q, want 5", h.fields["burst"])
+	}
+	if err := s.Delete(ctx, "limit"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.Get(&mu, "limit"); ok {
+		t.Error("deleted key limit is present locally")
+	}
+
+	h.fail = true
+	if err := s.Set(ctx, "burst", 6); err == nil {
+		t.Error("Set() error = nil, want the Redis error")
+	}
+	if v, _ := m.Get(&mu, "burst"); v != 5 {
+		t.Errorf("Get(burst) = %d after failed Set, want 5", v)
+	}
+
+	// Another service changes the hash.
+	h.mu.Lock()
+	h.fields["other"] = "7"
+	h.mu.Unlock()
+	events <- &redis.Message{Channel: "__keyspace@0__:flags", Payload: "hset"}
+	waitFor(t, func() bool { v, _ := m.Get(&mu, "other"); return v == 7 })
+
+	// Another service overwrites the field right after our write, and the
+	// reload it triggers must not be undone by the local write.
+	h.fail = false
+	h.afterSet = func() {
+		h.fields["burst"] = "9"
+		go func() { events <- &redis.Message{Channel: "__keyspace@0__:flags", Payload: "hset"} }()
+	}
+	if err := s.Set(ctx, "burst", 8); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { v, _ := m.Get(&mu, "burst"); return v == 9 })
+}
+
+func TestEscapeGlob(t *testing.T) {
+	if got, want := escapeGlob(`cfg:*[a]?\x`), `cfg:\*\[a\]\?\\x`; got != want {
+		t.Errorf("escapeGlob() = %s, want %s", got, want)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestMirrorReloadAppliesChanges(t *testing.T) {
+	mu := sync.RWMutex{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	h := &fakeHash{fields: map[string]string{"limit": "10", "burst": "5"}}
+	events := make(chan *redis.Message)
+	m := valuemap.New[string, int]()
+
+	s, err := newMirror(ctx, h, "flags", m, &mu, events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	changes := m.Watch(ctx, &mu)
+
+	h.mu.Lock()
+	h.fields["burst"] = "6"
+	delete(h.fields, "limit")
+	h.mu.Unlock()
+	events <- &redis.Message{Channel: "__keyspace@0__:flags", Payload: "hset"}
+
+	got := map[string]valuemap.EventKind{}
+	for len(got) < 2 {
+		select {
+		case e := <-changes:
+			if _, ok := got[e.Key]; ok || e.Kind == valuemap.EventClear {
+				t.Fatalf("unexpected event %+v", e)
+			}
+			got[e.Key] = e.Kind
+		case <-time.After(2 * time.Second):
+			t.Fatalf("events = %v, want a set of burst and a delete of limit", got)
+		}
+	}
+	if got["burst"] != valuemap.EventSet || got["limit"] != valuemap.EventDelete {
+		t.Errorf("events = %v, want a set of burst and a delete of limit", got)
+	}
+	select {
+	case e := <-changes:
+		t.Errorf("unexpected event %+v", e)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
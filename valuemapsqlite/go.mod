@@ -0,0 +1,10 @@
+module github.com/eaglebush/valuemap/valuemapsqlite
+
+go 1.24.2
+
+require (
+	github.com/eaglebush/valuemap v0.1.0
+	github.com/mattn/go-sqlite3 v1.14.32
+)
+
+replace github.com/eaglebush/valuemap => ../
@@ -0,0 +1,121 @@
+// Package valuemapsqlite provides a valuemap.Backend that stores the
+// entries of a valuemap.DiskValueMap in a SQLite table, so they are durable
+// and can be inspected with the sqlite3 shell. The table is
+//
+//	CREATE TABLE name (
+//		key        BLOB PRIMARY KEY,
+//		value      BLOB NOT NULL,
+//		updated_at TEXT NOT NULL
+//	)
+//
+// where value holds the entry encoded by the valuemap.Codec of the map and
+// updated_at the UTC time of the last write as "YYYY-MM-DD HH:MM:SS.SSS".
+//
+// The package works with any database/sql driver for SQLite 3.24 or later,
+// such as github.com/mattn/go-sqlite3 or modernc.org/sqlite; import the
+// driver and open the database with sql.Open.
+package valuemapsqlite
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/eaglebush/valuemap"
+)
+
+// Backend is a valuemap.Backend storing entries in a SQLite table.
+// Entries are ranged over in byte order of their keys.
+type Backend struct {
+	db                            *sql.DB
+	get, put, del, list, truncate string
+}
+
+// New returns a Backend storing entries in the named table of db,
+// creating the table if it does not exist. Closing the Backend leaves
+// db open.
+func New(db *sql.DB, table string) (*Backend, error) {
+	t := `"` + strings.ReplaceAll(table, `"`, `""`) + `"`
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS ` + t + ` (
+		key        BLOB PRIMARY KEY,
+		value      BLOB NOT NULL,
+		updated_at TEXT NOT NULL
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	return &Backend{
+		db:  db,
+		get: `SELECT value FROM ` + t + ` WHERE key = ?`,
+		put: `INSERT INTO ` + t + ` (key, value, updated_at)
+			VALUES (?, ?, strftime('%Y-%m-%d %H:%M:%f', 'now'))
+			ON CONFLICT (key) DO UPDATE
+			SET value = excluded.value, updated_at = excluded.updated_at`,
+		del:      `DELETE FROM ` + t + ` WHERE key = ?`,
+		list:     `SELECT key, value FROM ` + t + ` ORDER BY key`,
+		truncate: `DELETE FROM ` + t,
+	}, nil
+}
+
+// NewMap returns a DiskValueMap storing its entries in the named table
+// of db, encoded with c.
+func NewMap[K comparable, V any](db *sql.DB, table string, c valuemap.Codec[K, V]) (*valuemap.DiskValueMap[K, V], error) {
+	b, err := New(db, table)
+	if err != nil {
+		return nil, err
+	}
+	return valuemap.NewDisk(b, c), nil
+}
+
+// Get implements valuemap.Backend.
+func (b *Backend) Get(key []byte) (value []byte, ok bool, err error) {
+	err = b.db.QueryRow(b.get, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// Put implements valuemap.Backend.
+func (b *Backend) Put(key, value []byte) error {
+	_, err := b.db.Exec(b.put, key, value)
+	return err
+}
+
+// Delete implements valuemap.Backend.
+func (b *Backend) Delete(key []byte) error {
+	_, err := b.db.Exec(b.del, key)
+	return err
+}
+
+// Range implements valuemap.Backend.
+func (b *Backend) Range(fn func(key, value []byte) bool) error {
+	rows, err := b.db.Query(b.list)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key, value []byte
+		if err := rows.Scan(&key, &value); err != nil {
+			return err
+		}
+		if !fn(key, value) {
+			break
+		}
+	}
+	return rows.Err()
+}
+
+// Clear implements valuemap.Backend.
+func (b *Backend) Clear() error {
+	_, err := b.db.Exec(b.truncate)
+	return err
+}
+
+// Close implements valuemap.Backend. It does not close the database.
+func (b *Backend) Close() error {
+	return nil
+}
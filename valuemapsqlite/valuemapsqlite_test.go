@@ -0,0 +1,71 @@
+package valuemapsqlite
+
+import (
+	"database/sql"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/eaglebush/valuemap"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var _ valuemap.Backend = (*Backend)(nil)
+
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "data.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Ping(); err != nil {
+		t.Skipf("sqlite3 driver unavailable: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestMap(t *testing.T) {
+	mu := sync.RWMutex{}
+	db := openDB(t)
+
+	m, err := NewMap(db, "tokens", valuemap.JSONCodec[string, string]{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Set(&mu, "bob", "t1")
+	m.Set(&mu, "alice", "t2")
+	m.Set(&mu, "bob", "t3")
+	m.Delete(&mu, "carol")
+	if v, ok := m.Get(&mu, "bob"); !ok || v != "t3" {
+		t.Errorf("Get(bob) = %q, %v, want t3, true", v, ok)
+	}
+	if keys := m.Keys(&mu); !slices.Equal(keys, []string{"alice", "bob"}) {
+		t.Errorf("Keys() = %v, want [alice bob]", keys)
+	}
+
+	var key, updated string
+	if err := db.QueryRow(`SELECT CAST(key AS TEXT), updated_at FROM tokens WHERE key = CAST('bob' AS BLOB)`).Scan(&key, &updated); err != nil {
+		t.Fatal(err)
+	}
+	if len(updated) != len("2006-01-02 15:04:05.000") {
+		t.Errorf("updated_at = %q, want a timestamp", updated)
+	}
+
+	m.Delete(&mu, "bob")
+	m2, err := NewMap(db, "tokens", valuemap.JSONCodec[string, string]{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m2.Len(&mu) != 1 {
+		t.Errorf("Len() = %d, want 1", m2.Len(&mu))
+	}
+	m2.Clear(&mu)
+	if m2.Len(&mu) != 0 {
+		t.Errorf("Len() = %d after Clear, want 0", m2.Len(&mu))
+	}
+	if err := m2.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
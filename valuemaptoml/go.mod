@@ -0,0 +1,10 @@
+module github.com/eaglebush/valuemap/valuemaptoml
+
+go 1.24.2
+
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/eaglebush/valuemap v0.1.0
+)
+
+replace github.com/eaglebush/valuemap => ../
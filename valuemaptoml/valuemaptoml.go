@@ -0,0 +1,158 @@
+// Package valuemaptoml reads and writes string-keyed ValueMaps as TOML
+// documents using github.com/BurntSushi/toml.
+package valuemaptoml
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"github.com/eaglebush/valuemap"
+)
+
+// Encode writes the contents of m to w as a TOML document.
+//
+// mu is an external mutex to lock the internal map during encoding
+func Encode[V any](w io.Writer, m *valuemap.ValueMap[string, V], mu *sync.RWMutex) error {
+	return m.Encode(mu, w, Codec[V]{})
+}
+
+// Decode reads a TOML document from r and replaces the contents of m with
+// its top-level keys. The document is decoded completely before m is changed,
+// so on error m is left as it was.
+//
+// mu is an external mutex to lock the internal map during content replacement
+func Decode[V any](r io.Reader, m *valuemap.ValueMap[string, V], mu *sync.RWMutex) error {
+	return m.Decode(mu, r, Codec[V]{})
+}
+
+// Codec is a valuemap.Codec that writes the map as a TOML document.
+type Codec[V any] struct{}
+
+// Encode implements valuemap.Codec.
+func (Codec[V]) Encode(w io.Writer, data map[string]V) error {
+	return toml.NewEncoder(w).Encode(data)
+}
+
+// Decode implements valuemap.Codec.
+func (Codec[V]) Decode(r io.Reader) (map[string]V, error) {
+	var data map[string]V
+	if _, err := toml.NewDecoder(r).Decode(&data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Map binds a ValueMap to its mutex so that it can be embedded in a struct
+// encoded with github.com/BurntSushi/toml. It implements toml.Marshaler and
+// toml.Unmarshaler, locking Mu while the map is encoded or replaced.
+type Map[V any] struct {
+	M  *valuemap.ValueMap[string, V]
+	Mu *sync.RWMutex
+}
+
+// MarshalTOML implements toml.Marshaler. The encoder writes the result as
+// the value of the field, so the map is written as an inline table.
+func (t Map[V]) MarshalTOML() ([]byte, error) {
+	// Encode and decode the map to get its values in generic form.
+	var buf bytes.Buffer
+	if err := Encode(&buf, t.M, t.Mu); err != nil {
+		return nil, err
+	}
+	var data map[string]any
+	if _, err := toml.NewDecoder(&buf).Decode(&data); err != nil {
+		return nil, err
+	}
+	var out strings.Builder
+	if err := inline(&out, data); err != nil {
+		return nil, err
+	}
+	return []byte(out.String()), nil
+}
+
+var bareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// inline writes a generic TOML value on a single line, with tables as
+// inline tables.
+func inline(w *strings.Builder, v any) error {
+	switch v := v.(type) {
+	case map[string]any:
+		w.WriteString("{")
+		keys := slices.Sorted(maps.Keys(v))
+		for i, k := range keys {
+			if i > 0 {
+				w.WriteString(",")
+			}
+			w.WriteString(" ")
+			if bareKey.MatchString(k) {
+				w.WriteString(k)
+			} else if err := scalar(w, k); err != nil {
+				return err
+			}
+			w.WriteString(" = ")
+			if err := inline(w, v[k]); err != nil {
+				return err
+			}
+		}
+		if len(keys) > 0 {
+			w.WriteString(" ")
+		}
+		w.WriteString("}")
+	case []map[string]any:
+		w.WriteString("[")
+		for i, e := range v {
+			if i > 0 {
+				w.WriteString(", ")
+			}
+			if err := inline(w, e); err != nil {
+				return err
+			}
+		}
+		w.WriteString("]")
+	case []any:
+		w.WriteString("[")
+		for i, e := range v {
+			if i > 0 {
+				w.WriteString(", ")
+			}
+			if err := inline(w, e); err != nil {
+				return err
+			}
+		}
+		w.WriteString("]")
+	default:
+		return scalar(w, v)
+	}
+	return nil
+}
+
+// scalar writes a string, number, boolean or date with the TOML encoder,
+// so that it is quoted and formatted as the encoder does.
+func scalar(w *strings.Builder, v any) error {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(map[string]any{"v": v}); err != nil {
+		return err
+	}
+	s, ok := strings.CutPrefix(strings.TrimSuffix(buf.String(), "\n"), "v = ")
+	if !ok {
+		return fmt.Errorf("valuemaptoml: cannot encode %T inline", v)
+	}
+	w.WriteString(s)
+	return nil
+}
+
+// UnmarshalTOML implements toml.Unmarshaler. The decoder passes the table
+// as generic values, so it is encoded again and decoded into the value type.
+func (t Map[V]) UnmarshalTOML(data any) error {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(data); err != nil {
+		return err
+	}
+	return Decode(&buf, t.M, t.Mu)
+}
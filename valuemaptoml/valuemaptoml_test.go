@@ -0,0 +1,93 @@
+package valuemaptoml
+
+import (
+	"bytes"
+	"maps"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/eaglebush/valuemap"
+)
+
+type server struct {
+	Host string `toml:"host"`
+	Port int    `toml:"port"`
+}
+
+var _ valuemap.Codec[string, int] = Codec[int]{}
+
+func TestEncodeDecode(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := valuemap.FromMap(map[string]server{"api": {Host: "localhost", Port: 8080}})
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, m, &mu); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "[api]") {
+		t.Errorf("Encode = %q", buf.String())
+	}
+
+	got := valuemap.New[string, server]()
+	if err := Decode(&buf, got, &mu); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := got.Get(&mu, "api"); v.Port != 8080 {
+		t.Errorf("Get(api) = %+v", v)
+	}
+}
+
+func TestMap(t *testing.T) {
+	mu := sync.RWMutex{}
+	var cfg struct {
+		Name    string   `toml:"name"`
+		Servers Map[int] `toml:"ports"`
+	}
+	cfg.Servers = Map[int]{M: valuemap.New[string, int](), Mu: &mu}
+
+	doc := "name = \"svc\"\n\n[ports]\nhttp = 80\nhttps = 443\n"
+	if _, err := toml.Decode(doc, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := cfg.Servers.M.Get(&mu, "https"); v != 443 {
+		t.Errorf("Get(https) = %d, want 443", v)
+	}
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(cfg); err != nil {
+		t.Fatal(err)
+	}
+	out := cfg
+	out.Servers = Map[int]{M: valuemap.New[string, int](), Mu: &mu}
+	if _, err := toml.Decode(buf.String(), &out); err != nil {
+		t.Fatalf("decoding %q: %v", buf.String(), err)
+	}
+	if got := out.Servers.M.Raw(&mu); !maps.Equal(got, map[string]int{"http": 80, "https": 443}) {
+		t.Errorf("decoded %v from %q", got, buf.String())
+	}
+}
+
+func TestMapNested(t *testing.T) {
+	mu := sync.RWMutex{}
+	type config struct {
+		Servers Map[server] `toml:"servers"`
+	}
+	in := config{Servers: Map[server]{M: valuemap.FromMap(map[string]server{
+		"api":         {Host: "localhost", Port: 8080},
+		"db \"main\"": {Host: "db.internal", Port: 5432},
+	}), Mu: &mu}}
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(in); err != nil {
+		t.Fatal(err)
+	}
+	out := config{Servers: Map[server]{M: valuemap.New[string, server](), Mu: &mu}}
+	if _, err := toml.Decode(buf.String(), &out); err != nil {
+		t.Fatalf("decoding %q: %v", buf.String(), err)
+	}
+	if got, want := out.Servers.M.Raw(&mu), in.Servers.M.Raw(&mu); !maps.Equal(got, want) {
+		t.Errorf("decoded %v, want %v", got, want)
+	}
+}
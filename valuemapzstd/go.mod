@@ -0,0 +1,10 @@
+module github.com/eaglebush/valuemap/valuemapzstd
+
+go 1.24.2
+
+require (
+	github.com/eaglebush/valuemap v0.1.0
+	github.com/klauspost/compress v1.18.0
+)
+
+replace github.com/eaglebush/valuemap => ../
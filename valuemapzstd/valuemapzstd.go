@@ -0,0 +1,49 @@
+// Package valuemapzstd compresses ValueMap snapshots and journals with
+// Zstandard using github.com/klauspost/compress/zstd. It is a separate
+// module so that only programs using zstd depend on it; the valuemap
+// package itself offers gzip through valuemap.GzipCodec.
+package valuemapzstd
+
+import (
+	"io"
+
+	"github.com/eaglebush/valuemap"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codec is a valuemap.Codec that compresses the output of another
+// valuemap.Codec with Zstandard. It can be used wherever a valuemap.Codec
+// is accepted, such as SaveToFile, WithAutosave and WithJournal.
+type Codec[K comparable, V any] struct {
+	Codec valuemap.Codec[K, V]
+	// Level is the compression level.
+	// Zero means zstd.SpeedDefault.
+	Level zstd.EncoderLevel
+}
+
+// Encode implements valuemap.Codec.
+func (c Codec[K, V]) Encode(w io.Writer, data map[K]V) error {
+	level := c.Level
+	if level == 0 {
+		level = zstd.SpeedDefault
+	}
+	zw, err := zstd.NewWriter(w, zstd.WithEncoderLevel(level))
+	if err != nil {
+		return err
+	}
+	if err := c.Codec.Encode(zw, data); err != nil {
+		zw.Close()
+		return err
+	}
+	return zw.Close()
+}
+
+// Decode implements valuemap.Codec.
+func (c Codec[K, V]) Decode(r io.Reader) (map[K]V, error) {
+	zr, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return c.Codec.Decode(zr)
+}
@@ -0,0 +1,41 @@
+package valuemapzstd
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/eaglebush/valuemap"
+)
+
+var _ valuemap.Codec[int, string] = Codec[int, string]{}
+
+func TestCodec(t *testing.T) {
+	mu := sync.RWMutex{}
+	data := make(map[int]string)
+	for i := range 100 {
+		data[i] = strings.Repeat("value ", 20)
+	}
+	m := valuemap.FromMap(data)
+	c := Codec[int, string]{Codec: valuemap.JSONCodec[int, string]{}}
+
+	var plain, packed bytes.Buffer
+	if err := m.Encode(&mu, &plain, valuemap.JSONCodec[int, string]{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Encode(&mu, &packed, c); err != nil {
+		t.Fatal(err)
+	}
+	if packed.Len()*5 > plain.Len() {
+		t.Errorf("compressed size = %d, want at most a fifth of %d", packed.Len(), plain.Len())
+	}
+
+	got := valuemap.New[int, string]()
+	if err := got.Decode(&mu, &packed, c); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := got.Get(&mu, 7); v != data[7] {
+		t.Errorf("Get(7) = %q, want %q", v, data[7])
+	}
+}
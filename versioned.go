@@ -0,0 +1,95 @@
+package valuemap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrSnapshotVersion is returned when a snapshot cannot be brought to the
+// version of a VersionedCodec, because it is newer or a migration is missing.
+var ErrSnapshotVersion = errors.New("valuemap: unsupported snapshot version")
+
+// snapshotMagic starts every snapshot written by a VersionedCodec.
+var snapshotMagic = [4]byte{'V', 'M', 'A', 'P'}
+
+const snapshotHeaderSize = 8
+
+// Migration converts the payload of a snapshot from one format version
+// to the next.
+type Migration func(old []byte) ([]byte, error)
+
+// VersionedCodec is a Codec that prefixes the output of another Codec with
+// a header holding a format version. When the value type or the inner codec
+// changes in a way old snapshots cannot be decoded with, increase the
+// version and register a Migration from the previous one, so files written
+// by earlier releases remain loadable.
+//
+// The header is the four bytes "VMAP" and the version as a 4-byte
+// little-endian integer. Input without the header is treated as version 0,
+// which lets a VersionedCodec read files saved with its inner codec alone.
+type VersionedCodec[K comparable, V any] struct {
+	codec      Codec[K, V]
+	version    int
+	migrations map[int]Migration
+}
+
+// NewVersionedCodec returns a VersionedCodec writing format version
+// version with c. version must not be negative.
+func NewVersionedCodec[K comparable, V any](c Codec[K, V], version int) *VersionedCodec[K, V] {
+	if version < 0 {
+		panic("valuemap: negative snapshot version")
+	}
+	return &VersionedCodec[K, V]{codec: c, version: version, migrations: make(map[int]Migration)}
+}
+
+// RegisterMigration registers fn to convert payloads of version from to
+// version from+1. It must be called before the codec is used.
+func (vc *VersionedCodec[K, V]) RegisterMigration(from int, fn Migration) {
+	vc.migrations[from] = fn
+}
+
+// Version returns the format version the codec writes.
+func (vc *VersionedCodec[K, V]) Version() int {
+	return vc.version
+}
+
+// Encode implements Codec.
+func (vc *VersionedCodec[K, V]) Encode(w io.Writer, data map[K]V) error {
+	var h [snapshotHeaderSize]byte
+	copy(h[:], snapshotMagic[:])
+	binary.LittleEndian.PutUint32(h[4:], uint32(vc.version))
+	if _, err := w.Write(h[:]); err != nil {
+		return err
+	}
+	return vc.codec.Encode(w, data)
+}
+
+// Decode implements Codec. Payloads of older versions are passed through
+// the registered migrations, in order, before they are decoded.
+func (vc *VersionedCodec[K, V]) Decode(r io.Reader) (map[K]V, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	version := 0
+	if len(b) >= snapshotHeaderSize && bytes.Equal(b[:4], snapshotMagic[:]) {
+		version = int(binary.LittleEndian.Uint32(b[4:]))
+		b = b[snapshotHeaderSize:]
+	}
+	if version > vc.version {
+		return nil, fmt.Errorf("%w: %d is newer than %d", ErrSnapshotVersion, version, vc.version)
+	}
+	for ; version < vc.version; version++ {
+		fn, ok := vc.migrations[version]
+		if !ok {
+			return nil, fmt.Errorf("%w: no migration from %d", ErrSnapshotVersion, version)
+		}
+		if b, err = fn(b); err != nil {
+			return nil, fmt.Errorf("valuemap: migrating snapshot from version %d: %w", version, err)
+		}
+	}
+	return vc.codec.Decode(bytes.NewReader(b))
+}
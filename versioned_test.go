@@ -0,0 +1,81 @@
+package valuemap
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestVersionedCodec(t *testing.T) {
+	mu := sync.RWMutex{}
+	path := filepath.Join(t.TempDir(), "data.snap")
+
+	// Version 0 stored counts as strings, without a header.
+	old := FromMap(map[string]string{"a": "1", "b": "2"})
+	if err := old.SaveToFile(&mu, path, JSONCodec[string, string]{}); err != nil {
+		t.Fatal(err)
+	}
+	// Version 1 has the same payload. Version 2 stores counts as integers.
+	same := func(b []byte) ([]byte, error) { return b, nil }
+	v1 := NewVersionedCodec[string, string](JSONCodec[string, string]{}, 1)
+	v1.RegisterMigration(0, same)
+	if err := old.LoadFromFile(&mu, path, v1); err != nil {
+		t.Fatal(err)
+	}
+	if err := old.SaveToFile(&mu, path, v1); err != nil {
+		t.Fatal(err)
+	}
+
+	v2 := NewVersionedCodec[string, int](JSONCodec[string, int]{}, 2)
+	v2.RegisterMigration(0, same)
+	v2.RegisterMigration(1, func(b []byte) ([]byte, error) {
+		var data map[string]string
+		if err := json.Unmarshal(b, &data); err != nil {
+			return nil, err
+		}
+		out := make(map[string]int, len(data))
+		for k, v := range data {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = n
+		}
+		return json.Marshal(out)
+	})
+
+	m := New[string, int]()
+	if err := m.LoadFromFile(&mu, path, v2); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := m.Get(&mu, "b"); v != 2 {
+		t.Errorf("Get(b) = %d, want 2", v)
+	}
+
+	if err := m.SaveToFile(&mu, path, v2); err != nil {
+		t.Fatal(err)
+	}
+	if err := old.LoadFromFile(&mu, path, v1); !errors.Is(err, ErrSnapshotVersion) {
+		t.Errorf("loading newer snapshot: error = %v, want ErrSnapshotVersion", err)
+	}
+}
+
+func TestVersionedCodecMissingMigration(t *testing.T) {
+	var buf bytes.Buffer
+	v1 := NewVersionedCodec[string, int](JSONCodec[string, int]{}, 1)
+	if err := v1.Encode(&buf, map[string]int{"a": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("VMAP\x01\x00\x00\x00")) {
+		t.Errorf("header = %q, want VMAP and version 1", buf.Bytes()[:8])
+	}
+	v3 := NewVersionedCodec[string, int](JSONCodec[string, int]{}, 3)
+	v3.RegisterMigration(1, func(b []byte) ([]byte, error) { return b, nil })
+	if _, err := v3.Decode(&buf); !errors.Is(err, ErrSnapshotVersion) {
+		t.Errorf("Decode() error = %v, want ErrSnapshotVersion", err)
+	}
+}
@@ -0,0 +1,52 @@
+package valuemap
+
+import (
+	"context"
+	"sync"
+)
+
+// WaitForKey returns the value of key, waiting until it is assigned if it
+// is not present. It returns the error of ctx if ctx is done first, and
+// ErrClosed if the map is closed first. Producers and consumers can meet
+// on a key without a condition variable of their own:
+//
+//	go func() { m.Set(&mu, "config", load()) }()
+//	cfg, err := m.WaitForKey(ctx, &mu, "config")
+//
+// Any assignment of the key ends the wait, including one by a loader, and
+// the value assigned is returned even if the key is removed again before
+// WaitForKey returns.
+//
+// mu is an external mutex to lock the internal map during the lookup and
+// the subscription to the key. It is not held while waiting
+func (m *ValueMap[K, V]) WaitForKey(ctx context.Context, mu *sync.RWMutex, key K) (V, error) {
+	var zero V
+	w := &watcher[K, V]{ready: make(chan struct{}, 1), key: &key}
+	unlock := m.readLock(mu).Unlock
+	if v, ok := m.lookup(key); ok {
+		unlock()
+		return v, nil
+	}
+	hub := m.hub()
+	added := hub.add(w)
+	unlock()
+	if !added {
+		return zero, ErrClosed
+	}
+	defer hub.remove(w)
+
+	for {
+		select {
+		case <-w.ready:
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		case <-hub.done:
+			return zero, ErrClosed
+		}
+		for _, e := range w.take() {
+			if e.Kind == EventSet {
+				return e.New, nil
+			}
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package valuemap
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWaitForKey(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := New[string, int]()
+	m.Set(&mu, "a", 1)
+	if v, err := m.WaitForKey(context.Background(), &mu, "a"); v != 1 || err != nil {
+		t.Errorf("WaitForKey(a) = %d, %v, want the present 1", v, err)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		m.Set(&mu, "c", 3)
+		m.Delete(&mu, "c")
+		m.Set(&mu, "b", 2)
+	}()
+	if v, err := m.WaitForKey(context.Background(), &mu, "b"); v != 2 || err != nil {
+		t.Errorf("WaitForKey(b) = %d, %v, want 2", v, err)
+	}
+	if n := len(m.watch.Load().byKey); n != 0 {
+		t.Errorf("%d watched keys left", n)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := m.WaitForKey(ctx, &mu, "missing"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitForKey error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestWaitForKeyClosed(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := New[string, int]()
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		m.Close()
+	}()
+	if _, err := m.WaitForKey(context.Background(), &mu, "a"); err != ErrClosed {
+		t.Errorf("WaitForKey error = %v, want ErrClosed", err)
+	}
+	if _, err := m.WaitForKey(context.Background(), &mu, "a"); err != ErrClosed {
+		t.Errorf("WaitForKey error = %v after Close, want ErrClosed", err)
+	}
+}
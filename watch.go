@@ -0,0 +1,252 @@
+package valuemap
+
+import (
+	"context"
+	"sync"
+)
+
+// EventKind tells how a map changed.
+type EventKind int
+
+const (
+	// EventSet means a key was assigned a value.
+	EventSet EventKind = iota + 1
+	// EventDelete means a key was removed.
+	EventDelete
+	// EventClear means all entries were removed at once.
+	EventClear
+)
+
+// String returns the name of the kind.
+func (k EventKind) String() string {
+	switch k {
+	case EventSet:
+		return "set"
+	case EventDelete:
+		return "delete"
+	case EventClear:
+		return "clear"
+	}
+	return "unknown"
+}
+
+// Event describes a change of a map.
+type Event[K comparable, V any] struct {
+	Kind    EventKind
+	Key     K             // the changed key, zero for EventClear
+	Old     V             // the previous value, if Existed
+	New     V             // the assigned value, for EventSet
+	Existed bool          // the key had a value before the change
+	Reason  RemovalReason // why the key was removed, for EventDelete
+}
+
+// watchers holds the subscriptions of a map, those to all keys and those
+// to a single key. Its lock is taken under the write lock of the map by
+// emit, and without it by subscriptions ending.
+type watchers[K comparable, V any] struct {
+	mu     sync.Mutex
+	all    map[*watcher[K, V]]struct{}
+	byKey  map[K]map[*watcher[K, V]]struct{}
+	closed bool
+	done   chan struct{} // closed by Close to end the subscriptions
+}
+
+func newWatchers[K comparable, V any]() *watchers[K, V] {
+	return &watchers[K, V]{
+		all:   make(map[*watcher[K, V]]struct{}),
+		byKey: make(map[K]map[*watcher[K, V]]struct{}),
+		done:  make(chan struct{}),
+	}
+}
+
+// add adds a subscription and reports whether it was added, which it is
+// not once the map is closed.
+func (h *watchers[K, V]) add(w *watcher[K, V]) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return false
+	}
+	if w.key == nil {
+		h.all[w] = struct{}{}
+		return true
+	}
+	ws := h.byKey[*w.key]
+	if ws == nil {
+		ws = make(map[*watcher[K, V]]struct{})
+		h.byKey[*w.key] = ws
+	}
+	ws[w] = struct{}{}
+	return true
+}
+
+// close ends all subscriptions and refuses new ones.
+func (h *watchers[K, V]) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.closed {
+		h.closed = true
+		close(h.done)
+	}
+}
+
+func (h *watchers[K, V]) remove(w *watcher[K, V]) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if w.key == nil {
+		delete(h.all, w)
+		return
+	}
+	ws := h.byKey[*w.key]
+	delete(ws, w)
+	if len(ws) == 0 {
+		delete(h.byKey, *w.key)
+	}
+}
+
+// watcher queues the events of one subscription until they are delivered,
+// so that writers never wait for slow receivers.
+type watcher[K comparable, V any] struct {
+	mu    sync.Mutex
+	queue []Event[K, V]
+	ready chan struct{} // signaled when queue becomes non-empty
+	key   *K            // the watched key, nil for all keys
+}
+
+func (w *watcher[K, V]) push(e Event[K, V]) {
+	w.mu.Lock()
+	w.queue = append(w.queue, e)
+	w.mu.Unlock()
+	select {
+	case w.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (w *watcher[K, V]) take() []Event[K, V] {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	q := w.queue
+	w.queue = nil
+	return q
+}
+
+// Watch returns a channel that receives an Event for every change of the
+// map made after Watch returns, in the order they were made, until ctx is
+// done or the map is closed, after which the channel is closed. Replace
+// and LoadFromFile are reported as a clear followed by a set of every entry.
+//
+// Events are queued for each receiver, so writers never wait on them, but
+// a receiver that stops reading before ctx is done keeps its queue growing.
+// Expired entries are reported as deleted with ReasonExpired when they are
+// removed, which is when the janitor or DeleteExpired runs, or when their
+// key is written to, in which case the deletion precedes the set. Entries
+// evicted from a bounded map are reported after the set that evicted them,
+// even when the entry set is the one evicted.
+//
+// mu is an external mutex to lock the internal map during subscription
+func (m *ValueMap[K, V]) Watch(ctx context.Context, mu *sync.RWMutex) <-chan Event[K, V] {
+	return m.subscribe(ctx, mu, nil)
+}
+
+// WatchKey is like Watch, but the channel only receives the events of key.
+// Clear is reported as the deletion of key with ReasonCleared, and only
+// when key was present. Subscriptions to a single key are indexed by key,
+// so any number of them can be held without slowing down writes to other
+// keys.
+//
+// mu is an external mutex to lock the internal map during subscription
+func (m *ValueMap[K, V]) WatchKey(ctx context.Context, mu *sync.RWMutex, key K) <-chan Event[K, V] {
+	return m.subscribe(ctx, mu, &key)
+}
+
+func (m *ValueMap[K, V]) subscribe(ctx context.Context, mu *sync.RWMutex, key *K) <-chan Event[K, V] {
+	w := &watcher[K, V]{ready: make(chan struct{}, 1), key: key}
+	mu.Lock()
+	hub := m.hub()
+	added := hub.add(w)
+	mu.Unlock()
+
+	out := make(chan Event[K, V])
+	if !added {
+		close(out)
+		return out
+	}
+	go func() {
+		defer close(out)
+		defer hub.remove(w)
+		for {
+			select {
+			case <-w.ready:
+			case <-ctx.Done():
+				return
+			case <-hub.done:
+				return
+			}
+			for _, e := range w.take() {
+				select {
+				case out <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// emit delivers an event to the subscriptions of the map.
+// The caller must hold the write lock.
+func (m *ValueMap[K, V]) emit(e Event[K, V]) {
+	if m.audit != nil {
+		m.record(e)
+	}
+	h := m.watch.Load()
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for w := range h.all {
+		w.push(e)
+	}
+	if e.Kind != EventClear {
+		for w := range h.byKey[e.Key] {
+			w.push(e)
+		}
+	}
+}
+
+// emitCleared reports the watched keys present before a clear as deleted
+// to the subscriptions of each key. The caller must hold the write lock.
+func (m *ValueMap[K, V]) emitCleared() {
+	h := m.watch.Load()
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for k, ws := range h.byKey {
+		v, ok := m.stored(k)
+		if !ok {
+			continue
+		}
+		e := Event[K, V]{Kind: EventDelete, Key: k, Old: v, Existed: true, Reason: ReasonCleared}
+		if _, live := m.lookup(k); !live {
+			e.Reason = ReasonExpired
+		}
+		for w := range ws {
+			w.push(e)
+		}
+	}
+}
+
+// hub returns the subscriptions of the map, creating them on first use.
+// Close may create them too, to refuse later subscriptions.
+func (m *ValueMap[K, V]) hub() *watchers[K, V] {
+	if h := m.watch.Load(); h != nil {
+		return h
+	}
+	m.watch.CompareAndSwap(nil, newWatchers[K, V]())
+	return m.watch.Load()
+}
@@ -0,0 +1,148 @@
+package valuemap
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := New[string, int]()
+	m.Set(&mu, "a", 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := m.Watch(ctx, &mu)
+	m.Set(&mu, "a", 2)
+	m.Set(&mu, "b", 3)
+	m.Delete(&mu, "a")
+	m.Delete(&mu, "missing")
+	m.Clear(&mu)
+	m.Clear(&mu)
+
+	want := []Event[string, int]{
+		{Kind: EventSet, Key: "a", Old: 1, New: 2, Existed: true},
+		{Kind: EventSet, Key: "b", New: 3},
+		{Kind: EventDelete, Key: "a", Old: 2, Existed: true, Reason: ReasonDeleted},
+		{Kind: EventClear},
+	}
+	for i, w := range want {
+		select {
+		case e := <-events:
+			if e != w {
+				t.Errorf("event %d = %+v, want %+v", i, e, w)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("event %d not received", i)
+		}
+	}
+
+	cancel()
+	for range events {
+	}
+	m.Set(&mu, "c", 4) // no subscription left to block or queue on
+	if n := len(m.watch.Load().all); n != 0 {
+		t.Errorf("%d subscriptions left after cancel", n)
+	}
+}
+
+func TestWatchEviction(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := New[string, int](WithMaxEntries(1), WithMaxCost(10, func(k string, v int) int64 { return int64(v) }))
+	events := m.Watch(t.Context(), &mu)
+	m.Set(&mu, "a", 1)
+	m.Set(&mu, "b", 2)  // evicts a
+	m.Set(&mu, "c", 11) // can never fit
+	if _, ok := m.Get(&mu, "c"); ok {
+		t.Error("an entry over the cost limit was kept")
+	}
+
+	// Evictions are reported after the set that caused them, so replaying
+	// the events gives the contents of the map.
+	want := []Event[string, int]{
+		{Kind: EventSet, Key: "a", New: 1},
+		{Kind: EventSet, Key: "b", New: 2},
+		{Kind: EventDelete, Key: "a", Old: 1, Existed: true, Reason: ReasonEvicted},
+		{Kind: EventSet, Key: "c", New: 11},
+		{Kind: EventDelete, Key: "c", Old: 11, Existed: true, Reason: ReasonEvicted},
+	}
+	for i, w := range want {
+		select {
+		case e := <-events:
+			if e != w {
+				t.Errorf("event %d = %+v, want %+v", i, e, w)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("event %d not received", i)
+		}
+	}
+	if v, ok := m.Get(&mu, "b"); v != 2 || !ok {
+		t.Errorf("Get(b) = %d, %v, want the entry kept over the rejected one", v, ok)
+	}
+}
+
+func TestWatchExpired(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := NewWithTTL[string, int](&mu, time.Millisecond, 0)
+	defer m.Close()
+	events := m.Watch(t.Context(), &mu)
+	m.Set(&mu, "a", 1)
+	time.Sleep(5 * time.Millisecond)
+	m.DeleteExpired(&mu)
+
+	<-events
+	if e := <-events; e.Kind != EventDelete || e.Reason != ReasonExpired {
+		t.Errorf("event = %+v, want an expired deletion", e)
+	}
+
+	// Overwriting an expired entry reports its expiry first.
+	m.Set(&mu, "b", 1)
+	time.Sleep(5 * time.Millisecond)
+	m.Set(&mu, "b", 2)
+	want := []Event[string, int]{
+		{Kind: EventSet, Key: "b", New: 1},
+		{Kind: EventDelete, Key: "b", Old: 1, Existed: true, Reason: ReasonExpired},
+		{Kind: EventSet, Key: "b", New: 2},
+	}
+	for i, w := range want {
+		if e := <-events; e != w {
+			t.Errorf("event %d = %+v, want %+v", i, e, w)
+		}
+	}
+}
+
+func TestWatchKey(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := New[string, int]()
+	ctx, cancel := context.WithCancel(context.Background())
+	events := m.WatchKey(ctx, &mu, "a")
+	m.Set(&mu, "b", 1)
+	m.Set(&mu, "a", 2)
+	m.Delete(&mu, "b")
+	m.Clear(&mu)
+	m.Set(&mu, "b", 3)
+	m.Clear(&mu)
+
+	want := []Event[string, int]{
+		{Kind: EventSet, Key: "a", New: 2},
+		{Kind: EventDelete, Key: "a", Old: 2, Existed: true, Reason: ReasonCleared},
+	}
+	for i, w := range want {
+		if e := <-events; e != w {
+			t.Errorf("event %d = %+v, want %+v", i, e, w)
+		}
+	}
+	select {
+	case e := <-events:
+		t.Errorf("unexpected event %+v", e)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	cancel()
+	for range events {
+	}
+	if n := len(m.watch.Load().byKey); n != 0 {
+		t.Errorf("%d watched keys left after cancel", n)
+	}
+}
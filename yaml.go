@@ -0,0 +1,30 @@
+package valuemap
+
+import "maps"
+
+// MarshalYAML returns the contents of the map as a plain map for YAML
+// encoding. It implements the Marshaler interface of gopkg.in/yaml.v2 and
+// gopkg.in/yaml.v3 without importing either.
+//
+// Since MarshalYAML cannot take the external mutex, it does not lock the
+// map. Callers must hold the read lock of the mutex guarding it.
+func (m *ValueMap[K, V]) MarshalYAML() (any, error) {
+	return maps.Collect(m.live()), nil
+}
+
+// UnmarshalYAML replaces the contents of the map with a decoded YAML
+// mapping. The mapping is decoded completely before the map is changed,
+// so on error the map is left as it was. It implements the function-based
+// Unmarshaler interface, which both gopkg.in/yaml.v2 and gopkg.in/yaml.v3
+// support, without importing either.
+//
+// Since UnmarshalYAML cannot take the external mutex, it does not lock the
+// map. Callers must hold the write lock of the mutex guarding it.
+func (m *ValueMap[K, V]) UnmarshalYAML(unmarshal func(any) error) error {
+	var data map[K]V
+	if err := unmarshal(&data); err != nil {
+		return err
+	}
+	m.replace(data)
+	return nil
+}
@@ -0,0 +1,42 @@
+package valuemap
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+)
+
+func TestYAML(t *testing.T) {
+	mu := sync.RWMutex{}
+	m := FromMap(map[string]int{"a": 1})
+
+	out, err := m.MarshalYAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := out.(map[string]int); !ok || got["a"] != 1 {
+		t.Errorf("MarshalYAML = %#v", out)
+	}
+
+	// Stand in for the YAML decoder with JSON, which YAML is a superset of.
+	unmarshal := func(doc string) func(any) error {
+		return func(v any) error {
+			return json.Unmarshal([]byte(doc), v)
+		}
+	}
+	if err := m.UnmarshalYAML(unmarshal(`{"b": 2, "c": 3}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.Get(&mu, "a"); ok {
+		t.Error("UnmarshalYAML kept old contents")
+	}
+	if n := m.Len(&mu); n != 2 {
+		t.Errorf("Len = %d, want 2", n)
+	}
+	if err := m.UnmarshalYAML(unmarshal(`{"b": "x"}`)); err == nil {
+		t.Error("UnmarshalYAML of a mistyped value succeeded")
+	}
+	if n := m.Len(&mu); n != 2 {
+		t.Errorf("Len = %d after failed UnmarshalYAML, want 2", n)
+	}
+}